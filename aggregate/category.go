@@ -0,0 +1,49 @@
+package aggregate
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidCategory = errors.New("a category has to have a valid name")
+)
+
+// Category groups products into a section of the menu, sections are
+// listed by ascending position
+type Category struct {
+	id       uuid.UUID
+	name     string
+	position int
+}
+
+func NewCategory(name string, position int) (Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Category{}, ErrInvalidCategory
+	}
+
+	return Category{
+		id:       uuid.New(),
+		name:     name,
+		position: position,
+	}, nil
+}
+
+func (c *Category) GetID() uuid.UUID {
+	return c.id
+}
+
+func (c *Category) SetID(id uuid.UUID) {
+	c.id = id
+}
+
+func (c *Category) GetName() string {
+	return c.name
+}
+
+func (c *Category) GetPosition() int {
+	return c.position
+}
@@ -4,22 +4,40 @@ import (
 	"errors"
 	"golang-learn-ddd/entity"
 	"golang-learn-ddd/valueobject"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrInvalidPerson = errors.New("a customer has to have a valid name")
+	ErrInvalidPerson      = errors.New("a customer has to have a valid name")
+	ErrInvalidDateOfBirth = errors.New("a customer has to be born in the past")
+	ErrInvalidAmount      = errors.New("an amount of money has to be positive")
+	ErrInsufficientFunds  = errors.New("the balance of the customer is too low")
 )
 
+// CustomerConfiguration sets optional details of a new customer
+type CustomerConfiguration func(c *Customer) error
+
 type Customer struct {
 	person   *entity.Person
 	products []*entity.Item
 
 	transaction []valueobject.Transaction
+	// balance is the prepaid credit of the customer, in cents
+	balance int
+	// tier is the membership tier of the customer, see domain/membership
+	tier string
+
+	// loyalty is the history of the loyalty points of the customer
+	loyalty []LoyaltyEntry
+
+	// version is incremented by repositories on every update, so concurrent
+	// updates of the same customer can be detected
+	version int
 }
 
-func NewCustomer(name string) (Customer, error) {
+func NewCustomer(name string, cfgs ...CustomerConfiguration) (Customer, error) {
 	if name == "" {
 		return Customer{}, ErrInvalidPerson
 	}
@@ -29,11 +47,54 @@ func NewCustomer(name string) (Customer, error) {
 		Name: name,
 	}
 
-	return Customer{
+	c := Customer{
 		person:      person,
 		products:    make([]*entity.Item, 0),
 		transaction: make([]valueobject.Transaction, 0),
-	}, nil
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(&c); err != nil {
+			return Customer{}, err
+		}
+	}
+
+	return c, nil
+}
+
+func WithEmail(address string) CustomerConfiguration {
+	return func(c *Customer) error {
+		email, err := valueobject.NewEmail(address)
+		if err != nil {
+			return err
+		}
+
+		c.SetEmail(email)
+		return nil
+	}
+}
+
+func WithPhoneNumber(number string) CustomerConfiguration {
+	return func(c *Customer) error {
+		phone, err := valueobject.NewPhoneNumber(number)
+		if err != nil {
+			return err
+		}
+
+		c.SetPhoneNumber(phone)
+		return nil
+	}
+}
+
+func WithDateOfBirth(dob time.Time) CustomerConfiguration {
+	return func(c *Customer) error {
+		if dob.IsZero() || dob.After(time.Now()) {
+			return ErrInvalidDateOfBirth
+		}
+
+		c.SetDateOfBirth(dob)
+		return nil
+	}
 }
 
 func (c *Customer) GetID() uuid.UUID {
@@ -66,3 +127,118 @@ func (c *Customer) SetName(name string) {
 
 	c.person.Name = name
 }
+
+func (c *Customer) GetEmail() valueobject.Email {
+	if c.person == nil {
+		c.person = &entity.Person{}
+	}
+
+	return c.person.Email
+}
+
+func (c *Customer) SetEmail(email valueobject.Email) {
+	if c.person == nil {
+		c.person = &entity.Person{}
+	}
+
+	c.person.Email = email
+}
+
+func (c *Customer) GetPhoneNumber() valueobject.PhoneNumber {
+	if c.person == nil {
+		c.person = &entity.Person{}
+	}
+
+	return c.person.PhoneNumber
+}
+
+func (c *Customer) SetPhoneNumber(phone valueobject.PhoneNumber) {
+	if c.person == nil {
+		c.person = &entity.Person{}
+	}
+
+	c.person.PhoneNumber = phone
+}
+
+func (c *Customer) GetDateOfBirth() time.Time {
+	if c.person == nil {
+		c.person = &entity.Person{}
+	}
+
+	return c.person.DateOfBirth
+}
+
+func (c *Customer) SetDateOfBirth(dob time.Time) {
+	if c.person == nil {
+		c.person = &entity.Person{}
+	}
+
+	c.person.DateOfBirth = dob
+}
+
+// AddTransaction records money the customer paid or received
+func (c *Customer) AddTransaction(t valueobject.Transaction) {
+	c.transaction = append(c.transaction, t)
+}
+
+func (c *Customer) GetTransactions() []valueobject.Transaction {
+	return c.transaction
+}
+
+func (c *Customer) SetTransactions(transactions []valueobject.Transaction) {
+	c.transaction = append([]valueobject.Transaction(nil), transactions...)
+}
+
+// GetBalance is the prepaid credit of the customer
+func (c *Customer) GetBalance() float64 {
+	return float64(c.balance) / 100
+}
+
+func (c *Customer) SetBalance(balance float64) {
+	c.balance = valueobject.Cents(balance)
+}
+
+// Deposit adds prepaid credit to the wallet of the customer
+func (c *Customer) Deposit(amount float64, at time.Time) error {
+	cents := valueobject.Cents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+
+	c.balance += cents
+	c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionDeposit, cents, c.GetID(), c.GetID(), at))
+
+	return nil
+}
+
+// Withdraw takes credit from the wallet of the customer, it never overdraws
+func (c *Customer) Withdraw(amount float64, at time.Time) error {
+	cents := valueobject.Cents(amount)
+	if cents <= 0 {
+		return ErrInvalidAmount
+	}
+	if cents > c.balance {
+		return ErrInsufficientFunds
+	}
+
+	c.balance -= cents
+	c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionWithdrawal, cents, c.GetID(), c.GetID(), at))
+
+	return nil
+}
+
+func (c *Customer) GetTier() string {
+	return c.tier
+}
+
+func (c *Customer) SetTier(tier string) {
+	c.tier = tier
+}
+
+func (c *Customer) GetVersion() int {
+	return c.version
+}
+
+func (c *Customer) SetVersion(version int) {
+	c.version = version
+}
@@ -6,6 +6,7 @@ import (
 	"golang-learn-ddd/valueobject"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,6 +14,7 @@ import (
 func TestCustomer_NewCustomer(t *testing.T) {
 	type args struct {
 		name string
+		cfgs []CustomerConfiguration
 	}
 	tests := []struct {
 		name        string
@@ -33,11 +35,47 @@ func TestCustomer_NewCustomer(t *testing.T) {
 			},
 			expectedErr: nil,
 		},
+		{
+			name: "Valid contact details",
+			args: args{
+				name: "Adhiana",
+				cfgs: []CustomerConfiguration{
+					WithEmail("adhiana@example.com"),
+					WithPhoneNumber("+6281234567890"),
+					WithDateOfBirth(time.Date(1995, time.May, 17, 0, 0, 0, 0, time.UTC)),
+				},
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "Malformed email",
+			args: args{
+				name: "Adhiana",
+				cfgs: []CustomerConfiguration{WithEmail("adhiana@")},
+			},
+			expectedErr: valueobject.ErrInvalidEmail,
+		},
+		{
+			name: "Malformed phone number",
+			args: args{
+				name: "Adhiana",
+				cfgs: []CustomerConfiguration{WithPhoneNumber("12-34")},
+			},
+			expectedErr: valueobject.ErrInvalidPhoneNumber,
+		},
+		{
+			name: "Date of birth in the future",
+			args: args{
+				name: "Adhiana",
+				cfgs: []CustomerConfiguration{WithDateOfBirth(time.Now().AddDate(1, 0, 0))},
+			},
+			expectedErr: ErrInvalidDateOfBirth,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := NewCustomer(tt.args.name)
+			_, err := NewCustomer(tt.args.name, tt.args.cfgs...)
 			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
 			}
@@ -258,3 +296,83 @@ func TestCustomer_SetName(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomer_Wallet(t *testing.T) {
+	now := time.Now()
+
+	type testCase struct {
+		name        string
+		deposit     float64
+		withdraw    float64
+		expected    float64
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:     "withdraw part of the balance",
+			deposit:  20,
+			withdraw: 12.35,
+			expected: 7.65,
+		},
+		{
+			name:     "withdraw the whole balance",
+			deposit:  20,
+			withdraw: 20,
+			expected: 0,
+		},
+		{
+			name:        "overdraft",
+			deposit:     20,
+			withdraw:    20.01,
+			expected:    20,
+			expectedErr: ErrInsufficientFunds,
+		},
+		{
+			name:        "nothing to withdraw",
+			deposit:     20,
+			withdraw:    0,
+			expected:    20,
+			expectedErr: ErrInvalidAmount,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewCustomer("Luka")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Deposit(tc.deposit, now); err != nil {
+				t.Fatal(err)
+			}
+
+			err = c.Withdraw(tc.withdraw, now)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if c.GetBalance() != tc.expected {
+				t.Errorf("expected balance %v, got %v", tc.expected, c.GetBalance())
+			}
+
+			kinds := []valueobject.TransactionKind{}
+			for _, tr := range c.GetTransactions() {
+				kinds = append(kinds, tr.Kind())
+			}
+			expectedKinds := []valueobject.TransactionKind{valueobject.TransactionDeposit}
+			if err == nil {
+				expectedKinds = append(expectedKinds, valueobject.TransactionWithdrawal)
+			}
+			if !reflect.DeepEqual(kinds, expectedKinds) {
+				t.Errorf("expected transactions %v, got %v", expectedKinds, kinds)
+			}
+		})
+	}
+
+	c, err := NewCustomer("Luka")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Deposit(-5, now); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected error %v, got %v", ErrInvalidAmount, err)
+	}
+}
@@ -0,0 +1,217 @@
+package aggregate
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPoints      = errors.New("a number of points has to be positive")
+	ErrInsufficientPoints = errors.New("the customer does not have enough loyalty points")
+)
+
+type LoyaltyEntryKind string
+
+const (
+	PointsEarned   LoyaltyEntryKind = "earned"
+	PointsRedeemed LoyaltyEntryKind = "redeemed"
+	PointsExpired  LoyaltyEntryKind = "expired"
+	PointsRestored LoyaltyEntryKind = "restored"
+)
+
+// LoyaltyEntry is one accrual, redemption or expiry of loyalty points
+type LoyaltyEntry struct {
+	Kind   LoyaltyEntryKind
+	Points int
+	// Remaining is what is left of earned points, once redeemed or expired
+	Remaining int
+	// OrderID is the order the points were earned with or redeemed for
+	OrderID   uuid.UUID
+	CreatedAt time.Time
+	// ExpiresAt is when earned points expire, zero when they never do
+	ExpiresAt time.Time
+}
+
+func (e LoyaltyEntry) expired(at time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !at.Before(e.ExpiresAt)
+}
+
+// GetPoints is the number of points the customer can redeem at the given time
+func (c *Customer) GetPoints(at time.Time) int {
+	var points int
+	for _, e := range c.loyalty {
+		if e.Kind == PointsEarned && !e.expired(at) {
+			points += e.Remaining
+		}
+	}
+
+	return points
+}
+
+// GetLoyaltyHistory returns every accrual, redemption and expiry, oldest first
+func (c *Customer) GetLoyaltyHistory() []LoyaltyEntry {
+	return c.loyalty
+}
+
+func (c *Customer) SetLoyaltyHistory(history []LoyaltyEntry) {
+	c.loyalty = append([]LoyaltyEntry(nil), history...)
+}
+
+// EarnPoints credits points for an order, expiresAt is zero for points that never expire
+func (c *Customer) EarnPoints(points int, orderID uuid.UUID, at, expiresAt time.Time) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+
+	c.expirePoints(at)
+	c.loyalty = append(c.loyalty, LoyaltyEntry{
+		Kind:      PointsEarned,
+		Points:    points,
+		Remaining: points,
+		OrderID:   orderID,
+		CreatedAt: at,
+		ExpiresAt: expiresAt,
+	})
+
+	return nil
+}
+
+// RedeemPoints spends points on an order, the points expiring first are spent first
+func (c *Customer) RedeemPoints(points int, orderID uuid.UUID, at time.Time) error {
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+
+	c.expirePoints(at)
+	if c.GetPoints(at) < points {
+		return ErrInsufficientPoints
+	}
+
+	// the history is shared with copies of the customer, change a new one
+	c.SetLoyaltyHistory(c.loyalty)
+
+	earned := []int{}
+	for i, e := range c.loyalty {
+		if e.Kind == PointsEarned && e.Remaining > 0 {
+			earned = append(earned, i)
+		}
+	}
+	sort.SliceStable(earned, func(a, b int) bool {
+		ea, eb := c.loyalty[earned[a]], c.loyalty[earned[b]]
+		if ea.ExpiresAt.IsZero() || eb.ExpiresAt.IsZero() {
+			return !ea.ExpiresAt.IsZero() && eb.ExpiresAt.IsZero()
+		}
+		return ea.ExpiresAt.Before(eb.ExpiresAt)
+	})
+
+	left := points
+	for _, i := range earned {
+		spent := min(left, c.loyalty[i].Remaining)
+		c.loyalty[i].Remaining -= spent
+		left -= spent
+		if left == 0 {
+			break
+		}
+	}
+
+	c.loyalty = append(c.loyalty, LoyaltyEntry{
+		Kind:      PointsRedeemed,
+		Points:    points,
+		OrderID:   orderID,
+		CreatedAt: at,
+	})
+
+	return nil
+}
+
+// RestorePoints gives back the points redeemed for an order that was never
+// paid. They go back to the earned points that expire last, points that
+// could only go back to expired ones are lost.
+func (c *Customer) RestorePoints(orderID uuid.UUID, at time.Time) error {
+	var points int
+	for _, e := range c.loyalty {
+		if e.OrderID != orderID {
+			continue
+		}
+		switch e.Kind {
+		case PointsRedeemed:
+			points += e.Points
+		case PointsRestored:
+			points -= e.Points
+		}
+	}
+	if points <= 0 {
+		return ErrInvalidPoints
+	}
+
+	c.expirePoints(at)
+	c.SetLoyaltyHistory(c.loyalty)
+
+	spent := []int{}
+	for i, e := range c.loyalty {
+		if e.Kind == PointsEarned && e.Remaining < e.Points && !e.expired(at) {
+			spent = append(spent, i)
+		}
+	}
+	sort.SliceStable(spent, func(a, b int) bool {
+		ea, eb := c.loyalty[spent[a]], c.loyalty[spent[b]]
+		if ea.ExpiresAt.IsZero() || eb.ExpiresAt.IsZero() {
+			return ea.ExpiresAt.IsZero() && !eb.ExpiresAt.IsZero()
+		}
+		return ea.ExpiresAt.After(eb.ExpiresAt)
+	})
+
+	left := points
+	for _, i := range spent {
+		restored := min(left, c.loyalty[i].Points-c.loyalty[i].Remaining)
+		c.loyalty[i].Remaining += restored
+		left -= restored
+		if left == 0 {
+			break
+		}
+	}
+
+	c.loyalty = append(c.loyalty, LoyaltyEntry{
+		Kind:      PointsRestored,
+		Points:    points,
+		OrderID:   orderID,
+		CreatedAt: at,
+	})
+
+	return nil
+}
+
+// ExpirePoints records the expiry of the points not redeemed in time
+func (c *Customer) ExpirePoints(at time.Time) {
+	c.expirePoints(at)
+}
+
+func (c *Customer) expirePoints(at time.Time) {
+	var expired []LoyaltyEntry
+	for _, e := range c.loyalty {
+		if e.Kind == PointsEarned && e.Remaining > 0 && e.expired(at) {
+			expired = append(expired, e)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	c.SetLoyaltyHistory(c.loyalty)
+	for i, e := range c.loyalty {
+		if e.Kind == PointsEarned && e.Remaining > 0 && e.expired(at) {
+			c.loyalty[i].Remaining = 0
+		}
+	}
+	for _, e := range expired {
+		c.loyalty = append(c.loyalty, LoyaltyEntry{
+			Kind:      PointsExpired,
+			Points:    e.Remaining,
+			OrderID:   e.OrderID,
+			CreatedAt: e.ExpiresAt,
+		})
+	}
+}
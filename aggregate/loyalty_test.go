@@ -0,0 +1,139 @@
+package aggregate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLoyalty_RedeemPoints(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2023, time.June, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	c, err := NewCustomer("Kaito")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 points never expiring, 20 expiring on the 10th and 5 on the 4th
+	if err := c.EarnPoints(10, uuid.New(), day(1), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EarnPoints(20, uuid.New(), day(1), day(10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EarnPoints(5, uuid.New(), day(2), day(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := c.GetPoints(day(3)); got != 35 {
+		t.Errorf("expected %d points, got %d", 35, got)
+	}
+
+	// the points expiring first are spent first
+	if err := c.RedeemPoints(8, uuid.New(), day(3)); err != nil {
+		t.Fatal(err)
+	}
+	remaining := []int{}
+	for _, e := range c.GetLoyaltyHistory() {
+		if e.Kind == PointsEarned {
+			remaining = append(remaining, e.Remaining)
+		}
+	}
+	if !reflect.DeepEqual(remaining, []int{10, 17, 0}) {
+		t.Errorf("expected remaining points %v, got %v", []int{10, 17, 0}, remaining)
+	}
+
+	if err := c.RedeemPoints(28, uuid.New(), day(5)); !errors.Is(err, ErrInsufficientPoints) {
+		t.Errorf("expected error %v, got %v", ErrInsufficientPoints, err)
+	}
+
+	// the 17 points left on the 10th expire
+	c.ExpirePoints(day(11))
+	if got := c.GetPoints(day(11)); got != 10 {
+		t.Errorf("expected %d points, got %d", 10, got)
+	}
+
+	kinds := []LoyaltyEntryKind{}
+	for _, e := range c.GetLoyaltyHistory() {
+		kinds = append(kinds, e.Kind)
+	}
+	expected := []LoyaltyEntryKind{PointsEarned, PointsEarned, PointsEarned, PointsRedeemed, PointsExpired}
+	if !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("expected history %v, got %v", expected, kinds)
+	}
+	if expired := c.GetLoyaltyHistory()[4]; expired.Points != 17 || !expired.CreatedAt.Equal(day(10)) {
+		t.Errorf("expected 17 points expired on %v, got %d on %v", day(10), expired.Points, expired.CreatedAt)
+	}
+
+	if err := c.RedeemPoints(0, uuid.New(), day(11)); !errors.Is(err, ErrInvalidPoints) {
+		t.Errorf("expected error %v, got %v", ErrInvalidPoints, err)
+	}
+}
+
+func TestLoyalty_RedeemPointsKeepsCopies(t *testing.T) {
+	c, err := NewCustomer("Kaito")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EarnPoints(10, uuid.New(), time.Now(), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	copied := c
+	if err := copied.RedeemPoints(4, uuid.New(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetPoints(time.Now()); got != 10 {
+		t.Errorf("expected the original to keep %d points, got %d", 10, got)
+	}
+}
+
+func TestLoyalty_RestorePoints(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2023, time.June, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	c, err := NewCustomer("Kaito")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 10 points never expiring, 20 expiring on the 10th and 5 on the 4th, all spent
+	if err := c.EarnPoints(10, uuid.New(), day(1), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EarnPoints(20, uuid.New(), day(1), day(10)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.EarnPoints(5, uuid.New(), day(2), day(4)); err != nil {
+		t.Fatal(err)
+	}
+	orderID := uuid.New()
+	if err := c.RedeemPoints(35, orderID, day(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.RestorePoints(uuid.New(), day(5)); !errors.Is(err, ErrInvalidPoints) {
+		t.Errorf("expected error %v, got %v", ErrInvalidPoints, err)
+	}
+
+	// the order is not paid, the 5 points expired on the 4th are lost
+	if err := c.RestorePoints(orderID, day(5)); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.GetPoints(day(5)); got != 30 {
+		t.Errorf("expected %d points, got %d", 30, got)
+	}
+	if got := c.GetPoints(day(11)); got != 10 {
+		t.Errorf("expected %d points once the others expire, got %d", 10, got)
+	}
+
+	if err := c.RestorePoints(orderID, day(5)); !errors.Is(err, ErrInvalidPoints) {
+		t.Errorf("expected points to be restored once, got %v", err)
+	}
+}
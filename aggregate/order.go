@@ -0,0 +1,240 @@
+package aggregate
+
+import (
+	"errors"
+	"golang-learn-ddd/valueobject"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptyOrder = errors.New("an order has to have at least one line")
+)
+
+// OrderLine is one unit of a product as it was priced when ordered
+type OrderLine struct {
+	ProductID   uuid.UUID
+	VariantID   uuid.UUID
+	ModifierIDs []uuid.UUID
+	CategoryID  uuid.UUID
+	Name        string
+	// UnitPrice includes the variant and modifiers, before any discount
+	UnitPrice float64
+	Discount  float64
+	// Rule is the name of the pricing rule that granted the discount, if any
+	Rule string
+}
+
+// Total is the price of the line after its discount
+func (l OrderLine) Total() float64 {
+	return RoundMoney(l.UnitPrice - l.Discount)
+}
+
+// TaxLine is the tax collected at one rate over the lines it applies to
+type TaxLine struct {
+	Name string
+	// Rate is a percentage
+	Rate float64
+	// Base is the taxed amount, excluding the tax
+	Base   float64
+	Amount float64
+}
+
+type Order struct {
+	id         uuid.UUID
+	customerID uuid.UUID
+	lines      []OrderLine
+	createdAt  time.Time
+
+	// coupon is the promotion code applied to the whole order, if any
+	coupon         string
+	couponDiscount float64
+
+	// tier is the membership tier of the customer, which took tierDiscount
+	// off the whole order and gives the order its kitchen priority
+	tier         string
+	tierDiscount float64
+	priority     int
+
+	// pointsRedeemed loyalty points took pointsDiscount off the whole order
+	pointsRedeemed int
+	pointsDiscount float64
+
+	taxes []TaxLine
+	// taxInclusive is true when the prices of the lines already include the taxes
+	taxInclusive bool
+
+	// status, payments and refunds track the money of the order once billed
+	status        OrderStatus
+	paymentMethod PaymentMethod
+	payments      []valueobject.Transaction
+	paidAt        time.Time
+	refunds       []Refund
+	// extras are the service charges and tips paid along with the order,
+	// which refunds do not give back
+	extras []valueobject.Transaction
+
+	// version is incremented by repositories on every update, so concurrent
+	// refunds of the same order can be detected
+	version int
+}
+
+func NewOrder(customerID uuid.UUID, lines []OrderLine, createdAt time.Time) (Order, error) {
+	if len(lines) == 0 {
+		return Order{}, ErrEmptyOrder
+	}
+
+	return Order{
+		id:         uuid.New(),
+		customerID: customerID,
+		lines:      lines,
+		createdAt:  createdAt,
+	}, nil
+}
+
+func (o *Order) GetID() uuid.UUID {
+	return o.id
+}
+
+func (o *Order) SetID(id uuid.UUID) {
+	o.id = id
+}
+
+func (o *Order) GetCustomerID() uuid.UUID {
+	return o.customerID
+}
+
+func (o *Order) GetLines() []OrderLine {
+	return o.lines
+}
+
+func (o *Order) GetCreatedAt() time.Time {
+	return o.createdAt
+}
+
+// ApplyCoupon takes discount off the order for the promotion code
+func (o *Order) ApplyCoupon(code string, discount float64) {
+	o.coupon = code
+	o.couponDiscount = RoundMoney(discount)
+}
+
+func (o *Order) GetCoupon() string {
+	return o.coupon
+}
+
+func (o *Order) GetCouponDiscount() float64 {
+	return o.couponDiscount
+}
+
+// ApplyTierDiscount takes discount off the order for the membership tier of the customer
+func (o *Order) ApplyTierDiscount(tier string, discount float64) {
+	o.tier = tier
+	o.tierDiscount = RoundMoney(discount)
+}
+
+func (o *Order) GetTier() string {
+	return o.tier
+}
+
+func (o *Order) GetTierDiscount() float64 {
+	return o.tierDiscount
+}
+
+// SetPriority moves the order up the kitchen queue, higher first
+func (o *Order) SetPriority(priority int) {
+	o.priority = priority
+}
+
+func (o *Order) GetPriority() int {
+	return o.priority
+}
+
+// ApplyPoints takes discount off the order for the loyalty points redeemed
+func (o *Order) ApplyPoints(points int, discount float64) {
+	o.pointsRedeemed = points
+	o.pointsDiscount = RoundMoney(discount)
+}
+
+func (o *Order) GetPointsRedeemed() int {
+	return o.pointsRedeemed
+}
+
+func (o *Order) GetPointsDiscount() float64 {
+	return o.pointsDiscount
+}
+
+// GetOrderDiscount is what the membership tier, the coupon and the loyalty
+// points took off the whole order, on top of the discounts of the lines
+func (o *Order) GetOrderDiscount() float64 {
+	return RoundMoney(o.tierDiscount + o.couponDiscount + o.pointsDiscount)
+}
+
+// ApplyTax sets the tax breakdown of the order, inclusive taxes are
+// already part of the prices of the lines and do not add to the total
+func (o *Order) ApplyTax(taxes []TaxLine, inclusive bool) {
+	o.taxes = taxes
+	o.taxInclusive = inclusive
+}
+
+func (o *Order) GetTaxes() []TaxLine {
+	return o.taxes
+}
+
+func (o *Order) IsTaxInclusive() bool {
+	return o.taxInclusive
+}
+
+// GetTax is the sum of every tax of the order
+func (o *Order) GetTax() float64 {
+	var tax float64
+	for _, t := range o.taxes {
+		tax += t.Amount
+	}
+
+	return RoundMoney(tax)
+}
+
+// GetDiscount is the sum of the discounts of every line and of the whole order
+func (o *Order) GetDiscount() float64 {
+	discount := o.GetOrderDiscount()
+	for _, l := range o.lines {
+		discount += l.Discount
+	}
+
+	return RoundMoney(discount)
+}
+
+// GetSubtotal is the sum of every line after their discounts, before the discounts of the whole order
+func (o *Order) GetSubtotal() float64 {
+	var subtotal float64
+	for _, l := range o.lines {
+		subtotal += l.Total()
+	}
+
+	return RoundMoney(subtotal)
+}
+
+// GetTotal is what the customer pays, after every discount and with the taxes
+func (o *Order) GetTotal() float64 {
+	total := o.GetSubtotal() - o.GetOrderDiscount()
+	if !o.taxInclusive {
+		total += o.GetTax()
+	}
+
+	return RoundMoney(total)
+}
+
+func (o *Order) GetVersion() int {
+	return o.version
+}
+
+func (o *Order) SetVersion(version int) {
+	o.version = version
+}
+
+// RoundMoney rounds an amount to the cent
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -3,26 +3,51 @@ package aggregate
 import (
 	"errors"
 	"golang-learn-ddd/entity"
+	"golang-learn-ddd/valueobject"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 var (
-	ErrMissingValues = errors.New("missing important values")
+	ErrMissingValues     = errors.New("missing important values")
+	ErrInvalidPrice      = errors.New("price must not be negative")
+	ErrInvalidMinimumAge = errors.New("minimum age must not be negative")
+	ErrInvalidVariant    = errors.New("a variant has to have a name and a non negative price")
+	ErrInvalidModifier   = errors.New("a modifier has to have a name")
+	ErrVariantRequired   = errors.New("a variant has to be chosen for this product")
+	ErrVariantNotFound   = errors.New("variant not found for this product")
+	ErrModifierNotFound  = errors.New("modifier not found for this product")
+	ErrPricedByVariants  = errors.New("the product is priced by its variants")
 )
 
+// ProductConfiguration sets optional attributes of a new product
+type ProductConfiguration func(p *Product) error
+
 type Product struct {
-	item     *entity.Item
+	item *entity.Item
+	// price is the price the product was created with, see priceHistory
 	price    float64
 	quantity int
+	// minimumAge a customer must have to order the product, 0 if unrestricted
+	minimumAge int
+
+	// variants replace the price of the product when it has any
+	variants  []entity.Variant
+	modifiers []entity.Modifier
+
+	// priceHistory holds past and scheduled price changes sorted by effective time
+	priceHistory []valueobject.PriceChange
 }
 
-func NewProduct(name, description string, price float64) (Product, error) {
+func NewProduct(name, description string, price float64, cfgs ...ProductConfiguration) (Product, error) {
 	if name == "" || description == "" {
 		return Product{}, ErrMissingValues
 	}
 
-	return Product{
+	p := Product{
 		item: &entity.Item{
 			ID:          uuid.New(),
 			Name:        name,
@@ -30,17 +55,272 @@ func NewProduct(name, description string, price float64) (Product, error) {
 		},
 		price:    price,
 		quantity: 0,
-	}, nil
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(&p); err != nil {
+			return Product{}, err
+		}
+	}
+
+	return p, nil
+}
+
+func WithMinimumAge(age int) ProductConfiguration {
+	return func(p *Product) error {
+		if age < 0 {
+			return ErrInvalidMinimumAge
+		}
+
+		p.minimumAge = age
+		return nil
+	}
+}
+
+func WithCategory(categoryID uuid.UUID) ProductConfiguration {
+	return func(p *Product) error {
+		p.item.CategoryID = categoryID
+		return nil
+	}
+}
+
+func WithTags(tags ...string) ProductConfiguration {
+	return func(p *Product) error {
+		p.SetTags(tags...)
+		return nil
+	}
+}
+
+// WithVariant adds a variant with its own price and stock, once a product
+// has variants one of them has to be chosen when ordering it
+func WithVariant(name string, price float64, quantity int) ProductConfiguration {
+	return func(p *Product) error {
+		if name == "" || price < 0 {
+			return ErrInvalidVariant
+		}
+
+		p.variants = append(p.variants, entity.Variant{
+			ID:       uuid.New(),
+			Name:     name,
+			Price:    price,
+			Quantity: quantity,
+		})
+		return nil
+	}
+}
+
+// WithModifier adds an optional add-on changing the price by priceDelta
+func WithModifier(name string, priceDelta float64) ProductConfiguration {
+	return func(p *Product) error {
+		if name == "" {
+			return ErrInvalidModifier
+		}
+
+		p.modifiers = append(p.modifiers, entity.Modifier{
+			ID:         uuid.New(),
+			Name:       name,
+			PriceDelta: priceDelta,
+		})
+		return nil
+	}
 }
 
 func (p *Product) GetID() uuid.UUID {
 	return p.item.ID
 }
 
+func (p *Product) SetID(id uuid.UUID) {
+	p.item.ID = id
+}
+
 func (p *Product) GetItem() *entity.Item {
 	return p.item
 }
 
+// GetPrice returns the price effective now
 func (p *Product) GetPrice() float64 {
+	return p.PriceAt(time.Now())
+}
+
+// PriceAt returns the price effective at the given time
+func (p *Product) PriceAt(at time.Time) float64 {
+	price := p.price
+	for _, change := range p.priceHistory {
+		if change.EffectiveAt().After(at) {
+			break
+		}
+		price = change.New()
+	}
+
+	return price
+}
+
+// ChangePrice sets a new price from effectiveAt on, which may be in the future
+// to schedule a price change. The change is recorded in the price history.
+// A product with variants is priced by them, its price cannot change.
+func (p *Product) ChangePrice(price float64, effectiveAt time.Time) error {
+	if len(p.variants) > 0 {
+		return ErrPricedByVariants
+	}
+	if price < 0 {
+		return ErrInvalidPrice
+	}
+
+	change := valueobject.NewPriceChange(p.PriceAt(effectiveAt), price, effectiveAt)
+
+	// keep the history sorted, changes at the same time apply in the order they were made
+	i := sort.Search(len(p.priceHistory), func(i int) bool {
+		return p.priceHistory[i].EffectiveAt().After(effectiveAt)
+	})
+	// a new slice, copies of the product share the history they were made with
+	history := make([]valueobject.PriceChange, 0, len(p.priceHistory)+1)
+	history = append(history, p.priceHistory[:i]...)
+	history = append(history, change)
+	history = append(history, p.priceHistory[i:]...)
+	p.priceHistory = history
+
+	// later changes now apply on top of this one
+	for j := i + 1; j < len(p.priceHistory); j++ {
+		next := p.priceHistory[j]
+		p.priceHistory[j] = valueobject.NewPriceChange(p.priceHistory[j-1].New(), next.New(), next.EffectiveAt())
+	}
+
+	return nil
+}
+
+// GetBasePrice returns the price the product was created with, before any change
+func (p *Product) GetBasePrice() float64 {
 	return p.price
 }
+
+func (p *Product) GetPriceHistory() []valueobject.PriceChange {
+	return p.priceHistory
+}
+
+func (p *Product) SetPriceHistory(history []valueobject.PriceChange) {
+	p.priceHistory = append([]valueobject.PriceChange(nil), history...)
+	sort.SliceStable(p.priceHistory, func(i, j int) bool {
+		return p.priceHistory[i].EffectiveAt().Before(p.priceHistory[j].EffectiveAt())
+	})
+}
+
+func (p *Product) GetQuantity() int {
+	return p.quantity
+}
+
+func (p *Product) SetQuantity(quantity int) {
+	p.quantity = quantity
+}
+
+func (p *Product) GetMinimumAge() int {
+	return p.minimumAge
+}
+
+// IsAgeRestricted reports whether customers need a minimum age to order the product
+func (p *Product) IsAgeRestricted() bool {
+	return p.minimumAge > 0
+}
+
+func (p *Product) GetCategoryID() uuid.UUID {
+	return p.item.CategoryID
+}
+
+func (p *Product) SetCategoryID(categoryID uuid.UUID) {
+	p.item.CategoryID = categoryID
+}
+
+func (p *Product) GetTags() []string {
+	return p.item.Tags
+}
+
+// SetTags replaces the tags, they are trimmed, lowercased and deduplicated
+func (p *Product) SetTags(tags ...string) {
+	normalized := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	p.item.Tags = normalized
+}
+
+func (p *Product) HasTag(tag string) bool {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	for _, t := range p.item.Tags {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Product) GetVariants() []entity.Variant {
+	return p.variants
+}
+
+func (p *Product) SetVariants(variants []entity.Variant) {
+	p.variants = variants
+}
+
+func (p *Product) GetVariant(id uuid.UUID) (entity.Variant, error) {
+	for _, v := range p.variants {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+
+	return entity.Variant{}, ErrVariantNotFound
+}
+
+func (p *Product) GetModifiers() []entity.Modifier {
+	return p.modifiers
+}
+
+func (p *Product) SetModifiers(modifiers []entity.Modifier) {
+	p.modifiers = modifiers
+}
+
+func (p *Product) GetModifier(id uuid.UUID) (entity.Modifier, error) {
+	for _, m := range p.modifiers {
+		if m.ID == id {
+			return m, nil
+		}
+	}
+
+	return entity.Modifier{}, ErrModifierNotFound
+}
+
+// PriceFor prices one unit of the product, at the given time, in the given variant
+// with the given modifiers. variantID must be uuid.Nil for products without variants.
+func (p *Product) PriceFor(at time.Time, variantID uuid.UUID, modifierIDs []uuid.UUID) (float64, error) {
+	price := p.PriceAt(at)
+
+	switch {
+	case variantID != uuid.Nil:
+		v, err := p.GetVariant(variantID)
+		if err != nil {
+			return 0, err
+		}
+		price = v.Price
+	case len(p.variants) > 0:
+		return 0, ErrVariantRequired
+	}
+
+	for _, id := range modifierIDs {
+		m, err := p.GetModifier(id)
+		if err != nil {
+			return 0, err
+		}
+		price += m.PriceDelta
+	}
+
+	return price, nil
+}
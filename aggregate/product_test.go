@@ -0,0 +1,215 @@
+package aggregate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestProduct_PriceFor(t *testing.T) {
+	beer, err := NewProduct("Beer", "Halal Beer", 0,
+		WithVariant("Pint", 6, 10),
+		WithVariant("Half-pint", 3.5, 10),
+		WithModifier("Lemon", 0.5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakso, err := NewProduct("Bakso Kuah", "Bakso Kuah pedah hot jeletot", 1.5,
+		WithModifier("Extra noodles", 0.75),
+		WithModifier("Extra meatballs", 1.25),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pint, halfPint := beer.GetVariants()[0].ID, beer.GetVariants()[1].ID
+	lemon := beer.GetModifiers()[0].ID
+	noodles, meatballs := bakso.GetModifiers()[0].ID, bakso.GetModifiers()[1].ID
+
+	type testCase struct {
+		name        string
+		product     Product
+		variantID   uuid.UUID
+		modifierIDs []uuid.UUID
+		expected    float64
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:      "variant price replaces the product price",
+			product:   beer,
+			variantID: halfPint,
+			expected:  3.5,
+		},
+		{
+			name:        "variant with modifier",
+			product:     beer,
+			variantID:   pint,
+			modifierIDs: []uuid.UUID{lemon},
+			expected:    6.5,
+		},
+		{
+			name:        "product without variants with modifiers",
+			product:     bakso,
+			modifierIDs: []uuid.UUID{noodles, meatballs, noodles},
+			expected:    4.25,
+		},
+		{
+			name:        "variant is required",
+			product:     beer,
+			expectedErr: ErrVariantRequired,
+		},
+		{
+			name:        "unknown variant",
+			product:     bakso,
+			variantID:   pint,
+			expectedErr: ErrVariantNotFound,
+		},
+		{
+			name:        "modifier of another product",
+			product:     bakso,
+			modifierIDs: []uuid.UUID{lemon},
+			expectedErr: ErrModifierNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.product.PriceFor(time.Now(), tt.variantID, tt.modifierIDs)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+
+			if got != tt.expected {
+				t.Errorf("expected price %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestProduct_NewProductVariants(t *testing.T) {
+	if _, err := NewProduct("Beer", "Halal Beer", 0, WithVariant("", 6, 10)); !errors.Is(err, ErrInvalidVariant) {
+		t.Errorf("expected error %v, got %v", ErrInvalidVariant, err)
+	}
+	if _, err := NewProduct("Beer", "Halal Beer", 0, WithVariant("Pint", -1, 10)); !errors.Is(err, ErrInvalidVariant) {
+		t.Errorf("expected error %v, got %v", ErrInvalidVariant, err)
+	}
+	if _, err := NewProduct("Beer", "Halal Beer", 0, WithModifier("", 1)); !errors.Is(err, ErrInvalidModifier) {
+		t.Errorf("expected error %v, got %v", ErrInvalidModifier, err)
+	}
+}
+
+func TestProduct_ChangePrice(t *testing.T) {
+	now := time.Now()
+
+	beer, err := NewProduct("Beer", "Halal Beer", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// scheduled first, then a change effective earlier
+	if err := beer.ChangePrice(7, now.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := beer.ChangePrice(6, now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := beer.ChangePrice(-1, now); !errors.Is(err, ErrInvalidPrice) {
+		t.Errorf("expected error %v, got %v", ErrInvalidPrice, err)
+	}
+
+	type testCase struct {
+		name     string
+		at       time.Time
+		expected float64
+	}
+	tests := []testCase{
+		{name: "before any change", at: now.Add(-2 * time.Hour), expected: 5},
+		{name: "after the past change", at: now, expected: 6},
+		{name: "once the scheduled change is effective", at: now.Add(25 * time.Hour), expected: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := beer.PriceAt(tt.at); got != tt.expected {
+				t.Errorf("expected price %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	if beer.GetPrice() != 6 {
+		t.Errorf("expected current price %v, got %v", 6, beer.GetPrice())
+	}
+
+	history := beer.GetPriceHistory()
+	if len(history) != 2 {
+		t.Fatalf("expected 2 price changes, got %d", len(history))
+	}
+	if history[0].Old() != 5 || history[0].New() != 6 {
+		t.Errorf("expected first change 5 -> 6, got %v -> %v", history[0].Old(), history[0].New())
+	}
+	if history[1].Old() != 6 || history[1].New() != 7 {
+		t.Errorf("expected scheduled change 6 -> 7, got %v -> %v", history[1].Old(), history[1].New())
+	}
+}
+
+func TestProduct_ChangePriceOfVariants(t *testing.T) {
+	now := time.Now()
+
+	coffee, err := NewProduct("Coffee", "Freshly brewed", 0, WithVariant("Small", 3, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the price charged is the one of the variant, a change of the product would not apply
+	if err := coffee.ChangePrice(4, now.Add(-time.Hour)); !errors.Is(err, ErrPricedByVariants) {
+		t.Errorf("expected error %v, got %v", ErrPricedByVariants, err)
+	}
+	if len(coffee.GetPriceHistory()) != 0 {
+		t.Errorf("expected no price change, got %v", coffee.GetPriceHistory())
+	}
+
+	price, err := coffee.PriceFor(now, coffee.GetVariants()[0].ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 3 {
+		t.Errorf("expected price %v, got %v", 3, price)
+	}
+}
+
+func TestProduct_ChangePriceOfCopy(t *testing.T) {
+	now := time.Now()
+
+	beer, err := NewProduct("Beer", "Halal Beer", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, price := range []float64{6, 7, 8} {
+		if err := beer.ChangePrice(price, now.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a repository hands out copies of the product, changing one leaves the others as they are
+	fetched := beer
+	if err := fetched.ChangePrice(10, now.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	history := beer.GetPriceHistory()
+	if len(history) != 3 {
+		t.Fatalf("expected 3 price changes, got %d", len(history))
+	}
+	for i, expected := range []float64{6, 7, 8} {
+		if history[i].New() != expected || history[i].EffectiveAt() != now.Add(time.Duration(i)*time.Hour) {
+			t.Errorf("expected change %d to %v to be kept, got %v", i, expected, history[i].New())
+		}
+	}
+	if len(fetched.GetPriceHistory()) != 4 || fetched.PriceAt(now.Add(45*time.Minute)) != 10 {
+		t.Errorf("expected the copy to have the new change, got %v", fetched.GetPriceHistory())
+	}
+}
@@ -0,0 +1,275 @@
+package aggregate
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPromotion       = errors.New("invalid promotion")
+	ErrPromotionExpired       = errors.New("promotion has expired")
+	ErrMinSpendNotMet         = errors.New("order does not reach the minimum spend of the promotion")
+	ErrUsageLimitReached      = errors.New("customer has reached the usage limit of the promotion")
+	ErrPromotionNotApplicable = errors.New("promotion does not apply to this order")
+)
+
+type PromotionKind string
+
+const (
+	PercentageOff  PromotionKind = "percentage_off"
+	FixedAmountOff PromotionKind = "fixed_amount_off"
+	BuyXGetY       PromotionKind = "buy_x_get_y"
+)
+
+// PromotionConfiguration sets optional conditions of a new promotion
+type PromotionConfiguration func(p *Promotion) error
+
+// Promotion is a discount customers get on an order by entering its code
+type Promotion struct {
+	id   uuid.UUID
+	code string
+	kind PromotionKind
+	// value is a percentage for PercentageOff and an amount for FixedAmountOff
+	value float64
+
+	// productID, buy and free describe BuyXGetY: buy units of the product, get free more
+	productID uuid.UUID
+	buy       int
+	free      int
+
+	minSpend  float64
+	expiresAt time.Time
+	// usageLimit is the number of times each customer can use the code, 0 for unlimited
+	usageLimit int
+	usage      map[uuid.UUID]int
+
+	// version is incremented by repositories on every update, so concurrent
+	// redemptions of the same promotion can be detected
+	version int
+}
+
+func NewPercentageOff(code string, percent float64, cfgs ...PromotionConfiguration) (Promotion, error) {
+	if percent <= 0 || percent > 100 {
+		return Promotion{}, ErrInvalidPromotion
+	}
+
+	return newPromotion(code, PercentageOff, percent, cfgs)
+}
+
+func NewFixedAmountOff(code string, amount float64, cfgs ...PromotionConfiguration) (Promotion, error) {
+	if amount <= 0 {
+		return Promotion{}, ErrInvalidPromotion
+	}
+
+	return newPromotion(code, FixedAmountOff, amount, cfgs)
+}
+
+// NewBuyXGetY makes every free units of the product free once buy units are ordered,
+// e.g. buy 2 get 1 makes one unit out of every three free
+func NewBuyXGetY(code string, productID uuid.UUID, buy, free int, cfgs ...PromotionConfiguration) (Promotion, error) {
+	if productID == uuid.Nil || buy <= 0 || free <= 0 {
+		return Promotion{}, ErrInvalidPromotion
+	}
+
+	cfgs = append([]PromotionConfiguration{func(p *Promotion) error {
+		p.productID = productID
+		p.buy = buy
+		p.free = free
+		return nil
+	}}, cfgs...)
+
+	return newPromotion(code, BuyXGetY, 0, cfgs)
+}
+
+func newPromotion(code string, kind PromotionKind, value float64, cfgs []PromotionConfiguration) (Promotion, error) {
+	code = NormalizeCode(code)
+	if code == "" {
+		return Promotion{}, ErrInvalidPromotion
+	}
+
+	p := Promotion{
+		id:    uuid.New(),
+		code:  code,
+		kind:  kind,
+		value: value,
+		usage: map[uuid.UUID]int{},
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(&p); err != nil {
+			return Promotion{}, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithMinSpend only applies the promotion to orders of at least amount
+func WithMinSpend(amount float64) PromotionConfiguration {
+	return func(p *Promotion) error {
+		if amount < 0 {
+			return ErrInvalidPromotion
+		}
+
+		p.minSpend = amount
+		return nil
+	}
+}
+
+// WithExpiry makes the promotion unusable from expiresAt on
+func WithExpiry(expiresAt time.Time) PromotionConfiguration {
+	return func(p *Promotion) error {
+		p.expiresAt = expiresAt
+		return nil
+	}
+}
+
+// WithUsageLimit lets each customer use the promotion limit times
+func WithUsageLimit(limit int) PromotionConfiguration {
+	return func(p *Promotion) error {
+		if limit < 0 {
+			return ErrInvalidPromotion
+		}
+
+		p.usageLimit = limit
+		return nil
+	}
+}
+
+// NormalizeCode makes codes case and whitespace insensitive
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (p *Promotion) GetID() uuid.UUID {
+	return p.id
+}
+
+func (p *Promotion) SetID(id uuid.UUID) {
+	p.id = id
+}
+
+func (p *Promotion) GetCode() string {
+	return p.code
+}
+
+func (p *Promotion) GetKind() PromotionKind {
+	return p.kind
+}
+
+func (p *Promotion) GetValue() float64 {
+	return p.value
+}
+
+// GetBuyXGetY returns the product, the units to buy and the units offered of a BuyXGetY promotion
+func (p *Promotion) GetBuyXGetY() (uuid.UUID, int, int) {
+	return p.productID, p.buy, p.free
+}
+
+func (p *Promotion) GetMinSpend() float64 {
+	return p.minSpend
+}
+
+func (p *Promotion) GetExpiresAt() time.Time {
+	return p.expiresAt
+}
+
+func (p *Promotion) GetUsageLimit() int {
+	return p.usageLimit
+}
+
+// GetUsage returns how many times each customer used the promotion
+func (p *Promotion) GetUsage() map[uuid.UUID]int {
+	return p.usage
+}
+
+func (p *Promotion) SetUsage(usage map[uuid.UUID]int) {
+	p.usage = map[uuid.UUID]int{}
+	for customerID, count := range usage {
+		p.usage[customerID] = count
+	}
+}
+
+func (p *Promotion) GetVersion() int {
+	return p.version
+}
+
+func (p *Promotion) SetVersion(version int) {
+	p.version = version
+}
+
+// Discount computes what the promotion takes off the order at the given time,
+// it fails when any condition of the promotion is not met
+func (p *Promotion) Discount(order Order, at time.Time) (float64, error) {
+	if !p.expiresAt.IsZero() && !at.Before(p.expiresAt) {
+		return 0, ErrPromotionExpired
+	}
+	if p.usageLimit > 0 && p.usage[order.GetCustomerID()] >= p.usageLimit {
+		return 0, ErrUsageLimitReached
+	}
+
+	subtotal := order.GetSubtotal()
+	if subtotal < p.minSpend {
+		return 0, ErrMinSpendNotMet
+	}
+
+	var discount float64
+	switch p.kind {
+	case PercentageOff:
+		discount = subtotal * p.value / 100
+	case FixedAmountOff:
+		discount = p.value
+	case BuyXGetY:
+		var prices []float64
+		for _, l := range order.GetLines() {
+			if l.ProductID == p.productID {
+				prices = append(prices, l.Total())
+			}
+		}
+
+		freeUnits := len(prices) / (p.buy + p.free) * p.free
+		if freeUnits == 0 {
+			return 0, ErrPromotionNotApplicable
+		}
+
+		// the cheapest units are the free ones
+		sort.Float64s(prices)
+		for _, price := range prices[:freeUnits] {
+			discount += price
+		}
+	default:
+		return 0, ErrInvalidPromotion
+	}
+
+	// never discount more than what is left after the other discounts of the order
+	if due := subtotal - order.GetOrderDiscount(); discount > due {
+		discount = due
+	}
+
+	return RoundMoney(discount), nil
+}
+
+// Redeem records a use of the promotion by the customer
+func (p *Promotion) Redeem(customerID uuid.UUID) error {
+	if p.usageLimit > 0 && p.usage[customerID] >= p.usageLimit {
+		return ErrUsageLimitReached
+	}
+
+	if p.usage == nil {
+		p.usage = map[uuid.UUID]int{}
+	}
+	p.usage[customerID]++
+
+	return nil
+}
+
+// Unredeem takes back a use of the promotion by the customer, for an order that was never paid
+func (p *Promotion) Unredeem(customerID uuid.UUID) {
+	if p.usage[customerID] > 0 {
+		p.usage[customerID]--
+	}
+}
@@ -0,0 +1,180 @@
+package aggregate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPromotion_Discount(t *testing.T) {
+	now := time.Date(2023, time.June, 2, 12, 0, 0, 0, time.UTC)
+	customerID := uuid.New()
+	beerID, baksoID := uuid.New(), uuid.New()
+
+	order, err := NewOrder(customerID, []OrderLine{
+		{ProductID: beerID, Name: "Beer", UnitPrice: 6},
+		{ProductID: beerID, Name: "Beer", UnitPrice: 6, Discount: 2},
+		{ProductID: beerID, Name: "Beer", UnitPrice: 6},
+		{ProductID: baksoID, Name: "Bakso Kuah", UnitPrice: 2},
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustPromotion := func(p Promotion, err error) Promotion {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	used := mustPromotion(NewPercentageOff("once", 10, WithUsageLimit(1)))
+	if err := used.Redeem(customerID); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		promotion   Promotion
+		expected    float64
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:      "percentage off the subtotal",
+			promotion: mustPromotion(NewPercentageOff("tenoff", 10)),
+			expected:  1.8,
+		},
+		{
+			name:      "fixed amount off",
+			promotion: mustPromotion(NewFixedAmountOff("five", 5)),
+			expected:  5,
+		},
+		{
+			name:      "fixed amount is capped at the subtotal",
+			promotion: mustPromotion(NewFixedAmountOff("fifty", 50)),
+			expected:  18,
+		},
+		{
+			name:      "buy 2 get 1 makes the cheapest unit free",
+			promotion: mustPromotion(NewBuyXGetY("beer3", beerID, 2, 1)),
+			expected:  4,
+		},
+		{
+			name:        "buy x get y without enough units",
+			promotion:   mustPromotion(NewBuyXGetY("bakso2", baksoID, 1, 1)),
+			expectedErr: ErrPromotionNotApplicable,
+		},
+		{
+			name:        "minimum spend not reached",
+			promotion:   mustPromotion(NewPercentageOff("big", 20, WithMinSpend(20))),
+			expectedErr: ErrMinSpendNotMet,
+		},
+		{
+			name:        "expired",
+			promotion:   mustPromotion(NewPercentageOff("old", 20, WithExpiry(now))),
+			expectedErr: ErrPromotionExpired,
+		},
+		{
+			name:      "not expired yet",
+			promotion: mustPromotion(NewPercentageOff("new", 20, WithExpiry(now.Add(time.Hour)))),
+			expected:  3.6,
+		},
+		{
+			name:        "usage limit reached",
+			promotion:   used,
+			expectedErr: ErrUsageLimitReached,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			discount, err := tc.promotion.Discount(order, now)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if discount != tc.expected {
+				t.Errorf("expected discount %v, got %v", tc.expected, discount)
+			}
+		})
+	}
+}
+
+func TestPromotion_NewPromotion(t *testing.T) {
+	type testCase struct {
+		name        string
+		new         func() (Promotion, error)
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:        "empty code",
+			new:         func() (Promotion, error) { return NewPercentageOff("  ", 10) },
+			expectedErr: ErrInvalidPromotion,
+		},
+		{
+			name:        "percentage above 100",
+			new:         func() (Promotion, error) { return NewPercentageOff("free", 110) },
+			expectedErr: ErrInvalidPromotion,
+		},
+		{
+			name:        "negative amount",
+			new:         func() (Promotion, error) { return NewFixedAmountOff("less", -1) },
+			expectedErr: ErrInvalidPromotion,
+		},
+		{
+			name:        "buy x get y without product",
+			new:         func() (Promotion, error) { return NewBuyXGetY("b2g1", uuid.Nil, 2, 1) },
+			expectedErr: ErrInvalidPromotion,
+		},
+		{
+			name: "valid code is normalized",
+			new:  func() (Promotion, error) { return NewPercentageOff(" summer10 ", 10) },
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := tc.new()
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && p.GetCode() != "SUMMER10" {
+				t.Errorf("expected code %q, got %q", "SUMMER10", p.GetCode())
+			}
+		})
+	}
+}
+
+func TestPromotion_Redeem(t *testing.T) {
+	p, err := NewFixedAmountOff("twice", 1, WithUsageLimit(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	customerID := uuid.New()
+	for i := 0; i < 2; i++ {
+		if err := p.Redeem(customerID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Redeem(customerID); !errors.Is(err, ErrUsageLimitReached) {
+		t.Errorf("expected error %v, got %v", ErrUsageLimitReached, err)
+	}
+	if err := p.Redeem(uuid.New()); err != nil {
+		t.Errorf("expected another customer to redeem, got %v", err)
+	}
+
+	// a use taken back can be redeemed again
+	p.Unredeem(customerID)
+	if err := p.Redeem(customerID); err != nil {
+		t.Errorf("expected the use taken back to be redeemed, got %v", err)
+	}
+	p.Unredeem(uuid.New())
+	if p.GetUsage()[customerID] != 2 {
+		t.Errorf("expected a usage of 2, got %d", p.GetUsage()[customerID])
+	}
+}
@@ -0,0 +1,374 @@
+package aggregate
+
+import (
+	"errors"
+	"golang-learn-ddd/valueobject"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOrderPaid     = errors.New("the order is paid already")
+	ErrOrderNotPaid  = errors.New("the order is not paid")
+	ErrInvalidRefund = errors.New("a refund has to give back part of what was paid and not refunded yet, each line once")
+	ErrInvalidExtra  = errors.New("only service charges and tips are paid along with an order")
+)
+
+type OrderStatus string
+
+const (
+	// OrderUnpaid orders are created but not billed yet
+	OrderUnpaid            OrderStatus = ""
+	OrderPaid              OrderStatus = "paid"
+	OrderPartiallyRefunded OrderStatus = "partially_refunded"
+	OrderRefunded          OrderStatus = "refunded"
+)
+
+type PaymentMethod string
+
+const (
+	// PaymentAccount only records the payment on the account of the customer
+	PaymentAccount PaymentMethod = "account"
+	// PaymentWallet takes the payment from the prepaid credit of the customer
+	PaymentWallet PaymentMethod = "wallet"
+	// PaymentCard charges the card of the customer through a payment gateway
+	PaymentCard PaymentMethod = "card"
+)
+
+// Refund gives back money paid for an order
+type Refund struct {
+	ID uuid.UUID
+	// Lines are the indexes of the refunded lines, none when an amount was refunded
+	Lines  []int
+	Reason string
+	// Transactions give the money back to the payers, from the tavern
+	Transactions []valueobject.Transaction
+	CreatedAt    time.Time
+}
+
+// Amount is what the refund gives back to every payer
+func (r Refund) Amount() float64 {
+	var cents int
+	for _, t := range r.Transactions {
+		cents += t.Amount()
+	}
+
+	return float64(cents) / 100
+}
+
+// Pay records the payments of the order, one transaction per payer for
+// their share of the total
+func (o *Order) Pay(method PaymentMethod, payments []valueobject.Transaction, at time.Time) error {
+	if o.status != OrderUnpaid {
+		return ErrOrderPaid
+	}
+
+	o.status = OrderPaid
+	o.paymentMethod = method
+	o.payments = append([]valueobject.Transaction(nil), payments...)
+	o.paidAt = at
+
+	return nil
+}
+
+func (o *Order) GetStatus() OrderStatus {
+	return o.status
+}
+
+func (o *Order) GetPaymentMethod() PaymentMethod {
+	return o.paymentMethod
+}
+
+func (o *Order) GetPayments() []valueobject.Transaction {
+	return o.payments
+}
+
+func (o *Order) GetPaidAt() time.Time {
+	return o.paidAt
+}
+
+func (o *Order) GetRefunds() []Refund {
+	return o.refunds
+}
+
+// GetCharged is what the payers paid for the order
+func (o *Order) GetCharged() float64 {
+	var cents int
+	for _, p := range o.payments {
+		cents += p.Amount()
+	}
+
+	return float64(cents) / 100
+}
+
+// AddExtras records the service charges and tips the payers paid along
+// with the order
+func (o *Order) AddExtras(extras []valueobject.Transaction) error {
+	if o.status == OrderUnpaid {
+		return ErrOrderNotPaid
+	}
+	for _, t := range extras {
+		if t.Kind() != valueobject.TransactionServiceCharge && t.Kind() != valueobject.TransactionTip {
+			return ErrInvalidExtra
+		}
+	}
+
+	o.extras = append(append([]valueobject.Transaction(nil), o.extras...), extras...)
+
+	return nil
+}
+
+func (o *Order) GetExtras() []valueobject.Transaction {
+	return o.extras
+}
+
+// GetServiceCharge is the service charge paid along with the order
+func (o *Order) GetServiceCharge() float64 {
+	return o.extra(valueobject.TransactionServiceCharge)
+}
+
+// GetTip is the tip paid along with the order
+func (o *Order) GetTip() float64 {
+	return o.extra(valueobject.TransactionTip)
+}
+
+func (o *Order) extra(kind valueobject.TransactionKind) float64 {
+	var cents int
+	for _, t := range o.extras {
+		if t.Kind() == kind {
+			cents += t.Amount()
+		}
+	}
+
+	return float64(cents) / 100
+}
+
+// GetRefunded is what the refunds of the order gave back
+func (o *Order) GetRefunded() float64 {
+	var refunded float64
+	for _, r := range o.refunds {
+		refunded += r.Amount()
+	}
+
+	return RoundMoney(refunded)
+}
+
+// IsLineRefunded tells whether a refund gave back the line at index line
+func (o *Order) IsLineRefunded(line int) bool {
+	for _, r := range o.refunds {
+		for _, l := range r.Lines {
+			if l == line {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// RefundAmount prepares the refund of amount, shared between the payers in
+// proportion of what is left to refund to each of them. It is recorded by AddRefund.
+func (o *Order) RefundAmount(amount float64, reason string, at time.Time) (Refund, error) {
+	return o.newRefund(valueobject.Cents(amount), nil, reason, at)
+}
+
+// RefundLines prepares the refund of the lines, given by index. Every line
+// is worth its share of what was paid for the order, so the discounts and
+// the taxes of the order are refunded along, and refunding every line gives
+// back everything left. It is recorded by AddRefund.
+func (o *Order) RefundLines(lines []int, reason string, at time.Time) (Refund, error) {
+	if len(lines) == 0 {
+		return Refund{}, ErrInvalidRefund
+	}
+	if err := o.checkLines(lines); err != nil {
+		return Refund{}, err
+	}
+
+	var charged int
+	for _, p := range o.payments {
+		charged += p.Amount()
+	}
+
+	weights := make([]int, len(o.lines))
+	for i, l := range o.lines {
+		weights[i] = valueobject.Cents(l.Total())
+	}
+	worth, err := valueobject.Allocate(charged, weights)
+	if err != nil {
+		// every line is free, there is nothing to give back
+		return Refund{}, ErrInvalidRefund
+	}
+
+	var amount int
+	for _, l := range lines {
+		amount += worth[l]
+	}
+
+	_, left := o.refundable()
+	var total int
+	for _, cents := range left {
+		total += cents
+	}
+	if o.countRefundedLines()+len(lines) == len(o.lines) || amount > total {
+		amount = total
+	}
+
+	return o.newRefund(amount, lines, reason, at)
+}
+
+func (o *Order) newRefund(amount int, lines []int, reason string, at time.Time) (Refund, error) {
+	if o.status == OrderUnpaid {
+		return Refund{}, ErrOrderNotPaid
+	}
+
+	payments, left := o.refundable()
+	var total int
+	for _, cents := range left {
+		total += cents
+	}
+	if amount <= 0 || amount > total {
+		return Refund{}, ErrInvalidRefund
+	}
+
+	shares, err := valueobject.Allocate(amount, left)
+	if err != nil {
+		return Refund{}, ErrInvalidRefund
+	}
+
+	transactions := make([]valueobject.Transaction, 0, len(payments))
+	for i, p := range payments {
+		if shares[i] == 0 {
+			continue
+		}
+		transactions = append(transactions, valueobject.NewTransaction(
+			valueobject.TransactionRefund, shares[i], p.To(), p.From(), at,
+		).WithReference(p.Reference()))
+	}
+
+	return Refund{
+		ID:           uuid.New(),
+		Lines:        append([]int(nil), lines...),
+		Reason:       reason,
+		Transactions: transactions,
+		CreatedAt:    at,
+	}, nil
+}
+
+// AddRefund records a refund of the order, which cannot give any payer more
+// than is left of their payment nor refund a line twice
+func (o *Order) AddRefund(r Refund) error {
+	if o.status == OrderUnpaid {
+		return ErrOrderNotPaid
+	}
+	if len(r.Transactions) == 0 {
+		return ErrInvalidRefund
+	}
+	if err := o.checkLines(r.Lines); err != nil {
+		return err
+	}
+
+	payments, left := o.refundable()
+	for _, t := range r.Transactions {
+		i := -1
+		for j, p := range payments {
+			if p.From() == t.To() {
+				i = j
+				break
+			}
+		}
+		if i < 0 || t.Kind() != valueobject.TransactionRefund || t.Amount() <= 0 || t.Amount() > left[i] {
+			return ErrInvalidRefund
+		}
+		left[i] -= t.Amount()
+	}
+
+	// a new slice keeps copies of the order from sharing their refunds
+	o.refunds = append(append([]Refund(nil), o.refunds...), r)
+	o.updateStatus()
+
+	return nil
+}
+
+// CancelRefund forgets a refund that could not be given back
+func (o *Order) CancelRefund(id uuid.UUID) {
+	refunds := make([]Refund, 0, len(o.refunds))
+	for _, r := range o.refunds {
+		if r.ID != id {
+			refunds = append(refunds, r)
+		}
+	}
+
+	o.refunds = refunds
+	o.updateStatus()
+}
+
+// refundable returns the first payment of every payer, with the cents
+// left to refund to them
+func (o *Order) refundable() ([]valueobject.Transaction, []int) {
+	var payments []valueobject.Transaction
+	var left []int
+	index := map[uuid.UUID]int{}
+	for _, p := range o.payments {
+		i, ok := index[p.From()]
+		if !ok {
+			i = len(payments)
+			index[p.From()] = i
+			payments = append(payments, p)
+			left = append(left, 0)
+		}
+		left[i] += p.Amount()
+	}
+
+	for _, r := range o.refunds {
+		for _, t := range r.Transactions {
+			if i, ok := index[t.To()]; ok {
+				left[i] -= t.Amount()
+			}
+		}
+	}
+
+	return payments, left
+}
+
+func (o *Order) checkLines(lines []int) error {
+	seen := map[int]bool{}
+	for _, l := range lines {
+		if l < 0 || l >= len(o.lines) || seen[l] || o.IsLineRefunded(l) {
+			return ErrInvalidRefund
+		}
+		seen[l] = true
+	}
+
+	return nil
+}
+
+func (o *Order) countRefundedLines() int {
+	var count int
+	for _, r := range o.refunds {
+		count += len(r.Lines)
+	}
+
+	return count
+}
+
+func (o *Order) updateStatus() {
+	if o.status == OrderUnpaid {
+		return
+	}
+
+	_, left := o.refundable()
+	var total int
+	for _, cents := range left {
+		total += cents
+	}
+
+	switch {
+	case len(o.refunds) == 0:
+		o.status = OrderPaid
+	case total == 0:
+		o.status = OrderRefunded
+	default:
+		o.status = OrderPartiallyRefunded
+	}
+}
@@ -0,0 +1,219 @@
+package aggregate
+
+import (
+	"errors"
+	"golang-learn-ddd/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// paidOrder is a 30.00 order of three lines paid 18.00 by the first payer and 12.00 by the second
+func paidOrder(t *testing.T, tavern uuid.UUID, payers [2]uuid.UUID) Order {
+	t.Helper()
+
+	o, err := NewOrder(payers[0], []OrderLine{
+		{ProductID: uuid.New(), UnitPrice: 10},
+		{ProductID: uuid.New(), UnitPrice: 15, Discount: 5},
+		{ProductID: uuid.New(), UnitPrice: 10},
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = o.Pay(PaymentCard, []valueobject.Transaction{
+		valueobject.NewTransaction(valueobject.TransactionPayment, 1800, payers[0], tavern, time.Now()).WithReference("pay-1"),
+		valueobject.NewTransaction(valueobject.TransactionPayment, 1200, payers[1], tavern, time.Now()).WithReference("pay-2"),
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return o
+}
+
+func TestOrder_Refund(t *testing.T) {
+	tavern := uuid.New()
+	payers := [2]uuid.UUID{uuid.New(), uuid.New()}
+
+	type step struct {
+		lines  []int
+		amount float64
+		// expected is what every payer gets back
+		expected    [2]int
+		status      OrderStatus
+		expectedErr error
+	}
+
+	type testCase struct {
+		test  string
+		steps []step
+	}
+
+	testCases := []testCase{
+		{
+			test: "Lines then the rest",
+			steps: []step{
+				{lines: []int{1}, expected: [2]int{600, 400}, status: OrderPartiallyRefunded},
+				{lines: []int{1}, expectedErr: ErrInvalidRefund},
+				{lines: []int{0, 2}, expected: [2]int{1200, 800}, status: OrderRefunded},
+				{amount: 1, expectedErr: ErrInvalidRefund},
+			},
+		}, {
+			test: "Amounts",
+			steps: []step{
+				{amount: 0.05, expected: [2]int{3, 2}, status: OrderPartiallyRefunded},
+				{amount: 30, expectedErr: ErrInvalidRefund},
+				{amount: -1, expectedErr: ErrInvalidRefund},
+				{amount: 29.95, expected: [2]int{1797, 1198}, status: OrderRefunded},
+			},
+		}, {
+			test: "Lines capped by an amount refunded before",
+			steps: []step{
+				{amount: 25, expected: [2]int{1500, 1000}, status: OrderPartiallyRefunded},
+				{lines: []int{0}, expected: [2]int{300, 200}, status: OrderRefunded},
+			},
+		}, {
+			test: "Unknown line",
+			steps: []step{
+				{lines: []int{3}, expectedErr: ErrInvalidRefund},
+				{lines: []int{0, 0}, expectedErr: ErrInvalidRefund},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			o := paidOrder(t, tavern, payers)
+
+			for i, s := range tc.steps {
+				var refund Refund
+				var err error
+				if s.lines != nil {
+					refund, err = o.RefundLines(s.lines, "cold", time.Now())
+				} else {
+					refund, err = o.RefundAmount(s.amount, "cold", time.Now())
+				}
+				if err == nil {
+					err = o.AddRefund(refund)
+				}
+				if !errors.Is(err, s.expectedErr) {
+					t.Fatalf("step %d: expected error %v, got %v", i, s.expectedErr, err)
+				}
+				if err != nil {
+					continue
+				}
+
+				var got [2]int
+				for _, tr := range refund.Transactions {
+					if tr.Kind() != valueobject.TransactionRefund || tr.From() != tavern {
+						t.Errorf("step %d: expected a refund from the tavern, got %v from %v", i, tr.Kind(), tr.From())
+					}
+					for j, payer := range payers {
+						if tr.To() == payer {
+							got[j] += tr.Amount()
+						}
+					}
+				}
+				if got != s.expected {
+					t.Errorf("step %d: expected refunds %v, got %v", i, s.expected, got)
+				}
+				if o.GetStatus() != s.status {
+					t.Errorf("step %d: expected status %q, got %q", i, s.status, o.GetStatus())
+				}
+			}
+		})
+	}
+}
+
+func TestOrder_RefundRules(t *testing.T) {
+	tavern := uuid.New()
+	payers := [2]uuid.UUID{uuid.New(), uuid.New()}
+
+	unpaid, err := NewOrder(payers[0], []OrderLine{{ProductID: uuid.New(), UnitPrice: 10}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unpaid.RefundAmount(1, "", time.Now()); !errors.Is(err, ErrOrderNotPaid) {
+		t.Errorf("expected error %v, got %v", ErrOrderNotPaid, err)
+	}
+
+	o := paidOrder(t, tavern, payers)
+	if err := o.Pay(PaymentCard, nil, time.Now()); !errors.Is(err, ErrOrderPaid) {
+		t.Errorf("expected error %v, got %v", ErrOrderPaid, err)
+	}
+
+	// nobody gets more than they paid
+	tooMuch := Refund{ID: uuid.New(), Transactions: []valueobject.Transaction{
+		valueobject.NewTransaction(valueobject.TransactionRefund, 1201, tavern, payers[1], time.Now()),
+	}}
+	if err := o.AddRefund(tooMuch); !errors.Is(err, ErrInvalidRefund) {
+		t.Errorf("expected error %v, got %v", ErrInvalidRefund, err)
+	}
+
+	refund, err := o.RefundLines([]int{0}, "cold", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.AddRefund(refund); err != nil {
+		t.Fatal(err)
+	}
+	if !o.IsLineRefunded(0) || o.GetRefunded() != 10 || o.GetCharged() != 30 {
+		t.Errorf("expected line 0 refunded for 10 out of 30, got refunded %v of %v", o.GetRefunded(), o.GetCharged())
+	}
+	for _, tr := range refund.Transactions {
+		if tr.Reference() == "" {
+			t.Errorf("expected the refund to reference the card payment, got none")
+		}
+	}
+
+	o.CancelRefund(refund.ID)
+	if o.IsLineRefunded(0) || o.GetRefunded() != 0 || o.GetStatus() != OrderPaid {
+		t.Errorf("expected the refund to be cancelled, got %v refunded and status %q", o.GetRefunded(), o.GetStatus())
+	}
+}
+
+func TestOrder_AddExtras(t *testing.T) {
+	tavern := uuid.New()
+	payers := [2]uuid.UUID{uuid.New(), uuid.New()}
+	tip := valueobject.NewTransaction(valueobject.TransactionTip, 150, payers[0], tavern, time.Now())
+
+	unpaid, err := NewOrder(payers[0], []OrderLine{{ProductID: uuid.New(), UnitPrice: 10}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unpaid.AddExtras([]valueobject.Transaction{tip}); !errors.Is(err, ErrOrderNotPaid) {
+		t.Errorf("expected error %v, got %v", ErrOrderNotPaid, err)
+	}
+
+	o := paidOrder(t, tavern, payers)
+	payment := valueobject.NewTransaction(valueobject.TransactionPayment, 100, payers[0], tavern, time.Now())
+	if err := o.AddExtras([]valueobject.Transaction{payment}); !errors.Is(err, ErrInvalidExtra) {
+		t.Errorf("expected error %v, got %v", ErrInvalidExtra, err)
+	}
+
+	err = o.AddExtras([]valueobject.Transaction{
+		tip,
+		valueobject.NewTransaction(valueobject.TransactionServiceCharge, 300, payers[0], tavern, time.Now()),
+		valueobject.NewTransaction(valueobject.TransactionTip, 50, payers[1], tavern, time.Now()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.GetServiceCharge() != 3 || o.GetTip() != 2 || o.GetCharged() != 30 {
+		t.Errorf("expected a service charge of 3 and a tip of 2 on top of 30, got %v, %v and %v", o.GetServiceCharge(), o.GetTip(), o.GetCharged())
+	}
+
+	// the extras are not given back with the order
+	refund, err := o.RefundAmount(30, "", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.AddRefund(refund); err != nil {
+		t.Fatal(err)
+	}
+	if o.GetStatus() != OrderRefunded {
+		t.Errorf("expected the order to be refunded, got %q", o.GetStatus())
+	}
+}
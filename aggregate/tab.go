@@ -0,0 +1,124 @@
+package aggregate
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidTab     = errors.New("a tab has to belong to a customer and a table")
+	ErrTabClosed      = errors.New("the tab is closed")
+	ErrTabNotCustomer = errors.New("the order does not belong to the customer of the tab")
+)
+
+// Tab is the running bill of a customer at a table, settled in one charge when closed
+type Tab struct {
+	id         uuid.UUID
+	customerID uuid.UUID
+	table      string
+	orders     []Order
+	openedAt   time.Time
+	closedAt   time.Time
+
+	// version is incremented by repositories on every update, so concurrent
+	// updates of the same tab can be detected
+	version int
+}
+
+func NewTab(customerID uuid.UUID, table string, openedAt time.Time) (Tab, error) {
+	if customerID == uuid.Nil || table == "" {
+		return Tab{}, ErrInvalidTab
+	}
+
+	return Tab{
+		id:         uuid.New(),
+		customerID: customerID,
+		table:      table,
+		orders:     make([]Order, 0),
+		openedAt:   openedAt,
+	}, nil
+}
+
+func (t *Tab) GetID() uuid.UUID {
+	return t.id
+}
+
+func (t *Tab) SetID(id uuid.UUID) {
+	t.id = id
+}
+
+func (t *Tab) GetCustomerID() uuid.UUID {
+	return t.customerID
+}
+
+func (t *Tab) GetTable() string {
+	return t.table
+}
+
+func (t *Tab) GetOrders() []Order {
+	return t.orders
+}
+
+func (t *Tab) SetOrders(orders []Order) {
+	t.orders = append([]Order(nil), orders...)
+}
+
+func (t *Tab) GetOpenedAt() time.Time {
+	return t.openedAt
+}
+
+func (t *Tab) GetClosedAt() time.Time {
+	return t.closedAt
+}
+
+func (t *Tab) IsOpen() bool {
+	return t.closedAt.IsZero()
+}
+
+// AddOrder puts an order of the customer on the tab
+func (t *Tab) AddOrder(order Order) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+	if order.GetCustomerID() != t.customerID {
+		return ErrTabNotCustomer
+	}
+
+	t.orders = append(t.orders, order)
+	return nil
+}
+
+// Close stops the tab from taking more orders
+func (t *Tab) Close(at time.Time) error {
+	if !t.IsOpen() {
+		return ErrTabClosed
+	}
+
+	t.closedAt = at
+	return nil
+}
+
+// Reopen takes orders again on a tab whose settlement failed
+func (t *Tab) Reopen() {
+	t.closedAt = time.Time{}
+}
+
+func (t *Tab) GetVersion() int {
+	return t.version
+}
+
+func (t *Tab) SetVersion(version int) {
+	t.version = version
+}
+
+// GetTotal is what the customer pays for every order of the tab
+func (t *Tab) GetTotal() float64 {
+	var total float64
+	for _, o := range t.orders {
+		total += o.GetTotal()
+	}
+
+	return RoundMoney(total)
+}
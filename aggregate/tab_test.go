@@ -0,0 +1,77 @@
+package aggregate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTab_AddOrder(t *testing.T) {
+	now := time.Now()
+	customerID := uuid.New()
+
+	tab, err := NewTab(customerID, "12", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newOrder := func(customerID uuid.UUID, price float64) Order {
+		t.Helper()
+		o, err := NewOrder(customerID, []OrderLine{{ProductID: uuid.New(), UnitPrice: price}}, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+
+	if err := tab.AddOrder(newOrder(customerID, 2.5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tab.AddOrder(newOrder(customerID, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tab.AddOrder(newOrder(uuid.New(), 4)); !errors.Is(err, ErrTabNotCustomer) {
+		t.Errorf("expected error %v, got %v", ErrTabNotCustomer, err)
+	}
+	if tab.GetTotal() != 6.5 {
+		t.Errorf("expected total %v, got %v", 6.5, tab.GetTotal())
+	}
+
+	if err := tab.Close(now); err != nil {
+		t.Fatal(err)
+	}
+	if tab.IsOpen() {
+		t.Error("expected the tab to be closed")
+	}
+	if err := tab.AddOrder(newOrder(customerID, 1)); !errors.Is(err, ErrTabClosed) {
+		t.Errorf("expected error %v, got %v", ErrTabClosed, err)
+	}
+	if err := tab.Close(now); !errors.Is(err, ErrTabClosed) {
+		t.Errorf("expected error %v, got %v", ErrTabClosed, err)
+	}
+}
+
+func TestTab_NewTab(t *testing.T) {
+	type testCase struct {
+		name        string
+		customerID  uuid.UUID
+		table       string
+		expectedErr error
+	}
+	tests := []testCase{
+		{name: "valid", customerID: uuid.New(), table: "12"},
+		{name: "without customer", customerID: uuid.Nil, table: "12", expectedErr: ErrInvalidTab},
+		{name: "without table", customerID: uuid.New(), table: "", expectedErr: ErrInvalidTab},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewTab(tc.customerID, tc.table, time.Now())
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
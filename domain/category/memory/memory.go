@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/category"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type memoryRepository struct {
+	categories map[uuid.UUID]aggregate.Category
+	sync.Mutex
+}
+
+func New() category.CategoryRepository {
+	return &memoryRepository{
+		categories: map[uuid.UUID]aggregate.Category{},
+	}
+}
+
+func (r *memoryRepository) GetAll(ctx context.Context) ([]aggregate.Category, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	categories := make([]aggregate.Category, 0, len(r.categories))
+	for _, c := range r.categories {
+		categories = append(categories, c)
+	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := categories[i], categories[j]
+		if a.GetPosition() != b.GetPosition() {
+			return a.GetPosition() < b.GetPosition()
+		}
+
+		return a.GetName() < b.GetName()
+	})
+
+	return categories, nil
+}
+
+func (r *memoryRepository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Category, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if c, ok := r.categories[id]; ok {
+		return c, nil
+	}
+
+	return aggregate.Category{}, category.ErrCategoryNotFound
+}
+
+func (r *memoryRepository) Add(ctx context.Context, c aggregate.Category) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.categories[c.GetID()]; ok {
+		return fmt.Errorf("category already exists :%w", category.ErrFailedToAddCategory)
+	}
+
+	r.categories[c.GetID()] = c
+
+	return nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, c aggregate.Category) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.categories[c.GetID()]; !ok {
+		return fmt.Errorf("category is not exists :%w", category.ErrUpdateCategory)
+	}
+
+	r.categories[c.GetID()] = c
+
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.categories[id]; !ok {
+		return fmt.Errorf("category is not exists :%w", category.ErrDeleteCategory)
+	}
+
+	delete(r.categories, id)
+
+	return nil
+}
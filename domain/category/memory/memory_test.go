@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/category"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_memoryRepository_GetAll(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	for _, c := range []struct {
+		name     string
+		position int
+	}{
+		{"Snacks", 3},
+		{"Food", 2},
+		{"Drinks", 1},
+		{"Desserts", 2},
+	} {
+		cat, err := aggregate.NewCategory(c.name, c.position)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.Add(ctx, cat); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	categories, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, c := range categories {
+		names = append(names, c.GetName())
+	}
+
+	expected := []string{"Drinks", "Desserts", "Food", "Snacks"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected categories %v, got %v", expected, names)
+	}
+}
+
+func Test_memoryRepository_GetByID(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	drinks, err := aggregate.NewCategory("Drinks", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.Add(ctx, drinks)
+
+	type testCase struct {
+		name        string
+		id          uuid.UUID
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:        "category not found",
+			id:          uuid.New(),
+			expectedErr: category.ErrCategoryNotFound,
+		},
+		{
+			name:        "category found",
+			id:          drinks.GetID(),
+			expectedErr: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.GetByID(ctx, tt.id)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/category"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	db       *mongo.Database
+	category *mongo.Collection
+}
+
+// mongoCategory internal type to store CategoryAggregate to mongodb
+type mongoCategory struct {
+	ID       uuid.UUID `bson:"id"`
+	Name     string    `bson:"name"`
+	Position int       `bson:"position"`
+}
+
+func NewFromCategory(c aggregate.Category) mongoCategory {
+	return mongoCategory{
+		ID:       c.GetID(),
+		Name:     c.GetName(),
+		Position: c.GetPosition(),
+	}
+}
+
+func (m *mongoCategory) ToAggregate() (aggregate.Category, error) {
+	c, err := aggregate.NewCategory(m.Name, m.Position)
+	if err != nil {
+		return aggregate.Category{}, err
+	}
+
+	c.SetID(m.ID)
+
+	return c, nil
+}
+
+func New(ctx context.Context, connectionString string) (category.CategoryRepository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("learn-golang-ddd")
+	collection := db.Collection("categories")
+
+	return &mongoRepository{
+		db:       db,
+		category: collection,
+	}, nil
+}
+
+func (r *mongoRepository) GetAll(ctx context.Context) ([]aggregate.Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}})
+	cursor, err := r.category.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categories := []aggregate.Category{}
+	for cursor.Next(ctx) {
+		var row mongoCategory
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+
+		c, err := row.ToAggregate()
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, c)
+	}
+
+	return categories, cursor.Err()
+}
+
+func (r *mongoRepository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var row mongoCategory
+	err := r.category.FindOne(ctx, bson.M{"id": id}).Decode(&row)
+	if err != nil {
+		return aggregate.Category{}, fmt.Errorf("category does not exists: %w; %w", err, category.ErrCategoryNotFound)
+	}
+
+	return row.ToAggregate()
+}
+
+func (r *mongoRepository) Add(ctx context.Context, c aggregate.Category) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.category.InsertOne(ctx, NewFromCategory(c))
+	if err != nil {
+		return fmt.Errorf("failed to add a category: %w; %w", err, category.ErrFailedToAddCategory)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Update(ctx context.Context, c aggregate.Category) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := NewFromCategory(c)
+	filter := bson.M{"id": row.ID}
+	updateData := bson.M{
+		"$set": bson.M{
+			"name":     row.Name,
+			"position": row.Position,
+		},
+	}
+
+	res, err := r.category.UpdateOne(ctx, filter, updateData)
+	if err != nil {
+		return fmt.Errorf("failed to update a category: %w; %w", err, category.ErrUpdateCategory)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("category is not exists :%w", category.ErrUpdateCategory)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.category.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete a category: %w; %w", err, category.ErrDeleteCategory)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("category is not exists :%w", category.ErrDeleteCategory)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package category
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found in repository")
+	ErrFailedToAddCategory = errors.New("failed to add the category")
+	ErrUpdateCategory      = errors.New("failed to update the category")
+	ErrDeleteCategory      = errors.New("failed to delete the category")
+)
+
+type CategoryRepository interface {
+	// GetAll returns every category sorted by position, then name
+	GetAll(ctx context.Context) ([]aggregate.Category, error)
+	GetByID(ctx context.Context, id uuid.UUID) (aggregate.Category, error)
+	Add(ctx context.Context, category aggregate.Category) error
+	Update(ctx context.Context, category aggregate.Category) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
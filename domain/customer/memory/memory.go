@@ -1,9 +1,12 @@
 package memory
 
 import (
+	"context"
 	"fmt"
 	"golang-learn-ddd/aggregate"
 	"golang-learn-ddd/domain/customer"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/google/uuid"
@@ -20,50 +23,129 @@ func New() customer.CustomerRepository {
 	}
 }
 
-func (r *memoryRepository) Get(id uuid.UUID) (aggregate.Customer, error) {
+func (r *memoryRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Customer, error) {
+	r.Lock()
+	defer r.Unlock()
+
 	if customer, ok := r.customers[id]; ok {
-		return customer, nil
+		return copyCustomer(customer), nil
 	}
 
 	return aggregate.Customer{}, customer.ErrCustomerNotFound
 }
 
-func (r *memoryRepository) Add(c aggregate.Customer) error {
+func (r *memoryRepository) Add(ctx context.Context, c aggregate.Customer) error {
+	r.Lock()
+	defer r.Unlock()
+
 	// make sure customer is already in repository
 	if _, ok := r.customers[c.GetID()]; ok {
 		return fmt.Errorf("customer already exists :%w", customer.ErrFailedToAddCustomer)
 	}
 
 	// add customer to customer map
-	r.Lock()
-	r.customers[c.GetID()] = c
-	r.Unlock()
+	r.customers[c.GetID()] = copyCustomer(c)
 
 	return nil
 }
 
-func (r *memoryRepository) Update(c aggregate.Customer) error {
-	if _, ok := r.customers[c.GetID()]; !ok {
+func (r *memoryRepository) Update(ctx context.Context, c aggregate.Customer) error {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.customers[c.GetID()]
+	if !ok {
 		return fmt.Errorf("customer does not exists :%w", customer.ErrUpdateCustomer)
 	}
+	if stored.GetVersion() != c.GetVersion() {
+		return fmt.Errorf("customer is at version %d, not %d :%w", stored.GetVersion(), c.GetVersion(), customer.ErrConcurrentUpdate)
+	}
 
 	// overwrite customer
-	r.Lock()
+	c = copyCustomer(c)
+	c.SetVersion(c.GetVersion() + 1)
 	r.customers[c.GetID()] = c
-	r.Unlock()
 
 	return nil
 }
 
-func (r *memoryRepository) Delete(c aggregate.Customer) error {
+func (r *memoryRepository) Delete(ctx context.Context, c aggregate.Customer) error {
+	r.Lock()
+	defer r.Unlock()
+
 	if _, ok := r.customers[c.GetID()]; !ok {
 		return fmt.Errorf("customer does not exists :%w", customer.ErrDeleteCustomer)
 	}
 
 	// delete customer
-	r.Lock()
 	delete(r.customers, c.GetID())
-	r.Unlock()
 
 	return nil
 }
+
+func (r *memoryRepository) List(ctx context.Context, offset, limit int) (customer.Page, error) {
+	if offset < 0 || limit < 0 {
+		return customer.Page{}, customer.ErrInvalidPagination
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	customers := r.sorted(func(aggregate.Customer) bool { return true })
+	total := len(customers)
+
+	if offset >= total {
+		return customer.Page{Customers: []aggregate.Customer{}, Total: total}, nil
+	}
+
+	customers = customers[offset:]
+	if limit > 0 && limit < len(customers) {
+		customers = customers[:limit]
+	}
+
+	return customer.Page{
+		Customers: customers,
+		Total:     total,
+	}, nil
+}
+
+func (r *memoryRepository) FindByName(ctx context.Context, prefix string) ([]aggregate.Customer, error) {
+	prefix = strings.ToLower(prefix)
+
+	r.Lock()
+	defer r.Unlock()
+
+	return r.sorted(func(c aggregate.Customer) bool {
+		return strings.HasPrefix(strings.ToLower(c.GetName()), prefix)
+	}), nil
+}
+
+// sorted returns copies of the customers matching filter sorted by name, ties
+// broken by id. The lock has to be held.
+func (r *memoryRepository) sorted(filter func(aggregate.Customer) bool) []aggregate.Customer {
+	customers := []aggregate.Customer{}
+	for _, c := range r.customers {
+		if filter(c) {
+			customers = append(customers, copyCustomer(c))
+		}
+	}
+
+	sort.Slice(customers, func(i, j int) bool {
+		a, b := strings.ToLower(customers[i].GetName()), strings.ToLower(customers[j].GetName())
+		if a != b {
+			return a < b
+		}
+
+		return customers[i].GetID().String() < customers[j].GetID().String()
+	})
+
+	return customers
+}
+
+// copyCustomer keeps the transactions and loyalty points of the stored
+// customer from being changed by callers until they Update it
+func copyCustomer(c aggregate.Customer) aggregate.Customer {
+	c.SetTransactions(c.GetTransactions())
+	c.SetLoyaltyHistory(c.GetLoyaltyHistory())
+	return c
+}
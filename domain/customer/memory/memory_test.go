@@ -1,9 +1,12 @@
 package memory
 
 import (
+	"context"
 	"errors"
 	"golang-learn-ddd/aggregate"
 	"golang-learn-ddd/domain/customer"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/google/uuid"
@@ -17,7 +20,7 @@ func Test_memoryRepository_Get(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	repo.Add(cust)
+	repo.Add(context.Background(), cust)
 
 	type testCase struct {
 		name        string
@@ -39,7 +42,7 @@ func Test_memoryRepository_Get(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := repo.Get(tt.id)
+			_, err := repo.Get(context.Background(), tt.id)
 			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
 			}
@@ -75,7 +78,7 @@ func Test_memoryRepository_Add(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := repo.Add(tt.cust)
+			err := repo.Add(context.Background(), tt.cust)
 			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
 			}
@@ -96,7 +99,7 @@ func Test_memoryRepository_Update(t *testing.T) {
 	}
 
 	// add only cust1 to the repo
-	repo.Add(cust1)
+	repo.Add(context.Background(), cust1)
 
 	type testCase struct {
 		name        string
@@ -118,7 +121,7 @@ func Test_memoryRepository_Update(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := repo.Update(tt.cust)
+			err := repo.Update(context.Background(), tt.cust)
 			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
 			}
@@ -126,6 +129,45 @@ func Test_memoryRepository_Update(t *testing.T) {
 	}
 }
 
+func Test_memoryRepository_UpdateConcurrently(t *testing.T) {
+	repo := New()
+
+	cust, err := aggregate.NewCustomer("Adhiana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := repo.Get(context.Background(), cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Get(context.Background(), cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Update(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(context.Background(), second); !errors.Is(err, customer.ErrConcurrentUpdate) {
+		t.Errorf("expected error %v, got %v", customer.ErrConcurrentUpdate, err)
+	}
+
+	latest, err := repo.Get(context.Background(), cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.GetVersion() != 1 {
+		t.Errorf("expected version %d, got %d", 1, latest.GetVersion())
+	}
+	if err := repo.Update(context.Background(), latest); err != nil {
+		t.Errorf("expected the latest version to update, got %v", err)
+	}
+}
+
 func Test_memoryRepository_Delete(t *testing.T) {
 	repo := New()
 
@@ -139,7 +181,7 @@ func Test_memoryRepository_Delete(t *testing.T) {
 	}
 
 	// add only cust1 to the repo
-	repo.Add(cust1)
+	repo.Add(context.Background(), cust1)
 
 	type testCase struct {
 		name        string
@@ -161,10 +203,171 @@ func Test_memoryRepository_Delete(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := repo.Delete(tt.cust)
+			err := repo.Delete(context.Background(), tt.cust)
 			if !errors.Is(err, tt.expectedErr) {
 				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
 			}
 		})
 	}
 }
+
+func init_customers(t *testing.T, repo customer.CustomerRepository, names ...string) {
+	for _, name := range names {
+		cust, err := aggregate.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := repo.Add(context.Background(), cust); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func customerNames(customers []aggregate.Customer) []string {
+	var names []string
+	for _, c := range customers {
+		names = append(names, c.GetName())
+	}
+
+	return names
+}
+
+func Test_memoryRepository_List(t *testing.T) {
+	repo := New()
+	init_customers(t, repo, "mastur", "Adhiana", "SeeU", "Senyamiku")
+
+	type testCase struct {
+		name          string
+		offset, limit int
+		expectedNames []string
+		expectedErr   error
+	}
+	tests := []testCase{
+		{
+			name:          "list all customers sorted by name",
+			expectedNames: []string{"Adhiana", "mastur", "SeeU", "Senyamiku"},
+		},
+		{
+			name:          "second page",
+			offset:        2,
+			limit:         2,
+			expectedNames: []string{"SeeU", "Senyamiku"},
+		},
+		{
+			name:          "offset past the end",
+			offset:        10,
+			expectedNames: nil,
+		},
+		{
+			name:        "negative offset",
+			offset:      -1,
+			expectedErr: customer.ErrInvalidPagination,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.List(context.Background(), tt.offset, tt.limit)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if got := customerNames(page.Customers); !reflect.DeepEqual(got, tt.expectedNames) {
+				t.Errorf("expected customers %v, got %v", tt.expectedNames, got)
+			}
+			if page.Total != 4 {
+				t.Errorf("expected total 4, got %d", page.Total)
+			}
+		})
+	}
+}
+
+func Test_memoryRepository_FindByName(t *testing.T) {
+	repo := New()
+	init_customers(t, repo, "mastur", "Adhiana", "SeeU", "Senyamiku")
+
+	type testCase struct {
+		name          string
+		prefix        string
+		expectedNames []string
+	}
+	tests := []testCase{
+		{
+			name:          "prefix ignores case",
+			prefix:        "se",
+			expectedNames: []string{"SeeU", "Senyamiku"},
+		},
+		{
+			name:          "only matches the start of the name",
+			prefix:        "diana",
+			expectedNames: nil,
+		},
+		{
+			name:          "empty prefix matches everyone",
+			prefix:        "",
+			expectedNames: []string{"Adhiana", "mastur", "SeeU", "Senyamiku"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customers, err := repo.FindByName(context.Background(), tt.prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := customerNames(customers); !reflect.DeepEqual(got, tt.expectedNames) {
+				t.Errorf("expected customers %v, got %v", tt.expectedNames, got)
+			}
+		})
+	}
+}
+
+func Test_memoryRepository_ListWhileUpdating(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	cust, err := aggregate.NewCustomer("Adhiana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// run with -race, readers and writers share the map
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c, err := repo.Get(ctx, cust.GetID())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := repo.Update(ctx, c); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if _, err := repo.List(ctx, 0, 10); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := repo.FindByName(ctx, "adh"); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
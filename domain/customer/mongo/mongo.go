@@ -2,17 +2,24 @@ package mongo
 
 import (
 	"context"
+	"fmt"
 	"golang-learn-ddd/aggregate"
 	"golang-learn-ddd/domain/customer"
+	"golang-learn-ddd/valueobject"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// nameCollation compares names ignoring case, it is shared by the name
+// index and every query sorting on names so the index can be used
+var nameCollation = &options.Collation{Locale: "en", Strength: 2}
+
 type mongoRepository struct {
 	db       *mongo.Database
 	customer *mongo.Collection
@@ -20,24 +27,125 @@ type mongoRepository struct {
 
 // mongoCustomer internal type to store CustomerAggregate to mongodb
 type mongoCustomer struct {
-	ID   uuid.UUID `bson:"id"`
-	Name string    `bson:"name"`
+	ID          uuid.UUID `bson:"id"`
+	Name        string    `bson:"name"`
+	Email       string    `bson:"email,omitempty"`
+	PhoneNumber string    `bson:"phone_number,omitempty"`
+	DateOfBirth time.Time `bson:"date_of_birth,omitempty"`
+
+	Transactions []mongoTransaction `bson:"transactions"`
+	// Balance is in cents
+	Balance int            `bson:"balance"`
+	Loyalty []mongoLoyalty `bson:"loyalty"`
+	Tier    string         `bson:"tier,omitempty"`
+	Version int            `bson:"version"`
+}
+
+type mongoLoyalty struct {
+	Kind      string    `bson:"kind"`
+	Points    int       `bson:"points"`
+	Remaining int       `bson:"remaining"`
+	OrderID   uuid.UUID `bson:"order_id"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at,omitempty"`
+}
+
+type mongoTransaction struct {
+	Kind      string    `bson:"kind"`
+	Amount    int       `bson:"amount"`
+	From      uuid.UUID `bson:"from"`
+	To        uuid.UUID `bson:"to"`
+	CreatedAt time.Time `bson:"created_at"`
+	Reference string    `bson:"reference,omitempty"`
 }
 
 func NewFromCustomer(c aggregate.Customer) mongoCustomer {
+	transactions := make([]mongoTransaction, 0, len(c.GetTransactions()))
+	for _, t := range c.GetTransactions() {
+		transactions = append(transactions, mongoTransaction{
+			Kind:      string(t.Kind()),
+			Amount:    t.Amount(),
+			From:      t.From(),
+			To:        t.To(),
+			CreatedAt: t.CreatedAt(),
+			Reference: t.Reference(),
+		})
+	}
+
+	loyalty := make([]mongoLoyalty, 0, len(c.GetLoyaltyHistory()))
+	for _, e := range c.GetLoyaltyHistory() {
+		loyalty = append(loyalty, mongoLoyalty{
+			Kind:      string(e.Kind),
+			Points:    e.Points,
+			Remaining: e.Remaining,
+			OrderID:   e.OrderID,
+			CreatedAt: e.CreatedAt,
+			ExpiresAt: e.ExpiresAt,
+		})
+	}
+
 	return mongoCustomer{
-		ID:   c.GetID(),
-		Name: c.GetName(),
+		ID:           c.GetID(),
+		Name:         c.GetName(),
+		Email:        c.GetEmail().String(),
+		PhoneNumber:  c.GetPhoneNumber().String(),
+		DateOfBirth:  c.GetDateOfBirth(),
+		Transactions: transactions,
+		Balance:      valueobject.Cents(c.GetBalance()),
+		Loyalty:      loyalty,
+		Tier:         c.GetTier(),
+		Version:      c.GetVersion(),
 	}
 }
 
-func (m *mongoCustomer) ToAggregate() aggregate.Customer {
+func (m *mongoCustomer) ToAggregate() (aggregate.Customer, error) {
 	c := aggregate.Customer{}
 
 	c.SetID(m.ID)
 	c.SetName(m.Name)
+	c.SetDateOfBirth(m.DateOfBirth)
+
+	if m.Email != "" {
+		email, err := valueobject.NewEmail(m.Email)
+		if err != nil {
+			return aggregate.Customer{}, err
+		}
+		c.SetEmail(email)
+	}
+
+	if m.PhoneNumber != "" {
+		phone, err := valueobject.NewPhoneNumber(m.PhoneNumber)
+		if err != nil {
+			return aggregate.Customer{}, err
+		}
+		c.SetPhoneNumber(phone)
+	}
 
-	return c
+	transactions := make([]valueobject.Transaction, 0, len(m.Transactions))
+	for _, t := range m.Transactions {
+		transactions = append(transactions, valueobject.NewTransaction(
+			valueobject.TransactionKind(t.Kind), t.Amount, t.From, t.To, t.CreatedAt,
+		).WithReference(t.Reference))
+	}
+	c.SetTransactions(transactions)
+	c.SetBalance(float64(m.Balance) / 100)
+
+	loyalty := make([]aggregate.LoyaltyEntry, 0, len(m.Loyalty))
+	for _, e := range m.Loyalty {
+		loyalty = append(loyalty, aggregate.LoyaltyEntry{
+			Kind:      aggregate.LoyaltyEntryKind(e.Kind),
+			Points:    e.Points,
+			Remaining: e.Remaining,
+			OrderID:   e.OrderID,
+			CreatedAt: e.CreatedAt,
+			ExpiresAt: e.ExpiresAt,
+		})
+	}
+	c.SetLoyaltyHistory(loyalty)
+	c.SetTier(m.Tier)
+	c.SetVersion(m.Version)
+
+	return c, nil
 }
 
 func New(ctx context.Context, connectionString string) (customer.CustomerRepository, error) {
@@ -49,66 +157,164 @@ func New(ctx context.Context, connectionString string) (customer.CustomerReposit
 	db := client.Database("learn-golang-ddd")
 	collection := db.Collection("customers")
 
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}},
+		Options: options.Index().SetName("name_ci").SetCollation(nameCollation),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the customer name index: %w", err)
+	}
+
 	return &mongoRepository{
 		db:       db,
 		customer: collection,
 	}, nil
 }
 
-func (r *mongoRepository) Get(id uuid.UUID) (aggregate.Customer, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *mongoRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Customer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var row mongoCustomer
 	err := r.customer.FindOne(ctx, bson.M{"id": id}).Decode(&row)
 	if err != nil {
-		return aggregate.Customer{}, errors.Errorf("customer does not exists: %w; %w", err, customer.ErrCustomerNotFound)
+		return aggregate.Customer{}, fmt.Errorf("customer does not exists: %w; %w", err, customer.ErrCustomerNotFound)
 	}
 
-	return row.ToAggregate(), nil
+	return row.ToAggregate()
 }
 
-func (r *mongoRepository) Add(c aggregate.Customer) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *mongoRepository) Add(ctx context.Context, c aggregate.Customer) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	_, err := r.customer.InsertOne(ctx, NewFromCustomer(c))
 	if err != nil {
-		return errors.Errorf("failed to add a customer: %w; %w", err, customer.ErrFailedToAddCustomer)
+		return fmt.Errorf("failed to add a customer: %w; %w", err, customer.ErrFailedToAddCustomer)
 	}
 
 	return nil
 }
 
-func (r *mongoRepository) Update(c aggregate.Customer) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *mongoRepository) Update(ctx context.Context, c aggregate.Customer) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{"id": c.GetID()}
+	row := NewFromCustomer(c)
+	filter := bson.M{"id": row.ID, "version": row.Version}
+	if row.Version == 0 {
+		// customers stored before versioning have no version yet
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
 	updateData := bson.M{
 		"$set": bson.M{
-			"name": c.GetName(),
+			"name":          row.Name,
+			"email":         row.Email,
+			"phone_number":  row.PhoneNumber,
+			"date_of_birth": row.DateOfBirth,
+			"transactions":  row.Transactions,
+			"balance":       row.Balance,
+			"loyalty":       row.Loyalty,
+			"tier":          row.Tier,
+			"version":       row.Version + 1,
 		},
 	}
 
-	_, err := r.customer.UpdateOne(ctx, filter, updateData)
+	res, err := r.customer.UpdateOne(ctx, filter, updateData)
 	if err != nil {
-		return errors.Errorf("failed to update a customer: %w; %w", err, customer.ErrUpdateCustomer)
+		return fmt.Errorf("failed to update a customer: %w; %w", err, customer.ErrUpdateCustomer)
+	}
+	if res.MatchedCount == 0 {
+		if n, err := r.customer.CountDocuments(ctx, bson.M{"id": row.ID}); err == nil && n == 0 {
+			return fmt.Errorf("customer does not exists :%w", customer.ErrUpdateCustomer)
+		}
+		return fmt.Errorf("customer is not at version %d :%w", row.Version, customer.ErrConcurrentUpdate)
 	}
 
 	return nil
 }
 
-func (r *mongoRepository) Delete(c aggregate.Customer) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *mongoRepository) Delete(ctx context.Context, c aggregate.Customer) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	filter := bson.M{"id": c.GetID()}
 
 	_, err := r.customer.DeleteOne(ctx, filter)
 	if err != nil {
-		return errors.Errorf("failed to delete a customer: %w; %w", err, customer.ErrDeleteCustomer)
+		return fmt.Errorf("failed to delete a customer: %w; %w", err, customer.ErrDeleteCustomer)
 	}
 
 	return nil
 }
+
+func (r *mongoRepository) List(ctx context.Context, offset, limit int) (customer.Page, error) {
+	if offset < 0 || limit < 0 {
+		return customer.Page{}, customer.ErrInvalidPagination
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total, err := r.customer.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return customer.Page{}, err
+	}
+
+	opts := options.Find().
+		SetCollation(nameCollation).
+		SetSort(bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}}).
+		SetSkip(int64(offset))
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	customers, err := r.find(ctx, bson.M{}, opts)
+	if err != nil {
+		return customer.Page{}, err
+	}
+
+	return customer.Page{
+		Customers: customers,
+		Total:     int(total),
+	}, nil
+}
+
+func (r *mongoRepository) FindByName(ctx context.Context, prefix string) ([]aggregate.Customer, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"name": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix), Options: "i"},
+	}
+	opts := options.Find().
+		SetCollation(nameCollation).
+		SetSort(bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+func (r *mongoRepository) find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]aggregate.Customer, error) {
+	cursor, err := r.customer.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	customers := []aggregate.Customer{}
+	for cursor.Next(ctx) {
+		var row mongoCustomer
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+
+		c, err := row.ToAggregate()
+		if err != nil {
+			return nil, err
+		}
+
+		customers = append(customers, c)
+	}
+
+	return customers, cursor.Err()
+}
@@ -1,6 +1,7 @@
 package customer
 
 import (
+	"context"
 	"errors"
 	"golang-learn-ddd/aggregate"
 
@@ -12,11 +13,28 @@ var (
 	ErrFailedToAddCustomer = errors.New("failed to add the customer")
 	ErrUpdateCustomer      = errors.New("failed to update the customer")
 	ErrDeleteCustomer      = errors.New("failed to delete the customer")
+	ErrInvalidPagination   = errors.New("offset and limit must not be negative")
+	// ErrConcurrentUpdate is returned when the customer changed since it was read,
+	// get it again and retry the update
+	ErrConcurrentUpdate = errors.New("the customer was updated concurrently")
 )
 
+// Page is one page of customers sorted by name
+type Page struct {
+	Customers []aggregate.Customer
+	// Total is the number of customers across all pages
+	Total int
+}
+
 type CustomerRepository interface {
-	Get(uuid.UUID) (aggregate.Customer, error)
-	Add(aggregate.Customer) error
-	Update(aggregate.Customer) error
-	Delete(aggregate.Customer) error
+	Get(context.Context, uuid.UUID) (aggregate.Customer, error)
+	Add(context.Context, aggregate.Customer) error
+	// Update fails with ErrConcurrentUpdate when the version of the customer is
+	// not the stored one, and increments the stored version otherwise
+	Update(context.Context, aggregate.Customer) error
+	Delete(context.Context, aggregate.Customer) error
+	// List returns customers sorted by name, a limit of 0 returns all of them
+	List(ctx context.Context, offset, limit int) (Page, error)
+	// FindByName returns customers whose name starts with prefix, ignoring case
+	FindByName(ctx context.Context, prefix string) ([]aggregate.Customer, error)
 }
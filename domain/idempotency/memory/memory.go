@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/domain/idempotency"
+	"sync"
+	"time"
+)
+
+type memoryRepository struct {
+	results map[string]idempotency.Result
+	ttl     time.Duration
+	sync.Mutex
+}
+
+// New keeps results for ttl, expired results are dropped whenever a key is reserved
+func New(ttl time.Duration) (idempotency.ResultRepository, error) {
+	if ttl <= 0 {
+		return nil, idempotency.ErrInvalidTTL
+	}
+
+	return &memoryRepository{
+		results: map[string]idempotency.Result{},
+		ttl:     ttl,
+	}, nil
+}
+
+func (r *memoryRepository) Reserve(ctx context.Context, key, fingerprint string, now time.Time) (idempotency.Result, bool, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	for k, res := range r.results {
+		if !now.Before(res.ExpiresAt) {
+			delete(r.results, k)
+		}
+	}
+
+	if res, ok := r.results[key]; ok {
+		return res, false, nil
+	}
+
+	res := idempotency.Result{Key: key, Fingerprint: fingerprint, ExpiresAt: now.Add(r.ttl)}
+	r.results[key] = res
+
+	return res, true, nil
+}
+
+func (r *memoryRepository) Save(ctx context.Context, res idempotency.Result) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.results[res.Key]; !ok {
+		return fmt.Errorf("key %q is not reserved :%w", res.Key, idempotency.ErrSaveResult)
+	}
+	r.results[res.Key] = res
+
+	return nil
+}
+
+func (r *memoryRepository) Release(ctx context.Context, key string) error {
+	r.Lock()
+	defer r.Unlock()
+
+	delete(r.results, key)
+
+	return nil
+}
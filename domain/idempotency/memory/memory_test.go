@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/domain/idempotency"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_memoryRepository_Reserve(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+
+	if _, err := New(0); !errors.Is(err, idempotency.ErrInvalidTTL) {
+		t.Errorf("expected error %v, got %v", idempotency.ErrInvalidTTL, err)
+	}
+
+	repo, err := New(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only one of the concurrent requests reserves the key
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var reserved int
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, ok, err := repo.Reserve(ctx, "key", "request", now)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if reserved != 1 {
+		t.Fatalf("expected the key to be reserved once, got %d", reserved)
+	}
+
+	res, _, err := repo.Reserve(ctx, "key", "request", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Done = true
+	if err := repo.Save(ctx, res); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name     string
+		key      string
+		at       time.Time
+		reserved bool
+		done     bool
+	}
+	tests := []testCase{
+		{name: "replayed", key: "key", at: now.Add(59 * time.Minute), done: true},
+		{name: "expired", key: "key", at: now.Add(time.Hour), reserved: true},
+		{name: "other key", key: "other", at: now, reserved: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, ok, err := repo.Reserve(ctx, tc.key, "request", tc.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tc.reserved || res.Done != tc.done {
+				t.Errorf("expected reserved %v and done %v, got %v and %+v", tc.reserved, tc.done, ok, res)
+			}
+		})
+	}
+
+	if err := repo.Release(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := repo.Reserve(ctx, "other", "request", now); !ok {
+		t.Error("expected a released key to be reserved again")
+	}
+	if err := repo.Save(ctx, idempotency.Result{Key: "unknown"}); !errors.Is(err, idempotency.ErrSaveResult) {
+		t.Errorf("expected error %v, got %v", idempotency.ErrSaveResult, err)
+	}
+}
+
+func Test_memoryRepository_ReserveDropsExpired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+
+	repo, err := New(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"old", "recent"} {
+		if _, _, err := repo.Reserve(ctx, key, "request", now); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(30 * time.Minute)
+	}
+
+	// old expires at 19:00, recent at 19:30, and another key is reserved at 19:00
+	if _, _, err := repo.Reserve(ctx, "new", "request", now); err != nil {
+		t.Fatal(err)
+	}
+
+	results := repo.(*memoryRepository).results
+	if _, ok := results["old"]; ok {
+		t.Error("expected the expired key to be dropped")
+	}
+	if _, ok := results["recent"]; !ok {
+		t.Error("expected the key not expired yet to be kept")
+	}
+}
@@ -0,0 +1,162 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-learn-ddd/domain/idempotency"
+	orderMongo "golang-learn-ddd/domain/order/mongo"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxReserveAttempts bounds the retries of a key expired but not removed yet
+const maxReserveAttempts = 3
+
+type mongoRepository struct {
+	db     *mongo.Database
+	result *mongo.Collection
+	ttl    time.Duration
+}
+
+// mongoResult internal type to store the result of a request by its key
+type mongoResult struct {
+	Key         string                 `bson:"key"`
+	Fingerprint string                 `bson:"fingerprint"`
+	Done        bool                   `bson:"done"`
+	Order       *orderMongo.MongoOrder `bson:"order,omitempty"`
+	Err         string                 `bson:"error,omitempty"`
+	ExpiresAt   time.Time              `bson:"expires_at"`
+}
+
+func NewFromResult(r idempotency.Result) mongoResult {
+	row := mongoResult{
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Done:        r.Done,
+		Err:         r.Err,
+		ExpiresAt:   r.ExpiresAt,
+	}
+	if r.Done && r.Err == "" {
+		o := orderMongo.NewFromOrder(r.Order)
+		row.Order = &o
+	}
+
+	return row
+}
+
+func (m *mongoResult) ToAggregate() (idempotency.Result, error) {
+	r := idempotency.Result{
+		Key:         m.Key,
+		Fingerprint: m.Fingerprint,
+		Done:        m.Done,
+		Err:         m.Err,
+		ExpiresAt:   m.ExpiresAt,
+	}
+	if m.Order != nil {
+		o, err := m.Order.ToAggregate()
+		if err != nil {
+			return idempotency.Result{}, err
+		}
+		r.Order = o
+	}
+
+	return r, nil
+}
+
+// New keeps results for ttl. Mongo removes expired results in the background,
+// a minute or so late, so expired results are also ignored when reserving.
+func New(ctx context.Context, connectionString string, ttl time.Duration) (idempotency.ResultRepository, error) {
+	if ttl <= 0 {
+		return nil, idempotency.ErrInvalidTTL
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("learn-golang-ddd")
+	results := db.Collection("idempotency_results")
+
+	_, err = results.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index results: %w", err)
+	}
+
+	return &mongoRepository{
+		db:     db,
+		result: results,
+		ttl:    ttl,
+	}, nil
+}
+
+func (r *mongoRepository) Reserve(ctx context.Context, key, fingerprint string, now time.Time) (idempotency.Result, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res := idempotency.Result{Key: key, Fingerprint: fingerprint, ExpiresAt: now.Add(r.ttl)}
+
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		_, err := r.result.InsertOne(ctx, NewFromResult(res))
+		if err == nil {
+			return res, true, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return idempotency.Result{}, false, fmt.Errorf("failed to reserve the key: %w; %w", err, idempotency.ErrReserveKey)
+		}
+
+		var row mongoResult
+		err = r.result.FindOne(ctx, bson.M{"key": key, "expires_at": bson.M{"$gt": now}}).Decode(&row)
+		if err == nil {
+			kept, err := row.ToAggregate()
+			return kept, false, err
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return idempotency.Result{}, false, fmt.Errorf("failed to find the result: %w; %w", err, idempotency.ErrReserveKey)
+		}
+
+		// the result expired, it is removed before reserving the key again
+		_, err = r.result.DeleteOne(ctx, bson.M{"key": key, "expires_at": bson.M{"$lte": now}})
+		if err != nil {
+			return idempotency.Result{}, false, fmt.Errorf("failed to remove the expired result: %w; %w", err, idempotency.ErrReserveKey)
+		}
+	}
+
+	return idempotency.Result{}, false, fmt.Errorf("key %q reserved concurrently :%w", key, idempotency.ErrReserveKey)
+}
+
+func (r *mongoRepository) Save(ctx context.Context, res idempotency.Result) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := NewFromResult(res)
+
+	updated, err := r.result.ReplaceOne(ctx, bson.M{"key": row.Key}, row)
+	if err != nil {
+		return fmt.Errorf("failed to save the result: %w; %w", err, idempotency.ErrSaveResult)
+	}
+	if updated.MatchedCount == 0 {
+		return fmt.Errorf("key %q is not reserved :%w", row.Key, idempotency.ErrSaveResult)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Release(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.result.DeleteOne(ctx, bson.M{"key": key})
+	if err != nil {
+		return fmt.Errorf("failed to release the key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"time"
+)
+
+var (
+	ErrRequestInProgress = errors.New("a request with the idempotency key is still in progress")
+	ErrKeyReused         = errors.New("the idempotency key was used for another request")
+	ErrReserveKey        = errors.New("failed to reserve the idempotency key")
+	ErrSaveResult        = errors.New("failed to save the result of the request")
+	ErrInvalidTTL        = errors.New("results have to be kept for a positive duration")
+	ErrRequestFailed     = errors.New("the request failed after it was partly carried out")
+)
+
+// Result is what a request submitted with an idempotency key gave, replayed
+// to the retries of the request until it expires
+type Result struct {
+	Key string
+	// Fingerprint identifies the request, a key only replays the request it was first given with
+	Fingerprint string
+	// Done is false while the request is in progress
+	Done  bool
+	Order aggregate.Order
+	// Err is why a done request failed, without an order
+	Err string
+
+	ExpiresAt time.Time
+}
+
+// ResultRepository keeps the results of requests by idempotency key, for the
+// time to live it is configured with
+type ResultRepository interface {
+	// Reserve claims the key for a request at now. It returns true when the key
+	// was free, or else the result kept for the key, which may not be done yet.
+	Reserve(ctx context.Context, key, fingerprint string, now time.Time) (Result, bool, error)
+	// Save records the result of the request that reserved the key
+	Save(ctx context.Context, r Result) error
+	// Release frees the key of a request that failed, so it can be retried
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+	"golang-learn-ddd/domain/invoice"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type memoryRepository struct {
+	// numbers are the invoice numbers of the orders, by tavern
+	numbers map[uuid.UUID]map[uuid.UUID]int
+	sync.Mutex
+}
+
+func New() invoice.InvoiceRepository {
+	return &memoryRepository{
+		numbers: map[uuid.UUID]map[uuid.UUID]int{},
+	}
+}
+
+func (r *memoryRepository) Number(ctx context.Context, tavernID, orderID uuid.UUID) (int, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	numbers, ok := r.numbers[tavernID]
+	if !ok {
+		numbers = map[uuid.UUID]int{}
+		r.numbers[tavernID] = numbers
+	}
+
+	if n, ok := numbers[orderID]; ok {
+		return n, nil
+	}
+
+	n := len(numbers) + 1
+	numbers[orderID] = n
+
+	return n, nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_memoryRepository_Number(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+	tavern, other := uuid.New(), uuid.New()
+
+	orders := make([]uuid.UUID, 50)
+	for i := range orders {
+		orders[i] = uuid.New()
+	}
+
+	// every order asks twice, concurrently
+	var wg sync.WaitGroup
+	numbers := make([]int, 2*len(orders))
+	for i := range numbers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			n, err := repo.Number(ctx, tavern, orders[i%len(orders)])
+			if err != nil {
+				t.Error(err)
+			}
+			numbers[i] = n
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range orders {
+		if numbers[i] != numbers[i+len(orders)] {
+			t.Errorf("expected order %d to keep its number, got %d and %d", i, numbers[i], numbers[i+len(orders)])
+		}
+	}
+
+	issued := append([]int(nil), numbers[:len(orders)]...)
+	sort.Ints(issued)
+	for i, n := range issued {
+		if n != i+1 {
+			t.Fatalf("expected numbers 1 to %d without gaps, got %v", len(orders), issued)
+		}
+	}
+
+	// every tavern numbers its own invoices
+	n, err := repo.Number(ctx, other, orders[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected the first invoice of another tavern to be 1, got %d", n)
+	}
+}
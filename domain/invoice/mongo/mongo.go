@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-learn-ddd/domain/invoice"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxNumberAttempts bounds the retries of an invoice numbered concurrently with others
+const maxNumberAttempts = 10
+
+type mongoRepository struct {
+	db      *mongo.Database
+	invoice *mongo.Collection
+}
+
+// mongoInvoice internal type to store the number of the invoice of an order
+type mongoInvoice struct {
+	TavernID uuid.UUID `bson:"tavern_id"`
+	OrderID  uuid.UUID `bson:"order_id"`
+	Number   int       `bson:"number"`
+}
+
+// New numbers invoices without a counter: an invoice takes the number after
+// the last one of its tavern, and unique indexes on the numbers and on the
+// orders of every tavern make concurrent invoices retry instead of leaving gaps
+func New(ctx context.Context, connectionString string) (invoice.InvoiceRepository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("learn-golang-ddd")
+	invoices := db.Collection("invoices")
+
+	_, err = invoices.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tavern_id", Value: 1}, {Key: "number", Value: -1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "tavern_id", Value: 1}, {Key: "order_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index invoices: %w", err)
+	}
+
+	return &mongoRepository{
+		db:      db,
+		invoice: invoices,
+	}, nil
+}
+
+func (r *mongoRepository) Number(ctx context.Context, tavernID, orderID uuid.UUID) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	for attempt := 0; attempt < maxNumberAttempts; attempt++ {
+		var row mongoInvoice
+		err := r.invoice.FindOne(ctx, bson.M{"tavern_id": tavernID, "order_id": orderID}).Decode(&row)
+		if err == nil {
+			return row.Number, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, fmt.Errorf("failed to find the invoice: %w; %w", err, invoice.ErrNumberInvoice)
+		}
+
+		var last mongoInvoice
+		opts := options.FindOne().SetSort(bson.D{{Key: "number", Value: -1}})
+		err = r.invoice.FindOne(ctx, bson.M{"tavern_id": tavernID}, opts).Decode(&last)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return 0, fmt.Errorf("failed to find the last invoice: %w; %w", err, invoice.ErrNumberInvoice)
+		}
+
+		row = mongoInvoice{TavernID: tavernID, OrderID: orderID, Number: last.Number + 1}
+		_, err = r.invoice.InsertOne(ctx, row)
+		if err == nil {
+			return row.Number, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return 0, fmt.Errorf("failed to add the invoice: %w; %w", err, invoice.ErrNumberInvoice)
+		}
+		// another invoice took the number, or the order got one meanwhile
+	}
+
+	return 0, fmt.Errorf("too many invoices numbered concurrently :%w", invoice.ErrNumberInvoice)
+}
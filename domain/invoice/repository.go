@@ -0,0 +1,20 @@
+package invoice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNumberInvoice = errors.New("failed to number the invoice")
+)
+
+// InvoiceRepository numbers the invoices of every tavern from 1, without
+// gaps: a number is only given to an order, and always the same one
+type InvoiceRepository interface {
+	// Number returns the invoice number of the order, the next number of the
+	// tavern when the order has none yet
+	Number(ctx context.Context, tavernID, orderID uuid.UUID) (int, error)
+}
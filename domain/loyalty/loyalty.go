@@ -0,0 +1,118 @@
+package loyalty
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidProgram = errors.New("invalid loyalty program")
+)
+
+// Program decides how many points customers earn with their orders and
+// what the points are worth when redeemed
+type Program struct {
+	// pointsPerUnit is earned for every unit of money spent
+	pointsPerUnit float64
+	// pointValue is the money one point takes off an order
+	pointValue float64
+	// validity is how long earned points last, 0 for points that never expire
+	validity time.Duration
+
+	multipliers map[uuid.UUID]float64
+}
+
+type ProgramConfiguration func(p *Program) error
+
+// NewProgram earns pointsPerUnit points for every unit of money spent, each
+// point being worth pointValue when redeemed
+func NewProgram(pointsPerUnit, pointValue float64, cfgs ...ProgramConfiguration) (*Program, error) {
+	if pointsPerUnit <= 0 || pointValue <= 0 {
+		return nil, ErrInvalidProgram
+	}
+
+	p := &Program{
+		pointsPerUnit: pointsPerUnit,
+		pointValue:    pointValue,
+		multipliers:   map[uuid.UUID]float64{},
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithValidity makes earned points expire after the given duration
+func WithValidity(validity time.Duration) ProgramConfiguration {
+	return func(p *Program) error {
+		if validity < 0 {
+			return ErrInvalidProgram
+		}
+
+		p.validity = validity
+		return nil
+	}
+}
+
+// WithCategoryMultiplier earns multiplier times the points on the products of a category,
+// use 0 for categories not earning points
+func WithCategoryMultiplier(categoryID uuid.UUID, multiplier float64) ProgramConfiguration {
+	return func(p *Program) error {
+		if categoryID == uuid.Nil || multiplier < 0 {
+			return ErrInvalidProgram
+		}
+
+		p.multipliers[categoryID] = multiplier
+		return nil
+	}
+}
+
+// Earned is the number of points the order earns, on what was paid for
+// its lines once discounted, taxes excluded
+func (p *Program) Earned(order aggregate.Order) int {
+	subtotal := order.GetSubtotal()
+	if subtotal <= 0 {
+		return 0
+	}
+	paid := 1 - order.GetOrderDiscount()/subtotal
+
+	var points float64
+	for _, l := range order.GetLines() {
+		multiplier, ok := p.multipliers[l.CategoryID]
+		if !ok {
+			multiplier = 1
+		}
+
+		points += l.Total() * paid * p.pointsPerUnit * multiplier
+	}
+
+	// a small epsilon keeps 9.999999 from rounding down to 9
+	return int(math.Floor(points + 1e-9))
+}
+
+// ExpiresAt is when points earned at the given time expire, zero when they never do
+func (p *Program) ExpiresAt(earnedAt time.Time) time.Time {
+	if p.validity == 0 {
+		return time.Time{}
+	}
+
+	return earnedAt.Add(p.validity)
+}
+
+// Value is the money the points take off an order
+func (p *Program) Value(points int) float64 {
+	return aggregate.RoundMoney(float64(points) * p.pointValue)
+}
+
+// PointsFor is the number of points needed to take amount off an order
+func (p *Program) PointsFor(amount float64) int {
+	return int(math.Ceil(aggregate.RoundMoney(amount/p.pointValue) - 1e-9))
+}
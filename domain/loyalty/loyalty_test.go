@@ -0,0 +1,102 @@
+package loyalty
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLoyalty_Earned(t *testing.T) {
+	drinks, cigarettes := uuid.New(), uuid.New()
+
+	program, err := NewProgram(1, 0.05,
+		WithCategoryMultiplier(drinks, 2),
+		WithCategoryMultiplier(cigarettes, 0),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	line := func(categoryID uuid.UUID, price, discount float64) aggregate.OrderLine {
+		return aggregate.OrderLine{ProductID: uuid.New(), CategoryID: categoryID, UnitPrice: price, Discount: discount}
+	}
+
+	type testCase struct {
+		name     string
+		lines    []aggregate.OrderLine
+		coupon   float64
+		expected int
+	}
+	tests := []testCase{
+		{
+			name:     "a point per unit spent, rounded down",
+			lines:    []aggregate.OrderLine{line(uuid.Nil, 12.5, 0), line(uuid.Nil, 1.5, 0)},
+			expected: 14,
+		},
+		{
+			name:     "category multipliers",
+			lines:    []aggregate.OrderLine{line(drinks, 5, 0), line(cigarettes, 8, 0), line(uuid.Nil, 2, 0)},
+			expected: 12,
+		},
+		{
+			name:     "discounts do not earn points",
+			lines:    []aggregate.OrderLine{line(uuid.Nil, 10, 3), line(uuid.Nil, 10, 0)},
+			coupon:   7,
+			expected: 10,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			order, err := aggregate.NewOrder(uuid.New(), tc.lines, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.coupon > 0 {
+				order.ApplyCoupon("COUPON", tc.coupon)
+			}
+
+			if got := program.Earned(order); got != tc.expected {
+				t.Errorf("expected %d points, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLoyalty_Program(t *testing.T) {
+	program, err := NewProgram(1, 0.05, WithValidity(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := program.Value(30); got != 1.5 {
+		t.Errorf("expected 30 points to be worth %v, got %v", 1.5, got)
+	}
+	if got := program.PointsFor(1.5); got != 30 {
+		t.Errorf("expected %d points for %v, got %d", 30, 1.5, got)
+	}
+	if got := program.PointsFor(1.51); got != 31 {
+		t.Errorf("expected %d points for %v, got %d", 31, 1.51, got)
+	}
+
+	now := time.Now()
+	if got := program.ExpiresAt(now); !got.Equal(now.Add(24 * time.Hour)) {
+		t.Errorf("expected points to expire on %v, got %v", now.Add(24*time.Hour), got)
+	}
+
+	for _, cfgs := range [][]ProgramConfiguration{
+		{WithValidity(-time.Hour)},
+		{WithCategoryMultiplier(uuid.Nil, 2)},
+		{WithCategoryMultiplier(uuid.New(), -1)},
+	} {
+		if _, err := NewProgram(1, 0.05, cfgs...); !errors.Is(err, ErrInvalidProgram) {
+			t.Errorf("expected error %v, got %v", ErrInvalidProgram, err)
+		}
+	}
+	if _, err := NewProgram(0, 0.05); !errors.Is(err, ErrInvalidProgram) {
+		t.Errorf("expected error %v, got %v", ErrInvalidProgram, err)
+	}
+}
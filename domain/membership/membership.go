@@ -0,0 +1,127 @@
+package membership
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/valueobject"
+	"sort"
+)
+
+var (
+	ErrInvalidTier = errors.New("invalid membership tier")
+)
+
+type Tier string
+
+const (
+	// NoTier is the tier of customers who did not spend enough for any tier yet
+	NoTier Tier = ""
+	Bronze Tier = "bronze"
+	Silver Tier = "silver"
+	Gold   Tier = "gold"
+)
+
+// Benefits are what the customers of a tier get
+type Benefits struct {
+	// Discount is a percentage off every order
+	Discount float64
+	// Priority moves orders up the kitchen queue, higher first
+	Priority int
+}
+
+type tierRule struct {
+	tier     Tier
+	minSpend float64
+	benefits Benefits
+}
+
+// Evaluator assigns customers the highest tier their lifetime spend reaches
+type Evaluator struct {
+	// rules are sorted by decreasing minimum spend
+	rules []tierRule
+}
+
+type EvaluatorConfiguration func(e *Evaluator) error
+
+// NewEvaluator uses the default tiers unless tiers are given with WithTier:
+// Bronze from 100 with priority 1, Silver from 500 with 5% off and priority 2,
+// Gold from 2000 with 10% off and priority 3
+func NewEvaluator(cfgs ...EvaluatorConfiguration) (*Evaluator, error) {
+	e := &Evaluator{}
+
+	for _, cfg := range cfgs {
+		if err := cfg(e); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(e.rules) == 0 {
+		e.rules = []tierRule{
+			{tier: Gold, minSpend: 2000, benefits: Benefits{Discount: 10, Priority: 3}},
+			{tier: Silver, minSpend: 500, benefits: Benefits{Discount: 5, Priority: 2}},
+			{tier: Bronze, minSpend: 100, benefits: Benefits{Priority: 1}},
+		}
+	}
+
+	return e, nil
+}
+
+// WithTier reaches tier from a lifetime spend of minSpend
+func WithTier(tier Tier, minSpend float64, benefits Benefits) EvaluatorConfiguration {
+	return func(e *Evaluator) error {
+		if tier == NoTier || minSpend < 0 || benefits.Discount < 0 || benefits.Discount > 100 {
+			return ErrInvalidTier
+		}
+		for _, r := range e.rules {
+			if r.tier == tier {
+				return ErrInvalidTier
+			}
+		}
+
+		e.rules = append(e.rules, tierRule{tier: tier, minSpend: minSpend, benefits: benefits})
+		sort.SliceStable(e.rules, func(i, j int) bool {
+			return e.rules[i].minSpend > e.rules[j].minSpend
+		})
+		return nil
+	}
+}
+
+// LifetimeSpend is what the customer paid for orders and service charges,
+// less what was refunded. Tips, given back or not, and wallet deposits are
+// not spent at the tavern.
+func LifetimeSpend(c aggregate.Customer) float64 {
+	var cents int
+	for _, t := range c.GetTransactions() {
+		switch {
+		case t.From() == c.GetID() && (t.Kind() == valueobject.TransactionPayment || t.Kind() == valueobject.TransactionServiceCharge):
+			cents += t.Amount()
+		case t.To() == c.GetID() && t.Kind() == valueobject.TransactionRefund:
+			cents -= t.Amount()
+		}
+	}
+
+	return float64(cents) / 100
+}
+
+// Evaluate returns the tier the customer deserves
+func (e *Evaluator) Evaluate(c aggregate.Customer) Tier {
+	spend := LifetimeSpend(c)
+	for _, r := range e.rules {
+		if spend >= r.minSpend {
+			return r.tier
+		}
+	}
+
+	return NoTier
+}
+
+// Benefits returns the benefits of a tier, none for unknown tiers
+func (e *Evaluator) Benefits(tier Tier) Benefits {
+	for _, r := range e.rules {
+		if r.tier == tier {
+			return r.benefits
+		}
+	}
+
+	return Benefits{}
+}
@@ -0,0 +1,146 @@
+package membership
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// customerWith builds a customer from a synthetic history of transactions in cents
+func customerWith(t *testing.T, history map[valueobject.TransactionKind]int) aggregate.Customer {
+	t.Helper()
+
+	c, err := aggregate.NewCustomer("Miku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tavern := uuid.New()
+	for kind, amount := range history {
+		c.AddTransaction(valueobject.NewTransaction(kind, amount, c.GetID(), tavern, time.Now()))
+	}
+
+	return c
+}
+
+func TestMembership_Evaluate(t *testing.T) {
+	evaluator, err := NewEvaluator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name     string
+		history  map[valueobject.TransactionKind]int
+		expected Tier
+	}
+	tests := []testCase{
+		{
+			name:     "new customer",
+			expected: NoTier,
+		},
+		{
+			name:     "bronze at 100 exactly",
+			history:  map[valueobject.TransactionKind]int{valueobject.TransactionPayment: 10000},
+			expected: Bronze,
+		},
+		{
+			name: "service charges count as spend",
+			history: map[valueobject.TransactionKind]int{
+				valueobject.TransactionPayment:       45000,
+				valueobject.TransactionServiceCharge: 5000,
+			},
+			expected: Silver,
+		},
+		{
+			name: "tips and wallet movements do not count",
+			history: map[valueobject.TransactionKind]int{
+				valueobject.TransactionPayment:    9999,
+				valueobject.TransactionTip:        100000,
+				valueobject.TransactionDeposit:    500000,
+				valueobject.TransactionWithdrawal: 9999,
+			},
+			expected: NoTier,
+		},
+		{
+			name:     "gold",
+			history:  map[valueobject.TransactionKind]int{valueobject.TransactionPayment: 250000},
+			expected: Gold,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := customerWith(t, tc.history)
+			if got := evaluator.Evaluate(c); got != tc.expected {
+				t.Errorf("expected tier %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMembership_LifetimeSpendIgnoresOtherPayers(t *testing.T) {
+	c := customerWith(t, map[valueobject.TransactionKind]int{valueobject.TransactionPayment: 1250})
+	c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionPayment, 5000, uuid.New(), uuid.New(), time.Now()))
+
+	if got := LifetimeSpend(c); got != 12.5 {
+		t.Errorf("expected a lifetime spend of %v, got %v", 12.5, got)
+	}
+}
+
+func TestMembership_LifetimeSpendLessRefunds(t *testing.T) {
+	c := customerWith(t, map[valueobject.TransactionKind]int{valueobject.TransactionPayment: 1250})
+	c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionRefund, 250, uuid.New(), c.GetID(), time.Now()))
+
+	if got := LifetimeSpend(c); got != 10 {
+		t.Errorf("expected a lifetime spend of %v, got %v", 10, got)
+	}
+}
+
+func TestMembership_LifetimeSpendTipGivenBack(t *testing.T) {
+	c := customerWith(t, map[valueobject.TransactionKind]int{valueobject.TransactionPayment: 1250, valueobject.TransactionTip: 200})
+	c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionRefund, 1250, uuid.New(), c.GetID(), time.Now()))
+	c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionTipRefund, 200, uuid.New(), c.GetID(), time.Now()))
+
+	if got := LifetimeSpend(c); got != 0 {
+		t.Errorf("expected a lifetime spend of %v, got %v", 0, got)
+	}
+}
+
+func TestMembership_WithTier(t *testing.T) {
+	evaluator, err := NewEvaluator(
+		WithTier(Silver, 50, Benefits{Discount: 5, Priority: 1}),
+		WithTier(Gold, 200, Benefits{Discount: 15, Priority: 2}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := customerWith(t, map[valueobject.TransactionKind]int{valueobject.TransactionPayment: 10000})
+	if got := evaluator.Evaluate(c); got != Silver {
+		t.Errorf("expected tier %q, got %q", Silver, got)
+	}
+	if got := evaluator.Benefits(Gold); got.Discount != 15 || got.Priority != 2 {
+		t.Errorf("expected gold benefits 15%% and priority 2, got %+v", got)
+	}
+	if got := evaluator.Benefits(Bronze); got != (Benefits{}) {
+		t.Errorf("expected no benefits for a tier not configured, got %+v", got)
+	}
+
+	for _, cfg := range []EvaluatorConfiguration{
+		WithTier(NoTier, 10, Benefits{}),
+		WithTier(Gold, -1, Benefits{}),
+		WithTier(Gold, 10, Benefits{Discount: 110}),
+	} {
+		if _, err := NewEvaluator(cfg); !errors.Is(err, ErrInvalidTier) {
+			t.Errorf("expected error %v, got %v", ErrInvalidTier, err)
+		}
+	}
+	if _, err := NewEvaluator(WithTier(Gold, 10, Benefits{}), WithTier(Gold, 20, Benefits{})); !errors.Is(err, ErrInvalidTier) {
+		t.Errorf("expected error %v for a tier given twice, got %v", ErrInvalidTier, err)
+	}
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/order"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// memoryRepository stores orders by value, the order aggregate never changes
+// the slices it shares with its copies
+type memoryRepository struct {
+	orders map[uuid.UUID]aggregate.Order
+	sync.Mutex
+}
+
+func New() order.OrderRepository {
+	return &memoryRepository{
+		orders: map[uuid.UUID]aggregate.Order{},
+	}
+}
+
+func (r *memoryRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Order, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if o, ok := r.orders[id]; ok {
+		return o, nil
+	}
+
+	return aggregate.Order{}, order.ErrOrderNotFound
+}
+
+func (r *memoryRepository) Add(ctx context.Context, o aggregate.Order) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.orders[o.GetID()]; ok {
+		return fmt.Errorf("order already exists :%w", order.ErrFailedToAddOrder)
+	}
+
+	r.orders[o.GetID()] = o
+
+	return nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, o aggregate.Order) error {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.orders[o.GetID()]
+	if !ok {
+		return fmt.Errorf("order is not exists :%w", order.ErrUpdateOrder)
+	}
+	if stored.GetVersion() != o.GetVersion() {
+		return fmt.Errorf("order is at version %d, not %d :%w", stored.GetVersion(), o.GetVersion(), order.ErrConcurrentUpdate)
+	}
+
+	o.SetVersion(o.GetVersion() + 1)
+	r.orders[o.GetID()] = o
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/order"
+	"golang-learn-ddd/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_memoryRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+	now := time.Now()
+
+	customerID := uuid.New()
+	o, err := aggregate.NewOrder(customerID, []aggregate.OrderLine{{ProductID: uuid.New(), UnitPrice: 3}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Pay(aggregate.PaymentAccount, []valueobject.Transaction{
+		valueobject.NewTransaction(valueobject.TransactionPayment, 300, customerID, uuid.New(), now),
+	}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Update(ctx, o); !errors.Is(err, order.ErrUpdateOrder) {
+		t.Errorf("expected error %v, got %v", order.ErrUpdateOrder, err)
+	}
+	if err := repo.Add(ctx, o); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, o); !errors.Is(err, order.ErrFailedToAddOrder) {
+		t.Errorf("expected error %v, got %v", order.ErrFailedToAddOrder, err)
+	}
+
+	first, err := repo.Get(ctx, o.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := repo.Get(ctx, o.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := first.RefundAmount(1, "cold", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.AddRefund(refund); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	// the refund is only saved on Update, and the second copy is stale now
+	if len(second.GetRefunds()) != 0 {
+		t.Errorf("expected the second copy to be untouched, got %d refunds", len(second.GetRefunds()))
+	}
+	refund, err = second.RefundAmount(3, "cold", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := second.AddRefund(refund); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Update(ctx, second); !errors.Is(err, order.ErrConcurrentUpdate) {
+		t.Errorf("expected error %v, got %v", order.ErrConcurrentUpdate, err)
+	}
+
+	stored, err := repo.Get(ctx, o.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.GetRefunded() != 1 || stored.GetStatus() != aggregate.OrderPartiallyRefunded || stored.GetVersion() != 1 {
+		t.Errorf("expected version 1 with 1 refunded, got version %d with %v refunded and status %q",
+			stored.GetVersion(), stored.GetRefunded(), stored.GetStatus())
+	}
+
+	if _, err := repo.Get(ctx, uuid.New()); !errors.Is(err, order.ErrOrderNotFound) {
+		t.Errorf("expected error %v, got %v", order.ErrOrderNotFound, err)
+	}
+}
@@ -0,0 +1,290 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/order"
+	"golang-learn-ddd/valueobject"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	db    *mongo.Database
+	order *mongo.Collection
+}
+
+// MongoOrder is how an order is stored in mongodb, by this repository and
+// within the tabs of domain/tab/mongo
+type MongoOrder struct {
+	ID             uuid.UUID      `bson:"id"`
+	CustomerID     uuid.UUID      `bson:"customer_id"`
+	Lines          []mongoLine    `bson:"lines"`
+	CreatedAt      time.Time      `bson:"created_at"`
+	Coupon         string         `bson:"coupon,omitempty"`
+	CouponDiscount float64        `bson:"coupon_discount"`
+	Tier           string         `bson:"tier,omitempty"`
+	TierDiscount   float64        `bson:"tier_discount"`
+	Priority       int            `bson:"priority"`
+	PointsRedeemed int            `bson:"points_redeemed"`
+	PointsDiscount float64        `bson:"points_discount"`
+	Taxes          []mongoTaxLine `bson:"taxes"`
+	TaxInclusive   bool           `bson:"tax_inclusive"`
+
+	Status        string             `bson:"status,omitempty"`
+	PaymentMethod string             `bson:"payment_method,omitempty"`
+	Payments      []mongoTransaction `bson:"payments,omitempty"`
+	PaidAt        time.Time          `bson:"paid_at,omitempty"`
+	Refunds       []mongoRefund      `bson:"refunds,omitempty"`
+	Extras        []mongoTransaction `bson:"extras,omitempty"`
+	Version       int                `bson:"version,omitempty"`
+}
+
+type mongoLine struct {
+	ProductID   uuid.UUID   `bson:"product_id"`
+	VariantID   uuid.UUID   `bson:"variant_id"`
+	ModifierIDs []uuid.UUID `bson:"modifier_ids"`
+	CategoryID  uuid.UUID   `bson:"category_id"`
+	Name        string      `bson:"name"`
+	UnitPrice   float64     `bson:"unit_price"`
+	Discount    float64     `bson:"discount"`
+	Rule        string      `bson:"rule,omitempty"`
+}
+
+type mongoTaxLine struct {
+	Name   string  `bson:"name"`
+	Rate   float64 `bson:"rate"`
+	Base   float64 `bson:"base"`
+	Amount float64 `bson:"amount"`
+}
+
+type mongoTransaction struct {
+	Kind      string    `bson:"kind"`
+	Amount    int       `bson:"amount"`
+	From      uuid.UUID `bson:"from"`
+	To        uuid.UUID `bson:"to"`
+	CreatedAt time.Time `bson:"created_at"`
+	Reference string    `bson:"reference,omitempty"`
+}
+
+type mongoRefund struct {
+	ID           uuid.UUID          `bson:"id"`
+	Lines        []int              `bson:"lines,omitempty"`
+	Reason       string             `bson:"reason"`
+	Transactions []mongoTransaction `bson:"transactions"`
+	CreatedAt    time.Time          `bson:"created_at"`
+}
+
+func NewFromOrder(o aggregate.Order) MongoOrder {
+	lines := make([]mongoLine, 0, len(o.GetLines()))
+	for _, l := range o.GetLines() {
+		lines = append(lines, mongoLine{
+			ProductID:   l.ProductID,
+			VariantID:   l.VariantID,
+			ModifierIDs: l.ModifierIDs,
+			CategoryID:  l.CategoryID,
+			Name:        l.Name,
+			UnitPrice:   l.UnitPrice,
+			Discount:    l.Discount,
+			Rule:        l.Rule,
+		})
+	}
+
+	taxes := make([]mongoTaxLine, 0, len(o.GetTaxes()))
+	for _, t := range o.GetTaxes() {
+		taxes = append(taxes, mongoTaxLine(t))
+	}
+
+	refunds := make([]mongoRefund, 0, len(o.GetRefunds()))
+	for _, r := range o.GetRefunds() {
+		refunds = append(refunds, mongoRefund{
+			ID:           r.ID,
+			Lines:        r.Lines,
+			Reason:       r.Reason,
+			Transactions: newFromTransactions(r.Transactions),
+			CreatedAt:    r.CreatedAt,
+		})
+	}
+
+	return MongoOrder{
+		ID:             o.GetID(),
+		CustomerID:     o.GetCustomerID(),
+		Lines:          lines,
+		CreatedAt:      o.GetCreatedAt(),
+		Coupon:         o.GetCoupon(),
+		CouponDiscount: o.GetCouponDiscount(),
+		Tier:           o.GetTier(),
+		TierDiscount:   o.GetTierDiscount(),
+		Priority:       o.GetPriority(),
+		PointsRedeemed: o.GetPointsRedeemed(),
+		PointsDiscount: o.GetPointsDiscount(),
+		Taxes:          taxes,
+		TaxInclusive:   o.IsTaxInclusive(),
+		Status:         string(o.GetStatus()),
+		PaymentMethod:  string(o.GetPaymentMethod()),
+		Payments:       newFromTransactions(o.GetPayments()),
+		Extras:         newFromTransactions(o.GetExtras()),
+		PaidAt:         o.GetPaidAt(),
+		Refunds:        refunds,
+		Version:        o.GetVersion(),
+	}
+}
+
+func newFromTransactions(transactions []valueobject.Transaction) []mongoTransaction {
+	rows := make([]mongoTransaction, 0, len(transactions))
+	for _, t := range transactions {
+		rows = append(rows, mongoTransaction{
+			Kind:      string(t.Kind()),
+			Amount:    t.Amount(),
+			From:      t.From(),
+			To:        t.To(),
+			CreatedAt: t.CreatedAt(),
+			Reference: t.Reference(),
+		})
+	}
+
+	return rows
+}
+
+func toTransactions(rows []mongoTransaction) []valueobject.Transaction {
+	transactions := make([]valueobject.Transaction, 0, len(rows))
+	for _, t := range rows {
+		transactions = append(transactions, valueobject.NewTransaction(
+			valueobject.TransactionKind(t.Kind), t.Amount, t.From, t.To, t.CreatedAt,
+		).WithReference(t.Reference))
+	}
+
+	return transactions
+}
+
+func (m *MongoOrder) ToAggregate() (aggregate.Order, error) {
+	lines := make([]aggregate.OrderLine, 0, len(m.Lines))
+	for _, l := range m.Lines {
+		lines = append(lines, aggregate.OrderLine{
+			ProductID:   l.ProductID,
+			VariantID:   l.VariantID,
+			ModifierIDs: l.ModifierIDs,
+			CategoryID:  l.CategoryID,
+			Name:        l.Name,
+			UnitPrice:   l.UnitPrice,
+			Discount:    l.Discount,
+			Rule:        l.Rule,
+		})
+	}
+
+	o, err := aggregate.NewOrder(m.CustomerID, lines, m.CreatedAt)
+	if err != nil {
+		return aggregate.Order{}, err
+	}
+	o.SetID(m.ID)
+
+	o.ApplyTierDiscount(m.Tier, m.TierDiscount)
+	o.SetPriority(m.Priority)
+	if m.Coupon != "" {
+		o.ApplyCoupon(m.Coupon, m.CouponDiscount)
+	}
+	if m.PointsRedeemed > 0 {
+		o.ApplyPoints(m.PointsRedeemed, m.PointsDiscount)
+	}
+
+	taxes := make([]aggregate.TaxLine, 0, len(m.Taxes))
+	for _, t := range m.Taxes {
+		taxes = append(taxes, aggregate.TaxLine(t))
+	}
+	o.ApplyTax(taxes, m.TaxInclusive)
+
+	if aggregate.OrderStatus(m.Status) != aggregate.OrderUnpaid {
+		if err := o.Pay(aggregate.PaymentMethod(m.PaymentMethod), toTransactions(m.Payments), m.PaidAt); err != nil {
+			return aggregate.Order{}, err
+		}
+		if err := o.AddExtras(toTransactions(m.Extras)); err != nil {
+			return aggregate.Order{}, err
+		}
+	}
+	for _, r := range m.Refunds {
+		err := o.AddRefund(aggregate.Refund{
+			ID:           r.ID,
+			Lines:        r.Lines,
+			Reason:       r.Reason,
+			Transactions: toTransactions(r.Transactions),
+			CreatedAt:    r.CreatedAt,
+		})
+		if err != nil {
+			return aggregate.Order{}, fmt.Errorf("invalid refund %s of order %s: %w", r.ID, m.ID, err)
+		}
+	}
+	o.SetVersion(m.Version)
+
+	return o, nil
+}
+
+func New(ctx context.Context, connectionString string) (order.OrderRepository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("learn-golang-ddd")
+	orders := db.Collection("orders")
+
+	return &mongoRepository{
+		db:    db,
+		order: orders,
+	}, nil
+}
+
+func (r *mongoRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var row MongoOrder
+	err := r.order.FindOne(ctx, bson.M{"id": id}).Decode(&row)
+	if err != nil {
+		return aggregate.Order{}, fmt.Errorf("order does not exists: %w; %w", err, order.ErrOrderNotFound)
+	}
+
+	return row.ToAggregate()
+}
+
+func (r *mongoRepository) Add(ctx context.Context, o aggregate.Order) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.order.InsertOne(ctx, NewFromOrder(o))
+	if err != nil {
+		return fmt.Errorf("failed to add an order: %w; %w", err, order.ErrFailedToAddOrder)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Update(ctx context.Context, o aggregate.Order) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := NewFromOrder(o)
+	filter := bson.M{"id": row.ID, "version": row.Version}
+	if row.Version == 0 {
+		// the version is omitted while it is 0
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	}
+	row.Version++
+
+	res, err := r.order.ReplaceOne(ctx, filter, row)
+	if err != nil {
+		return fmt.Errorf("failed to update an order: %w; %w", err, order.ErrUpdateOrder)
+	}
+	if res.MatchedCount == 0 {
+		if n, err := r.order.CountDocuments(ctx, bson.M{"id": row.ID}); err == nil && n == 0 {
+			return fmt.Errorf("order is not exists :%w", order.ErrUpdateOrder)
+		}
+		return fmt.Errorf("order is not at version %d :%w", o.GetVersion(), order.ErrConcurrentUpdate)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOrderNotFound    = errors.New("order not found in repository")
+	ErrFailedToAddOrder = errors.New("failed to add the order")
+	ErrUpdateOrder      = errors.New("failed to update the order")
+	// ErrConcurrentUpdate is returned when the order changed since it was read,
+	// get it again and retry the update
+	ErrConcurrentUpdate = errors.New("the order was updated concurrently")
+)
+
+// OrderRepository keeps the paid orders, to refund them
+type OrderRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (aggregate.Order, error)
+	Add(ctx context.Context, order aggregate.Order) error
+	// Update fails with ErrConcurrentUpdate when the version of the order is
+	// not the stored one, and increments the stored version otherwise
+	Update(ctx context.Context, order aggregate.Order) error
+}
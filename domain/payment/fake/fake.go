@@ -0,0 +1,259 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-learn-ddd/domain/payment"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Gateway is an in memory card processor for tests and local runs, it can
+// be told to be slow, to fail and to decline payments
+type Gateway struct {
+	payments map[string]payment.Payment
+	// results replays the calls made with the same idempotency key
+	results map[string]result
+
+	delay    time.Duration
+	declines func(customerID uuid.UUID, amount int) bool
+	failures int
+	calls    int
+
+	sync.Mutex
+}
+
+type result struct {
+	request string
+	payment payment.Payment
+	err     error
+}
+
+type Configuration func(g *Gateway) error
+
+func New(cfgs ...Configuration) (*Gateway, error) {
+	g := &Gateway{
+		payments: map[string]payment.Payment{},
+		results:  map[string]result{},
+		declines: func(uuid.UUID, int) bool { return false },
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(g); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+// WithDelay makes every call take at least d, unless its context is done first
+func WithDelay(d time.Duration) Configuration {
+	return func(g *Gateway) error {
+		g.delay = d
+		return nil
+	}
+}
+
+// DeclineAbove declines authorizations of more than amount cents
+func DeclineAbove(amount int) Configuration {
+	return func(g *Gateway) error {
+		previous := g.declines
+		g.declines = func(customerID uuid.UUID, a int) bool {
+			return a > amount || previous(customerID, a)
+		}
+		return nil
+	}
+}
+
+// DeclineCustomers declines every authorization of the customers
+func DeclineCustomers(customerIDs ...uuid.UUID) Configuration {
+	return func(g *Gateway) error {
+		declined := map[uuid.UUID]bool{}
+		for _, id := range customerIDs {
+			declined[id] = true
+		}
+
+		previous := g.declines
+		g.declines = func(customerID uuid.UUID, a int) bool {
+			return declined[customerID] || previous(customerID, a)
+		}
+		return nil
+	}
+}
+
+// WithFailures makes the next n calls fail with payment.ErrPaymentFailed
+func WithFailures(n int) Configuration {
+	return func(g *Gateway) error {
+		g.failures = n
+		return nil
+	}
+}
+
+// FailNext makes the next n calls fail with payment.ErrPaymentFailed
+func (g *Gateway) FailNext(n int) {
+	g.Lock()
+	defer g.Unlock()
+
+	g.failures = n
+}
+
+// Calls is the number of calls the gateway processed, replays excluded
+func (g *Gateway) Calls() int {
+	g.Lock()
+	defer g.Unlock()
+
+	return g.calls
+}
+
+// Payment returns the payment as the gateway knows it
+func (g *Gateway) Payment(id string) (payment.Payment, bool) {
+	g.Lock()
+	defer g.Unlock()
+
+	p, ok := g.payments[id]
+	return p, ok
+}
+
+// Payments returns the payments of the customer, in no particular order
+func (g *Gateway) Payments(customerID uuid.UUID) []payment.Payment {
+	g.Lock()
+	defer g.Unlock()
+
+	var payments []payment.Payment
+	for _, p := range g.payments {
+		if p.CustomerID == customerID {
+			payments = append(payments, p)
+		}
+	}
+
+	return payments
+}
+
+func (g *Gateway) Authorize(ctx context.Context, idempotencyKey string, customerID uuid.UUID, amount int) (payment.Payment, error) {
+	request := fmt.Sprintf("authorize %s %d", customerID, amount)
+	return g.call(ctx, idempotencyKey, request, func() (payment.Payment, error) {
+		if amount <= 0 {
+			return payment.Payment{}, payment.ErrInvalidAmount
+		}
+		if g.declines(customerID, amount) {
+			return payment.Payment{}, payment.ErrPaymentDeclined
+		}
+
+		p := payment.Payment{
+			ID:         uuid.NewString(),
+			CustomerID: customerID,
+			Status:     payment.Authorized,
+			Amount:     amount,
+		}
+		g.payments[p.ID] = p
+
+		return p, nil
+	})
+}
+
+func (g *Gateway) Capture(ctx context.Context, idempotencyKey string, paymentID string, amount int) (payment.Payment, error) {
+	request := fmt.Sprintf("capture %s %d", paymentID, amount)
+	return g.call(ctx, idempotencyKey, request, func() (payment.Payment, error) {
+		p, ok := g.payments[paymentID]
+		if !ok {
+			return payment.Payment{}, payment.ErrPaymentNotFound
+		}
+		if amount <= 0 || amount > p.Amount {
+			return payment.Payment{}, payment.ErrInvalidAmount
+		}
+		if p.Status != payment.Authorized {
+			return payment.Payment{}, payment.ErrInvalidPaymentState
+		}
+
+		p.Status = payment.Captured
+		p.Captured = amount
+		g.payments[p.ID] = p
+
+		return p, nil
+	})
+}
+
+func (g *Gateway) Void(ctx context.Context, idempotencyKey string, paymentID string) (payment.Payment, error) {
+	request := fmt.Sprintf("void %s", paymentID)
+	return g.call(ctx, idempotencyKey, request, func() (payment.Payment, error) {
+		p, ok := g.payments[paymentID]
+		if !ok {
+			return payment.Payment{}, payment.ErrPaymentNotFound
+		}
+		if p.Status != payment.Authorized {
+			return payment.Payment{}, payment.ErrInvalidPaymentState
+		}
+
+		p.Status = payment.Voided
+		g.payments[p.ID] = p
+
+		return p, nil
+	})
+}
+
+func (g *Gateway) Refund(ctx context.Context, idempotencyKey string, paymentID string, amount int) (payment.Payment, error) {
+	request := fmt.Sprintf("refund %s %d", paymentID, amount)
+	return g.call(ctx, idempotencyKey, request, func() (payment.Payment, error) {
+		p, ok := g.payments[paymentID]
+		if !ok {
+			return payment.Payment{}, payment.ErrPaymentNotFound
+		}
+		if p.Status != payment.Captured && p.Status != payment.Refunded {
+			return payment.Payment{}, payment.ErrInvalidPaymentState
+		}
+		if amount <= 0 || p.Refunded+amount > p.Captured {
+			return payment.Payment{}, payment.ErrInvalidAmount
+		}
+
+		p.Refunded += amount
+		if p.Refunded == p.Captured {
+			p.Status = payment.Refunded
+		}
+		g.payments[p.ID] = p
+
+		return p, nil
+	})
+}
+
+// call waits for the delay, then replays the result of the idempotency key
+// or runs do. Failures are not recorded so the call can be retried.
+func (g *Gateway) call(ctx context.Context, idempotencyKey, request string, do func() (payment.Payment, error)) (payment.Payment, error) {
+	if idempotencyKey == "" {
+		return payment.Payment{}, payment.ErrIdempotencyKey
+	}
+
+	if g.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return payment.Payment{}, fmt.Errorf("%w; %w", ctx.Err(), payment.ErrPaymentFailed)
+		case <-time.After(g.delay):
+		}
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	if r, ok := g.results[idempotencyKey]; ok {
+		if r.request != request {
+			return payment.Payment{}, fmt.Errorf("key %q was used for %q: %w", idempotencyKey, r.request, payment.ErrIdempotencyKeyReused)
+		}
+		return r.payment, r.err
+	}
+
+	if g.failures > 0 {
+		g.failures--
+		return payment.Payment{}, payment.ErrPaymentFailed
+	}
+
+	g.calls++
+	p, err := do()
+	if !errors.Is(err, payment.ErrPaymentFailed) {
+		g.results[idempotencyKey] = result{request: request, payment: p, err: err}
+	}
+
+	return p, err
+}
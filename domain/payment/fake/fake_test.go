@@ -0,0 +1,171 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/domain/payment"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGateway_Authorize(t *testing.T) {
+	ctx := context.Background()
+	declined := uuid.New()
+
+	type testCase struct {
+		test        string
+		cfgs        []Configuration
+		customerID  uuid.UUID
+		amount      int
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:       "Authorized",
+			customerID: uuid.New(),
+			amount:     1000,
+		}, {
+			test:        "Invalid amount",
+			customerID:  uuid.New(),
+			amount:      0,
+			expectedErr: payment.ErrInvalidAmount,
+		}, {
+			test:        "Declined above the limit",
+			cfgs:        []Configuration{DeclineAbove(500)},
+			customerID:  uuid.New(),
+			amount:      501,
+			expectedErr: payment.ErrPaymentDeclined,
+		}, {
+			test:        "Declined customer",
+			cfgs:        []Configuration{DeclineAbove(5000), DeclineCustomers(declined)},
+			customerID:  declined,
+			amount:      100,
+			expectedErr: payment.ErrPaymentDeclined,
+		}, {
+			test:        "Failing gateway",
+			cfgs:        []Configuration{WithFailures(1)},
+			customerID:  uuid.New(),
+			amount:      100,
+			expectedErr: payment.ErrPaymentFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			g, err := New(tc.cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := g.Authorize(ctx, "key", tc.customerID, tc.amount)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && (p.Status != payment.Authorized || p.Amount != tc.amount || p.CustomerID != tc.customerID) {
+				t.Errorf("unexpected payment %+v", p)
+			}
+		})
+	}
+}
+
+func TestGateway_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	g, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := g.Authorize(ctx, "authorize", uuid.New(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Refund(ctx, "early refund", p.ID, 100); !errors.Is(err, payment.ErrInvalidPaymentState) {
+		t.Errorf("expected error %v, got %v", payment.ErrInvalidPaymentState, err)
+	}
+	if _, err := g.Capture(ctx, "capture too much", p.ID, 1001); !errors.Is(err, payment.ErrInvalidAmount) {
+		t.Errorf("expected error %v, got %v", payment.ErrInvalidAmount, err)
+	}
+
+	p, err = g.Capture(ctx, "capture", p.ID, 800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != payment.Captured || p.Captured != 800 {
+		t.Errorf("unexpected payment %+v", p)
+	}
+	if _, err := g.Void(ctx, "void", p.ID); !errors.Is(err, payment.ErrInvalidPaymentState) {
+		t.Errorf("expected error %v, got %v", payment.ErrInvalidPaymentState, err)
+	}
+
+	if _, err := g.Refund(ctx, "refund 1", p.ID, 500); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Refund(ctx, "refund 2", p.ID, 301); !errors.Is(err, payment.ErrInvalidAmount) {
+		t.Errorf("expected error %v, got %v", payment.ErrInvalidAmount, err)
+	}
+	p, err = g.Refund(ctx, "refund 3", p.ID, 300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != payment.Refunded || p.Refunded != 800 {
+		t.Errorf("unexpected payment %+v", p)
+	}
+
+	if _, err := g.Void(ctx, "unknown", "nope"); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected error %v, got %v", payment.ErrPaymentNotFound, err)
+	}
+}
+
+func TestGateway_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	customerID := uuid.New()
+	g, err := New(WithFailures(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Authorize(ctx, "", customerID, 1000); !errors.Is(err, payment.ErrIdempotencyKey) {
+		t.Errorf("expected error %v, got %v", payment.ErrIdempotencyKey, err)
+	}
+
+	// failures are not recorded, the retry goes through
+	if _, err := g.Authorize(ctx, "key", customerID, 1000); !errors.Is(err, payment.ErrPaymentFailed) {
+		t.Fatalf("expected error %v, got %v", payment.ErrPaymentFailed, err)
+	}
+	first, err := g.Authorize(ctx, "key", customerID, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := g.Authorize(ctx, "key", customerID, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || g.Calls() != 1 {
+		t.Errorf("expected the payment %s to be replayed after %d call, got %s after %d calls", first.ID, 1, second.ID, g.Calls())
+	}
+
+	if _, err := g.Authorize(ctx, "key", customerID, 2000); !errors.Is(err, payment.ErrIdempotencyKeyReused) {
+		t.Errorf("expected error %v, got %v", payment.ErrIdempotencyKeyReused, err)
+	}
+}
+
+func TestGateway_Delay(t *testing.T) {
+	g, err := New(WithDelay(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := g.Authorize(ctx, "key", uuid.New(), 1000); !errors.Is(err, payment.ErrPaymentFailed) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error %v, got %v", context.DeadlineExceeded, err)
+	}
+	if g.Calls() != 0 {
+		t.Errorf("expected no call to be processed, got %d", g.Calls())
+	}
+}
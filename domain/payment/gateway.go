@@ -0,0 +1,51 @@
+package payment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPaymentDeclined      = errors.New("the payment was declined")
+	ErrPaymentFailed        = errors.New("the payment gateway failed")
+	ErrPaymentNotFound      = errors.New("payment not found")
+	ErrInvalidPaymentState  = errors.New("the payment cannot do this in its current state")
+	ErrInvalidAmount        = errors.New("a payment amount has to be positive")
+	ErrIdempotencyKey       = errors.New("an idempotency key is required")
+	ErrIdempotencyKeyReused = errors.New("the idempotency key was used for another request")
+)
+
+type Status string
+
+const (
+	Authorized Status = "authorized"
+	Captured   Status = "captured"
+	Voided     Status = "voided"
+	Refunded   Status = "refunded"
+)
+
+// Payment is a card payment as known by the gateway, amounts are in cents
+type Payment struct {
+	ID         string
+	CustomerID uuid.UUID
+	Status     Status
+	Amount     int
+	Captured   int
+	Refunded   int
+}
+
+// PaymentGateway is the port to a card processor. Every call takes an
+// idempotency key: calling again with the same key returns the result of
+// the first call instead of charging twice, so failed calls can be retried.
+type PaymentGateway interface {
+	// Authorize holds amount on the card of the customer
+	Authorize(ctx context.Context, idempotencyKey string, customerID uuid.UUID, amount int) (Payment, error)
+	// Capture charges up to the authorized amount
+	Capture(ctx context.Context, idempotencyKey string, paymentID string, amount int) (Payment, error)
+	// Void releases an authorization that was not captured
+	Void(ctx context.Context, idempotencyKey string, paymentID string) (Payment, error)
+	// Refund gives back up to the captured amount, in one or several refunds
+	Refund(ctx context.Context, idempotencyKey string, paymentID string, amount int) (Payment, error)
+}
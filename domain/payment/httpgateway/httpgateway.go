@@ -0,0 +1,176 @@
+package httpgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang-learn-ddd/domain/payment"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKeyHeader carries the idempotency key of every request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// The codes of the errors answered with 422
+const (
+	CodeInvalidAmount        = "invalid_amount"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+)
+
+// Gateway calls a card processor over its JSON HTTP API:
+//
+//	POST /payments                 {"customer_id", "amount"} authorizes
+//	POST /payments/{id}/capture    {"amount"}                captures
+//	POST /payments/{id}/void                                 voids
+//	POST /payments/{id}/refunds    {"amount"}                refunds
+//
+// Every call answers with the payment, 402 when declined, 404 when the
+// payment is unknown, 409 when its state forbids the call and 422 for
+// invalid amounts or reused idempotency keys, told apart by the code of the
+// error.
+type Gateway struct {
+	baseURL *url.URL
+	client  *http.Client
+	apiKey  string
+}
+
+type Configuration func(g *Gateway) error
+
+func New(baseURL string, cfgs ...Configuration) (*Gateway, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid payment gateway url: %w", err)
+	}
+
+	g := &Gateway{
+		baseURL: u,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(g); err != nil {
+			return nil, err
+		}
+	}
+
+	return g, nil
+}
+
+func WithHTTPClient(client *http.Client) Configuration {
+	return func(g *Gateway) error {
+		g.client = client
+		return nil
+	}
+}
+
+// WithAPIKey authenticates every request with a bearer token
+func WithAPIKey(apiKey string) Configuration {
+	return func(g *Gateway) error {
+		g.apiKey = apiKey
+		return nil
+	}
+}
+
+// PaymentRequest is the body of the requests
+type PaymentRequest struct {
+	CustomerID uuid.UUID `json:"customer_id,omitempty"`
+	Amount     int       `json:"amount,omitempty"`
+}
+
+// PaymentResponse is the body of the responses
+type PaymentResponse struct {
+	ID         string    `json:"id"`
+	CustomerID uuid.UUID `json:"customer_id"`
+	Status     string    `json:"status"`
+	Amount     int       `json:"amount"`
+	Captured   int       `json:"captured"`
+	Refunded   int       `json:"refunded"`
+	Error      string    `json:"error,omitempty"`
+	Code       string    `json:"code,omitempty"`
+}
+
+func (g *Gateway) Authorize(ctx context.Context, idempotencyKey string, customerID uuid.UUID, amount int) (payment.Payment, error) {
+	return g.post(ctx, idempotencyKey, "/payments", PaymentRequest{CustomerID: customerID, Amount: amount})
+}
+
+func (g *Gateway) Capture(ctx context.Context, idempotencyKey string, paymentID string, amount int) (payment.Payment, error) {
+	return g.post(ctx, idempotencyKey, "/payments/"+url.PathEscape(paymentID)+"/capture", PaymentRequest{Amount: amount})
+}
+
+func (g *Gateway) Void(ctx context.Context, idempotencyKey string, paymentID string) (payment.Payment, error) {
+	return g.post(ctx, idempotencyKey, "/payments/"+url.PathEscape(paymentID)+"/void", PaymentRequest{})
+}
+
+func (g *Gateway) Refund(ctx context.Context, idempotencyKey string, paymentID string, amount int) (payment.Payment, error) {
+	return g.post(ctx, idempotencyKey, "/payments/"+url.PathEscape(paymentID)+"/refunds", PaymentRequest{Amount: amount})
+}
+
+func (g *Gateway) post(ctx context.Context, idempotencyKey, path string, body PaymentRequest) (payment.Payment, error) {
+	if idempotencyKey == "" {
+		return payment.Payment{}, payment.ErrIdempotencyKey
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return payment.Payment{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.baseURL.String()+path, bytes.NewReader(data))
+	if err != nil {
+		return payment.Payment{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	if g.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+g.apiKey)
+	}
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return payment.Payment{}, fmt.Errorf("%w; %w", err, payment.ErrPaymentFailed)
+	}
+	defer res.Body.Close()
+
+	var row PaymentResponse
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&row); err != nil && res.StatusCode < 400 {
+		return payment.Payment{}, fmt.Errorf("invalid payment gateway response: %w; %w", err, payment.ErrPaymentFailed)
+	}
+
+	if res.StatusCode >= 400 {
+		return payment.Payment{}, fmt.Errorf("payment gateway answered %d %s: %w", res.StatusCode, row.Error, statusError(res.StatusCode, row.Code))
+	}
+
+	return payment.Payment{
+		ID:         row.ID,
+		CustomerID: row.CustomerID,
+		Status:     payment.Status(row.Status),
+		Amount:     row.Amount,
+		Captured:   row.Captured,
+		Refunded:   row.Refunded,
+	}, nil
+}
+
+func statusError(status int, code string) error {
+	switch status {
+	case http.StatusPaymentRequired:
+		return payment.ErrPaymentDeclined
+	case http.StatusNotFound:
+		return payment.ErrPaymentNotFound
+	case http.StatusConflict:
+		return payment.ErrInvalidPaymentState
+	case http.StatusUnprocessableEntity:
+		if code == CodeIdempotencyKeyReused {
+			return payment.ErrIdempotencyKeyReused
+		}
+		return payment.ErrInvalidAmount
+	default:
+		return payment.ErrPaymentFailed
+	}
+}
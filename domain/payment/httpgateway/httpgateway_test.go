@@ -0,0 +1,261 @@
+package httpgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"golang-learn-ddd/domain/payment"
+	"golang-learn-ddd/domain/payment/fake"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// standIn serves the API of the processor from a fake gateway, recording
+// the idempotency keys it receives
+type standIn struct {
+	gateway *fake.Gateway
+	apiKey  string
+
+	sync.Mutex
+	keys []string
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	key := r.Header.Get(IdempotencyKeyHeader)
+	s.Lock()
+	s.keys = append(s.keys, key)
+	s.Unlock()
+
+	var body PaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var p payment.Payment
+	var err error
+	switch parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/"); {
+	case len(parts) == 1 && parts[0] == "payments":
+		p, err = s.gateway.Authorize(r.Context(), key, body.CustomerID, body.Amount)
+	case len(parts) == 3 && parts[2] == "capture":
+		p, err = s.gateway.Capture(r.Context(), key, parts[1], body.Amount)
+	case len(parts) == 3 && parts[2] == "void":
+		p, err = s.gateway.Void(r.Context(), key, parts[1])
+	case len(parts) == 3 && parts[2] == "refunds":
+		p, err = s.gateway.Refund(r.Context(), key, parts[1], body.Amount)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var code string
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, payment.ErrPaymentDeclined):
+		w.WriteHeader(http.StatusPaymentRequired)
+	case errors.Is(err, payment.ErrPaymentNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, payment.ErrInvalidPaymentState):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, payment.ErrInvalidAmount):
+		code = CodeInvalidAmount
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	case errors.Is(err, payment.ErrIdempotencyKeyReused):
+		code = CodeIdempotencyKeyReused
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	case err != nil:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	res := PaymentResponse{
+		ID:         p.ID,
+		CustomerID: p.CustomerID,
+		Status:     string(p.Status),
+		Amount:     p.Amount,
+		Captured:   p.Captured,
+		Refunded:   p.Refunded,
+	}
+	if err != nil {
+		res.Error = err.Error()
+		res.Code = code
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+func newStandIn(t *testing.T, cfgs ...fake.Configuration) (*standIn, *httptest.Server) {
+	t.Helper()
+
+	g, err := fake.New(cfgs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &standIn{gateway: g, apiKey: "secret"}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+
+	return s, server
+}
+
+func TestGateway_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	customerID := uuid.New()
+	s, server := newStandIn(t)
+
+	g, err := New(server.URL+"/", WithAPIKey("secret"), WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := g.Authorize(ctx, "authorize", customerID, 1250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID == "" || p.CustomerID != customerID || p.Status != payment.Authorized || p.Amount != 1250 {
+		t.Errorf("unexpected payment %+v", p)
+	}
+
+	p, err = g.Capture(ctx, "capture", p.ID, 1250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != payment.Captured || p.Captured != 1250 {
+		t.Errorf("unexpected payment %+v", p)
+	}
+
+	p, err = g.Refund(ctx, "refund", p.ID, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != payment.Captured || p.Refunded != 250 {
+		t.Errorf("unexpected payment %+v", p)
+	}
+
+	if _, err := g.Void(ctx, "void", p.ID); !errors.Is(err, payment.ErrInvalidPaymentState) {
+		t.Errorf("expected error %v, got %v", payment.ErrInvalidPaymentState, err)
+	}
+	if _, err := g.Capture(ctx, "capture unknown", "nope", 100); !errors.Is(err, payment.ErrPaymentNotFound) {
+		t.Errorf("expected error %v, got %v", payment.ErrPaymentNotFound, err)
+	}
+
+	expected := []string{"authorize", "capture", "refund", "void", "capture unknown"}
+	if strings.Join(s.keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected idempotency keys %v, got %v", expected, s.keys)
+	}
+}
+
+func TestGateway_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	type testCase struct {
+		test        string
+		cfgs        []fake.Configuration
+		gateway     []Configuration
+		key         string
+		amount      int
+		expectedErr error
+	}
+
+	testCases := []testCase{
+		{
+			test:        "Declined",
+			cfgs:        []fake.Configuration{fake.DeclineAbove(1000)},
+			key:         "key",
+			amount:      1001,
+			expectedErr: payment.ErrPaymentDeclined,
+		}, {
+			test:        "Invalid amount",
+			key:         "key",
+			amount:      -1,
+			expectedErr: payment.ErrInvalidAmount,
+		}, {
+			test:        "Missing idempotency key",
+			amount:      100,
+			expectedErr: payment.ErrIdempotencyKey,
+		}, {
+			test:        "Gateway down",
+			cfgs:        []fake.Configuration{fake.WithFailures(1)},
+			key:         "key",
+			amount:      100,
+			expectedErr: payment.ErrPaymentFailed,
+		}, {
+			test:        "Unauthorized",
+			gateway:     []Configuration{WithAPIKey("wrong")},
+			key:         "key",
+			amount:      100,
+			expectedErr: payment.ErrPaymentFailed,
+		}, {
+			test:        "Timeout",
+			cfgs:        []fake.Configuration{fake.WithDelay(time.Second)},
+			gateway:     []Configuration{WithHTTPClient(&http.Client{Timeout: 10 * time.Millisecond})},
+			key:         "key",
+			amount:      100,
+			expectedErr: payment.ErrPaymentFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.test, func(t *testing.T) {
+			_, server := newStandIn(t, tc.cfgs...)
+
+			g, err := New(server.URL, append([]Configuration{WithAPIKey("secret")}, tc.gateway...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = g.Authorize(ctx, tc.key, uuid.New(), tc.amount)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestGateway_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	customerID := uuid.New()
+	s, server := newStandIn(t, fake.WithFailures(1))
+
+	g, err := New(server.URL, WithAPIKey("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.Authorize(ctx, "key", customerID, 1000); !errors.Is(err, payment.ErrPaymentFailed) {
+		t.Fatalf("expected error %v, got %v", payment.ErrPaymentFailed, err)
+	}
+
+	first, err := g.Authorize(ctx, "key", customerID, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := g.Authorize(ctx, "key", customerID, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second || s.gateway.Calls() != 1 {
+		t.Errorf("expected the payment %s to be replayed after %d call, got %s after %d calls", first.ID, 1, second.ID, s.gateway.Calls())
+	}
+
+	// the same key for another amount is not an invalid amount
+	_, err = g.Authorize(ctx, "key", customerID, 2000)
+	if !errors.Is(err, payment.ErrIdempotencyKeyReused) || errors.Is(err, payment.ErrInvalidAmount) {
+		t.Errorf("expected error %v, got %v", payment.ErrIdempotencyKeyReused, err)
+	}
+}
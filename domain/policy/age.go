@@ -0,0 +1,46 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"time"
+)
+
+var (
+	ErrAgeRestricted = errors.New("customer does not meet the minimum age of the product")
+)
+
+// CheckAgeRestriction verifies the customer is old enough, at the given time,
+// for every age restricted product. A customer without a known date of birth
+// cannot order age restricted products.
+func CheckAgeRestriction(c aggregate.Customer, products []aggregate.Product, at time.Time) error {
+	for _, p := range products {
+		if !p.IsAgeRestricted() {
+			continue
+		}
+
+		dob := c.GetDateOfBirth()
+		if dob.IsZero() {
+			return fmt.Errorf("%s requires a known date of birth: %w", p.GetItem().Name, ErrAgeRestricted)
+		}
+
+		if age := Age(dob, at); age < p.GetMinimumAge() {
+			return fmt.Errorf("%s requires age %d, customer is %d: %w", p.GetItem().Name, p.GetMinimumAge(), age, ErrAgeRestricted)
+		}
+	}
+
+	return nil
+}
+
+// Age returns the age in full years, at the given time, of someone born on dob
+func Age(dob, at time.Time) int {
+	at = at.In(dob.Location())
+
+	age := at.Year() - dob.Year()
+	if at.Month() < dob.Month() || (at.Month() == dob.Month() && at.Day() < dob.Day()) {
+		age--
+	}
+
+	return age
+}
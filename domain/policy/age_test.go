@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"testing"
+	"time"
+)
+
+func TestPolicy_Age(t *testing.T) {
+	dob := time.Date(2000, time.March, 15, 0, 0, 0, 0, time.UTC)
+
+	type testCase struct {
+		name     string
+		at       time.Time
+		expected int
+	}
+	tests := []testCase{
+		{name: "day before birthday", at: time.Date(2021, time.March, 14, 23, 0, 0, 0, time.UTC), expected: 20},
+		{name: "on birthday", at: time.Date(2021, time.March, 15, 0, 0, 0, 0, time.UTC), expected: 21},
+		{name: "month after birthday", at: time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC), expected: 21},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Age(dob, tt.at); got != tt.expected {
+				t.Errorf("expected age %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckAgeRestriction(t *testing.T) {
+	now := time.Date(2023, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 99.92, aggregate.WithMinimumAge(21))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakso, err := aggregate.NewProduct("Bakso Kuah", "Bakso Kuah pedah hot jeletot", 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adult, err := aggregate.NewCustomer("Adhiana", aggregate.WithDateOfBirth(now.AddDate(-21, 0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	minor, err := aggregate.NewCustomer("SeeU", aggregate.WithDateOfBirth(now.AddDate(-21, 0, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		customer    aggregate.Customer
+		products    []aggregate.Product
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:     "adult orders beer",
+			customer: adult,
+			products: []aggregate.Product{bakso, beer},
+		},
+		{
+			name:        "minor orders beer",
+			customer:    minor,
+			products:    []aggregate.Product{bakso, beer},
+			expectedErr: ErrAgeRestricted,
+		},
+		{
+			name:     "minor orders unrestricted products",
+			customer: minor,
+			products: []aggregate.Product{bakso},
+		},
+		{
+			name:        "unknown date of birth orders beer",
+			customer:    unknown,
+			products:    []aggregate.Product{beer},
+			expectedErr: ErrAgeRestricted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAgeRestriction(tt.customer, tt.products, now)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
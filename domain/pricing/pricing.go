@@ -0,0 +1,179 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRule = errors.New("invalid pricing rule")
+)
+
+// Rule discounts a product ordered at a given time
+type Rule interface {
+	Name() string
+	// Discount returns the discount for one unit priced unitPrice, ok is false
+	// when the rule does not apply
+	Discount(p aggregate.Product, unitPrice float64, at time.Time) (discount float64, ok bool)
+}
+
+// Engine picks, for every line, the rule granting the biggest discount.
+// Discounts never stack.
+type Engine struct {
+	rules []Rule
+}
+
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{
+		rules: rules,
+	}
+}
+
+// Apply sets the discount and the rule of the line ordered at the given time
+func (e *Engine) Apply(line *aggregate.OrderLine, p aggregate.Product, at time.Time) {
+	if e == nil {
+		return
+	}
+
+	for _, rule := range e.rules {
+		discount, ok := rule.Discount(p, line.UnitPrice, at)
+		if !ok || discount <= line.Discount {
+			continue
+		}
+
+		line.Discount = aggregate.RoundMoney(discount)
+		line.Rule = rule.Name()
+	}
+}
+
+// TimeWindowRule takes a percentage off products ordered between two times
+// of the day, such as a 17:00-19:00 happy hour, optionally restricted to
+// some days of the week and to a category
+type TimeWindowRule struct {
+	name string
+	// start and end are offsets from midnight, the window wraps around
+	// midnight when end is before start and lasts all day when they are equal
+	start   time.Duration
+	end     time.Duration
+	percent float64
+
+	days       map[time.Weekday]bool
+	categoryID uuid.UUID
+	location   *time.Location
+}
+
+type TimeWindowConfiguration func(r *TimeWindowRule) error
+
+// NewTimeWindowRule discounts percent (0-100) between start and end, formatted as "15:04".
+// Use the same start and end for a rule lasting all day, e.g. on Tuesdays only.
+func NewTimeWindowRule(name, start, end string, percent float64, cfgs ...TimeWindowConfiguration) (*TimeWindowRule, error) {
+	if name == "" || percent <= 0 || percent > 100 {
+		return nil, ErrInvalidRule
+	}
+
+	from, err := parseClock(start)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseClock(end)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &TimeWindowRule{
+		name:     name,
+		start:    from,
+		end:      to,
+		percent:  percent,
+		location: time.Local,
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// OnDays restricts the rule to some days of the week
+func OnDays(days ...time.Weekday) TimeWindowConfiguration {
+	return func(r *TimeWindowRule) error {
+		r.days = map[time.Weekday]bool{}
+		for _, d := range days {
+			r.days[d] = true
+		}
+		return nil
+	}
+}
+
+// ForCategory restricts the rule to the products of a category
+func ForCategory(categoryID uuid.UUID) TimeWindowConfiguration {
+	return func(r *TimeWindowRule) error {
+		r.categoryID = categoryID
+		return nil
+	}
+}
+
+// InLocation evaluates the window in the given time zone instead of the local one
+func InLocation(location *time.Location) TimeWindowConfiguration {
+	return func(r *TimeWindowRule) error {
+		if location == nil {
+			return ErrInvalidRule
+		}
+
+		r.location = location
+		return nil
+	}
+}
+
+func (r *TimeWindowRule) Name() string {
+	return r.name
+}
+
+func (r *TimeWindowRule) Discount(p aggregate.Product, unitPrice float64, at time.Time) (float64, bool) {
+	if r.categoryID != uuid.Nil && p.GetCategoryID() != r.categoryID {
+		return 0, false
+	}
+
+	at = at.In(r.location)
+	midnight := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, r.location)
+	clock := at.Sub(midnight)
+
+	// for windows wrapping midnight, the part after midnight belongs to the previous day
+	day := at.Weekday()
+	var inWindow bool
+	if r.start == r.end {
+		inWindow = true
+	} else if r.start < r.end {
+		inWindow = clock >= r.start && clock < r.end
+	} else {
+		inWindow = clock >= r.start || clock < r.end
+		if clock < r.end {
+			day = at.AddDate(0, 0, -1).Weekday()
+		}
+	}
+
+	if !inWindow {
+		return 0, false
+	}
+	if len(r.days) > 0 && !r.days[day] {
+		return 0, false
+	}
+
+	return unitPrice * r.percent / 100, true
+}
+
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, ErrInvalidRule)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
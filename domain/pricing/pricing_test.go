@@ -0,0 +1,114 @@
+package pricing
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPricing_TimeWindowRule(t *testing.T) {
+	drinks := uuid.New()
+
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 10, aggregate.WithCategory(drinks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakso, err := aggregate.NewProduct("Bakso Kuah", "Bakso Kuah pedah hot jeletot", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	happyHour, err := NewTimeWindowRule("happy hour", "17:00", "19:00", 30,
+		ForCategory(drinks),
+		InLocation(time.UTC),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lateNight, err := NewTimeWindowRule("late night", "22:00", "02:00", 10,
+		OnDays(time.Friday),
+		InLocation(time.UTC),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuesday, err := NewTimeWindowRule("bakso tuesday", "00:00", "00:00", 50,
+		OnDays(time.Tuesday),
+		InLocation(time.UTC),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 2023-06-02 is a Friday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2023, time.June, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	type testCase struct {
+		name     string
+		rule     Rule
+		product  aggregate.Product
+		at       time.Time
+		expected float64
+		applies  bool
+	}
+	tests := []testCase{
+		{name: "happy hour drink", rule: happyHour, product: beer, at: at(2, 17, 0), expected: 3, applies: true},
+		{name: "happy hour ends at 19:00", rule: happyHour, product: beer, at: at(2, 19, 0)},
+		{name: "happy hour food", rule: happyHour, product: bakso, at: at(2, 18, 0)},
+		{name: "late night friday", rule: lateNight, product: bakso, at: at(2, 23, 30), expected: 1, applies: true},
+		{name: "late night after midnight belongs to friday", rule: lateNight, product: bakso, at: at(3, 1, 0), expected: 1, applies: true},
+		{name: "late night thursday", rule: lateNight, product: bakso, at: at(1, 23, 30)},
+		{name: "all day tuesday", rule: tuesday, product: bakso, at: at(6, 9, 0), expected: 5, applies: true},
+		{name: "not tuesday", rule: tuesday, product: bakso, at: at(7, 9, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.rule.Discount(tt.product, tt.product.GetPrice(), tt.at)
+			if ok != tt.applies {
+				t.Fatalf("expected rule to apply %v, got %v", tt.applies, ok)
+			}
+			if got != tt.expected {
+				t.Errorf("expected discount %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPricing_NewTimeWindowRule(t *testing.T) {
+	for _, args := range [][]string{
+		{"", "17:00", "19:00"},
+		{"happy hour", "5pm", "19:00"},
+		{"happy hour", "17:00", "25:00"},
+	} {
+		if _, err := NewTimeWindowRule(args[0], args[1], args[2], 30); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("expected error %v for %v, got %v", ErrInvalidRule, args, err)
+		}
+	}
+
+	if _, err := NewTimeWindowRule("free beer", "17:00", "19:00", 120); !errors.Is(err, ErrInvalidRule) {
+		t.Errorf("expected error %v, got %v", ErrInvalidRule, err)
+	}
+}
+
+func TestPricing_EngineBestDiscount(t *testing.T) {
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	small, _ := NewTimeWindowRule("small", "00:00", "00:00", 10)
+	big, _ := NewTimeWindowRule("big", "00:00", "00:00", 25)
+
+	line := aggregate.OrderLine{UnitPrice: 10}
+	NewEngine(small, big, small).Apply(&line, beer, time.Now())
+
+	if line.Discount != 2.5 || line.Rule != "big" {
+		t.Errorf("expected the biggest discount 2.5 from %q, got %v from %q", "big", line.Discount, line.Rule)
+	}
+}
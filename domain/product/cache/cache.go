@@ -0,0 +1,266 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/product"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Stats are the counters of a cache since it was created
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// Repository is a read-through cache in front of any product.ProductRepository.
+// GetByID is served from a size bounded LRU whose entries expire after a TTL,
+// Update and Delete invalidate the cached product.
+type Repository struct {
+	next product.ProductRepository
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	entries map[uuid.UUID]*list.Element
+	lru     *list.List
+	stats   Stats
+	// loads are the products being read from next, an invalidation during
+	// a load keeps what it read out of the cache
+	loads map[uuid.UUID]*load
+	sync.Mutex
+}
+
+type entry struct {
+	product   aggregate.Product
+	expiresAt time.Time
+}
+
+type load struct {
+	// pending counts the loads of the product in progress
+	pending int
+	// generation changes with every invalidation of the product
+	generation uint64
+}
+
+// New caches up to size products for ttl each, a size <= 0 never evicts
+// and a ttl <= 0 never expires
+func New(next product.ProductRepository, size int, ttl time.Duration) *Repository {
+	return &Repository{
+		next:    next,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[uuid.UUID]*list.Element{},
+		lru:     list.New(),
+		loads:   map[uuid.UUID]*load{},
+	}
+}
+
+// Stats returns a snapshot of the hit/miss counters
+func (r *Repository) Stats() Stats {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.stats
+}
+
+func (r *Repository) GetAll(ctx context.Context) ([]aggregate.Product, error) {
+	return r.next.GetAll(ctx)
+}
+
+func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Product, error) {
+	if p, ok := r.get(id); ok {
+		return p, nil
+	}
+
+	generation := r.startLoad(id)
+	p, err := r.next.GetByID(ctx, id)
+	if err != nil {
+		r.endLoad(id, generation, nil)
+		return aggregate.Product{}, err
+	}
+
+	r.endLoad(id, generation, &p)
+
+	return p, nil
+}
+
+// GetByIDs serves the cached products and fetches the others in a single call
+func (r *Repository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]aggregate.Product, []uuid.UUID, error) {
+	found := map[uuid.UUID]aggregate.Product{}
+	seen := map[uuid.UUID]bool{}
+	var uncached []uuid.UUID
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if p, ok := r.get(id); ok {
+			found[id] = p
+			continue
+		}
+
+		uncached = append(uncached, id)
+	}
+
+	var missing []uuid.UUID
+	if len(uncached) > 0 {
+		generations := make([]uint64, len(uncached))
+		for i, id := range uncached {
+			generations[i] = r.startLoad(id)
+		}
+
+		fetched, m, err := r.next.GetByIDs(ctx, uncached)
+		if err != nil {
+			for i, id := range uncached {
+				r.endLoad(id, generations[i], nil)
+			}
+			return nil, nil, err
+		}
+
+		for _, p := range fetched {
+			found[p.GetID()] = p
+		}
+		for i, id := range uncached {
+			if p, ok := found[id]; ok {
+				r.endLoad(id, generations[i], &p)
+				continue
+			}
+			r.endLoad(id, generations[i], nil)
+		}
+		missing = m
+	}
+
+	var products []aggregate.Product
+	for _, id := range ids {
+		if p, ok := found[id]; ok {
+			products = append(products, p)
+		}
+	}
+
+	return products, missing, nil
+}
+
+func (r *Repository) Search(ctx context.Context, query product.Query) (product.Page, error) {
+	return r.next.Search(ctx, query)
+}
+
+func (r *Repository) Add(ctx context.Context, p aggregate.Product) error {
+	return r.next.Add(ctx, p)
+}
+
+func (r *Repository) Update(ctx context.Context, p aggregate.Product) error {
+	// invalidate even if the update fails, the backend state is unknown
+	defer r.invalidate(p.GetID())
+
+	return r.next.Update(ctx, p)
+}
+
+func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.invalidate(id)
+
+	return r.next.Delete(ctx, id)
+}
+
+func (r *Repository) get(id uuid.UUID) (aggregate.Product, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	el, ok := r.entries[id]
+	if !ok {
+		r.stats.Misses++
+		return aggregate.Product{}, false
+	}
+
+	e := el.Value.(*entry)
+	if r.ttl > 0 && !r.now().Before(e.expiresAt) {
+		r.remove(el)
+		r.stats.Misses++
+		return aggregate.Product{}, false
+	}
+
+	r.lru.MoveToFront(el)
+	r.stats.Hits++
+
+	return e.product, true
+}
+
+// startLoad registers a load of the product from next, returning the
+// generation of the product to give to endLoad
+func (r *Repository) startLoad(id uuid.UUID) uint64 {
+	r.Lock()
+	defer r.Unlock()
+
+	l, ok := r.loads[id]
+	if !ok {
+		l = &load{}
+		r.loads[id] = l
+	}
+	l.pending++
+
+	return l.generation
+}
+
+// endLoad caches the product loaded, if any, unless it was invalidated
+// since the load started
+func (r *Repository) endLoad(id uuid.UUID, generation uint64, p *aggregate.Product) {
+	r.Lock()
+	defer r.Unlock()
+
+	l := r.loads[id]
+	if p != nil && l.generation == generation {
+		r.set(*p)
+	}
+
+	l.pending--
+	if l.pending == 0 {
+		delete(r.loads, id)
+	}
+}
+
+// set must be called with the lock held
+func (r *Repository) set(p aggregate.Product) {
+	e := &entry{
+		product:   p,
+		expiresAt: r.now().Add(r.ttl),
+	}
+
+	if el, ok := r.entries[p.GetID()]; ok {
+		el.Value = e
+		r.lru.MoveToFront(el)
+		return
+	}
+
+	r.entries[p.GetID()] = r.lru.PushFront(e)
+
+	if r.size > 0 && r.lru.Len() > r.size {
+		r.remove(r.lru.Back())
+		r.stats.Evictions++
+	}
+}
+
+func (r *Repository) invalidate(id uuid.UUID) {
+	r.Lock()
+	defer r.Unlock()
+
+	if el, ok := r.entries[id]; ok {
+		r.remove(el)
+	}
+	if l, ok := r.loads[id]; ok {
+		l.generation++
+	}
+}
+
+// remove must be called with the lock held
+func (r *Repository) remove(el *list.Element) {
+	r.lru.Remove(el)
+	delete(r.entries, el.Value.(*entry).product.GetID())
+}
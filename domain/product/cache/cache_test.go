@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/product"
+	"golang-learn-ddd/domain/product/memory"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func init_repository(t *testing.T, size int, ttl time.Duration) (*Repository, []aggregate.Product) {
+	ctx := context.Background()
+	backend := memory.New()
+
+	var products []aggregate.Product
+	for _, name := range []string{"Beer", "Peanut Butter", "Bakso Kuah"} {
+		p, err := aggregate.NewProduct(name, name, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := backend.Add(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+
+		products = append(products, p)
+	}
+
+	return New(backend, size, ttl), products
+}
+
+func TestCache_GetByID(t *testing.T) {
+	ctx := context.Background()
+	repo, products := init_repository(t, 10, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := repo.GetByID(ctx, products[0].GetID()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// not found products are not cached
+	for i := 0; i < 2; i++ {
+		if _, err := repo.GetByID(ctx, uuid.New()); !errors.Is(err, product.ErrProductNotFound) {
+			t.Errorf("expected error %v, got %v", product.ErrProductNotFound, err)
+		}
+	}
+
+	expected := Stats{Hits: 2, Misses: 3}
+	if got := repo.Stats(); got != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, got)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	repo, products := init_repository(t, 10, time.Minute)
+
+	now := time.Now()
+	repo.now = func() time.Time { return now }
+
+	repo.GetByID(ctx, products[0].GetID())
+
+	now = now.Add(59 * time.Second)
+	repo.GetByID(ctx, products[0].GetID())
+
+	now = now.Add(time.Second)
+	repo.GetByID(ctx, products[0].GetID())
+
+	expected := Stats{Hits: 1, Misses: 2}
+	if got := repo.Stats(); got != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, got)
+	}
+}
+
+func TestCache_Eviction(t *testing.T) {
+	ctx := context.Background()
+	repo, products := init_repository(t, 2, 0)
+
+	repo.GetByID(ctx, products[0].GetID())
+	repo.GetByID(ctx, products[1].GetID())
+	// touch products[0] so products[1] is the least recently used
+	repo.GetByID(ctx, products[0].GetID())
+	repo.GetByID(ctx, products[2].GetID())
+
+	if _, ok := repo.entries[products[1].GetID()]; ok {
+		t.Error("expected the least recently used product to be evicted")
+	}
+	if _, ok := repo.entries[products[0].GetID()]; !ok {
+		t.Error("expected the recently used product to stay cached")
+	}
+
+	expected := Stats{Hits: 1, Misses: 3, Evictions: 1}
+	if got := repo.Stats(); got != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, got)
+	}
+}
+
+func TestCache_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	repo, products := init_repository(t, 10, 0)
+
+	repo.GetByID(ctx, products[0].GetID())
+	repo.GetByID(ctx, products[1].GetID())
+
+	if err := repo.Update(ctx, products[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Delete(ctx, products[1].GetID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.entries) != 0 {
+		t.Errorf("expected updated and deleted products to be invalidated, %d still cached", len(repo.entries))
+	}
+
+	if _, err := repo.GetByID(ctx, products[1].GetID()); !errors.Is(err, product.ErrProductNotFound) {
+		t.Errorf("expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+}
+
+// slowRepository holds the reads of products until released
+type slowRepository struct {
+	product.ProductRepository
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (r *slowRepository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Product, error) {
+	p, err := r.ProductRepository.GetByID(ctx, id)
+	r.reading <- struct{}{}
+	<-r.release
+
+	return p, err
+}
+
+func TestCache_InvalidatedDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.New()
+
+	p, err := aggregate.NewProduct("Beer", "Beer", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.Add(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	slow := &slowRepository{ProductRepository: backend, reading: make(chan struct{}), release: make(chan struct{})}
+	repo := New(slow, 10, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		repo.GetByID(ctx, p.GetID())
+	}()
+
+	// the product changes once the load has read it
+	<-slow.reading
+	p.SetQuantity(42)
+	if err := repo.Update(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	close(slow.release)
+	<-done
+
+	if len(repo.entries) != 0 || len(repo.loads) != 0 {
+		t.Fatalf("expected the stale product not to be cached, got %d entries and %d loads", len(repo.entries), len(repo.loads))
+	}
+
+	go func() { <-slow.reading }()
+	got, err := repo.GetByID(ctx, p.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetQuantity() != 42 {
+		t.Errorf("expected the updated quantity 42, got %d", got.GetQuantity())
+	}
+}
+
+func TestCache_GetByIDs(t *testing.T) {
+	ctx := context.Background()
+	repo, products := init_repository(t, 10, 0)
+
+	repo.GetByID(ctx, products[0].GetID())
+
+	unknown := uuid.New()
+	ids := []uuid.UUID{products[1].GetID(), products[0].GetID(), unknown, products[1].GetID()}
+
+	found, missing, err := repo.GetByIDs(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(found) != 3 || found[0].GetID() != products[1].GetID() || found[1].GetID() != products[0].GetID() {
+		t.Errorf("expected products in the requested order, got %v", found)
+	}
+	if len(missing) != 1 || missing[0] != unknown {
+		t.Errorf("expected missing %v, got %v", unknown, missing)
+	}
+
+	// products[1] was fetched and is now cached
+	expected := Stats{Hits: 1, Misses: 3}
+	if got := repo.Stats(); got != expected {
+		t.Errorf("expected stats %+v, got %+v", expected, got)
+	}
+	if _, ok := repo.entries[products[1].GetID()]; !ok {
+		t.Error("expected fetched products to be cached")
+	}
+}
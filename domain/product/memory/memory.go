@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"fmt"
 	"golang-learn-ddd/aggregate"
 	"golang-learn-ddd/domain/product"
@@ -20,17 +21,46 @@ func New() product.ProductRepository {
 	}
 }
 
-func (r *memoryRepository) GetAll() ([]aggregate.Product, error) {
+func (r *memoryRepository) GetAll(ctx context.Context) ([]aggregate.Product, error) {
+	r.Lock()
 	var products []aggregate.Product
-
 	for _, product := range r.products {
 		products = append(products, product)
 	}
+	r.Unlock()
+
+	// map iteration order is random, always return the products sorted
+	product.Query{}.Sort(products)
 
 	return products, nil
 }
 
-func (r *memoryRepository) GetByID(id uuid.UUID) (aggregate.Product, error) {
+func (r *memoryRepository) Search(ctx context.Context, query product.Query) (product.Page, error) {
+	if err := query.Validate(); err != nil {
+		return product.Page{}, err
+	}
+
+	r.Lock()
+	var products []aggregate.Product
+	for _, p := range r.products {
+		if query.Matches(p) {
+			products = append(products, p)
+		}
+	}
+	r.Unlock()
+
+	query.Sort(products)
+
+	return product.Page{
+		Products: query.Paginate(products),
+		Total:    len(products),
+	}, nil
+}
+
+func (r *memoryRepository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Product, error) {
+	r.Lock()
+	defer r.Unlock()
+
 	if product, ok := r.products[id]; ok {
 		return product, nil
 	}
@@ -38,7 +68,26 @@ func (r *memoryRepository) GetByID(id uuid.UUID) (aggregate.Product, error) {
 	return aggregate.Product{}, product.ErrProductNotFound
 }
 
-func (r *memoryRepository) Add(p aggregate.Product) error {
+func (r *memoryRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]aggregate.Product, []uuid.UUID, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	var products []aggregate.Product
+	var missing []uuid.UUID
+
+	for _, id := range ids {
+		if product, ok := r.products[id]; ok {
+			products = append(products, product)
+			continue
+		}
+
+		missing = appendMissing(missing, id)
+	}
+
+	return products, missing, nil
+}
+
+func (r *memoryRepository) Add(ctx context.Context, p aggregate.Product) error {
 	r.Lock()
 	defer r.Unlock()
 
@@ -51,7 +100,7 @@ func (r *memoryRepository) Add(p aggregate.Product) error {
 	return nil
 }
 
-func (r *memoryRepository) Update(p aggregate.Product) error {
+func (r *memoryRepository) Update(ctx context.Context, p aggregate.Product) error {
 	r.Lock()
 	defer r.Unlock()
 
@@ -64,7 +113,7 @@ func (r *memoryRepository) Update(p aggregate.Product) error {
 	return nil
 }
 
-func (r *memoryRepository) Delete(id uuid.UUID) error {
+func (r *memoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	r.Lock()
 	defer r.Unlock()
 
@@ -76,3 +125,14 @@ func (r *memoryRepository) Delete(id uuid.UUID) error {
 
 	return nil
 }
+
+// appendMissing appends id once, no matter how many times it was requested
+func appendMissing(missing []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	for _, m := range missing {
+		if m == id {
+			return missing
+		}
+	}
+
+	return append(missing, id)
+}
@@ -0,0 +1,291 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/product"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_memoryRepository_GetByIDs(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 99.92)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakso, err := aggregate.NewProduct("Bakso Kuah", "Bakso Kuah pedah hot jeletot", 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.Add(ctx, beer)
+	repo.Add(ctx, bakso)
+
+	unknown := uuid.New()
+
+	type testCase struct {
+		name            string
+		ids             []uuid.UUID
+		expectedFound   []uuid.UUID
+		expectedMissing []uuid.UUID
+	}
+	tests := []testCase{
+		{
+			name:          "all products found, in order",
+			ids:           []uuid.UUID{bakso.GetID(), beer.GetID()},
+			expectedFound: []uuid.UUID{bakso.GetID(), beer.GetID()},
+		},
+		{
+			name:          "same product ordered twice",
+			ids:           []uuid.UUID{beer.GetID(), beer.GetID()},
+			expectedFound: []uuid.UUID{beer.GetID(), beer.GetID()},
+		},
+		{
+			name:            "missing products are reported once",
+			ids:             []uuid.UUID{unknown, beer.GetID(), unknown},
+			expectedFound:   []uuid.UUID{beer.GetID()},
+			expectedMissing: []uuid.UUID{unknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products, missing, err := repo.GetByIDs(ctx, tt.ids)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var found []uuid.UUID
+			for _, p := range products {
+				found = append(found, p.GetID())
+			}
+
+			if !reflect.DeepEqual(found, tt.expectedFound) {
+				t.Errorf("expected products %v, got %v", tt.expectedFound, found)
+			}
+			if !reflect.DeepEqual(missing, tt.expectedMissing) {
+				t.Errorf("expected missing %v, got %v", tt.expectedMissing, missing)
+			}
+		})
+	}
+}
+
+func Test_memoryRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	for _, p := range []struct {
+		name, description string
+		price             float64
+		quantity          int
+	}{
+		{"Beer", "Halal Beer", 99.92, 10},
+		{"Peanut Butter", "Peanut Nut Day", 12.5, 0},
+		{"Bakso Kuah", "Bakso Kuah pedah hot jeletot", 1.5, 3},
+		{"Root Beer", "Sweet and fizzy", 12.5, 5},
+	} {
+		prod, err := aggregate.NewProduct(p.name, p.description, p.price)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prod.SetQuantity(p.quantity)
+
+		repo.Add(ctx, prod)
+	}
+
+	type testCase struct {
+		name          string
+		query         product.Query
+		expectedNames []string
+		expectedTotal int
+		expectedErr   error
+	}
+	tests := []testCase{
+		{
+			name:          "zero query sorts by name",
+			query:         product.Query{},
+			expectedNames: []string{"Bakso Kuah", "Beer", "Peanut Butter", "Root Beer"},
+			expectedTotal: 4,
+		},
+		{
+			name:          "text matches name and description case-insensitively",
+			query:         product.Query{Text: "BEER"},
+			expectedNames: []string{"Beer", "Root Beer"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "price range",
+			query:         product.Query{MinPrice: 10, MaxPrice: 50},
+			expectedNames: []string{"Peanut Butter", "Root Beer"},
+			expectedTotal: 2,
+		},
+		{
+			name:          "in stock only sorted by price descending",
+			query:         product.Query{InStock: true, SortBy: product.SortByPrice, Descending: true},
+			expectedNames: []string{"Beer", "Root Beer", "Bakso Kuah"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "paginated",
+			query:         product.Query{Offset: 1, Limit: 2},
+			expectedNames: []string{"Beer", "Peanut Butter"},
+			expectedTotal: 4,
+		},
+		{
+			name:          "offset past the end",
+			query:         product.Query{Offset: 10},
+			expectedNames: nil,
+			expectedTotal: 4,
+		},
+		{
+			name:        "invalid price range",
+			query:       product.Query{MinPrice: 10, MaxPrice: 5},
+			expectedErr: product.ErrInvalidQuery,
+		},
+		{
+			name:        "unknown sort field",
+			query:       product.Query{SortBy: "stock"},
+			expectedErr: product.ErrInvalidQuery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.Search(ctx, tt.query)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+
+			var got []string
+			for _, p := range page.Products {
+				got = append(got, p.GetItem().Name)
+			}
+
+			if !reflect.DeepEqual(got, tt.expectedNames) {
+				t.Errorf("expected products %v, got %v", tt.expectedNames, got)
+			}
+			if page.Total != tt.expectedTotal {
+				t.Errorf("expected total %d, got %d", tt.expectedTotal, page.Total)
+			}
+		})
+	}
+
+	// products sharing a price are ordered by id, on every call
+	query := product.Query{SortBy: product.SortByPrice, MinPrice: 12.5, MaxPrice: 12.5}
+	first, _ := repo.Search(ctx, query)
+	for i := 0; i < 10; i++ {
+		again, _ := repo.Search(ctx, query)
+		if !reflect.DeepEqual(first, again) {
+			t.Fatal("expected a deterministic order for equal prices")
+		}
+	}
+}
+
+func Test_memoryRepository_SearchVariants(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	// stock and prices are held by the variants only
+	coffee, err := aggregate.NewProduct("Coffee", "Freshly brewed", 0,
+		aggregate.WithVariant("Small", 3, 0),
+		aggregate.WithVariant("Large", 5, 2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tea, err := aggregate.NewProduct("Tea", "Jasmine", 0, aggregate.WithVariant("Cup", 2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Add(ctx, coffee)
+	repo.Add(ctx, tea)
+
+	type testCase struct {
+		name          string
+		query         product.Query
+		expectedNames []string
+	}
+	tests := []testCase{
+		{name: "a variant in stock", query: product.Query{InStock: true}, expectedNames: []string{"Coffee"}},
+		{name: "a variant priced in range", query: product.Query{MinPrice: 4, MaxPrice: 6}, expectedNames: []string{"Coffee"}},
+		{name: "the cheapest variant in range", query: product.Query{MaxPrice: 2.5}, expectedNames: []string{"Tea"}},
+		{name: "no variant in range", query: product.Query{MinPrice: 10}, expectedNames: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, p := range page.Products {
+				got = append(got, p.GetItem().Name)
+			}
+			if !reflect.DeepEqual(got, tt.expectedNames) {
+				t.Errorf("expected products %v, got %v", tt.expectedNames, got)
+			}
+		})
+	}
+}
+
+func Test_memoryRepository_SearchWhileUpdating(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 99.92)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, beer); err != nil {
+		t.Fatal(err)
+	}
+
+	// run with -race, readers and writers share the map
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			p, err := repo.GetByID(ctx, beer.GetID())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			p.SetQuantity(i)
+			if err := repo.Update(ctx, p); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			if _, err := repo.Search(ctx, product.Query{Text: "beer"}); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := repo.GetAll(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, _, err := repo.GetByIDs(ctx, []uuid.UUID{beer.GetID()}); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
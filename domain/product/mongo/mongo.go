@@ -0,0 +1,430 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/product"
+	"golang-learn-ddd/entity"
+	"golang-learn-ddd/valueobject"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	db      *mongo.Database
+	product *mongo.Collection
+}
+
+// mongoProduct internal type to store ProductAggregate to mongodb
+type mongoProduct struct {
+	ID          uuid.UUID `bson:"id"`
+	Name        string    `bson:"name"`
+	Description string    `bson:"description"`
+	// Price is the price effective when the product was saved, for reading the
+	// collection only: a scheduled change makes it stale, so searches compute
+	// the effective price from BasePrice and PriceHistory instead.
+	Price        float64            `bson:"price"`
+	BasePrice    float64            `bson:"base_price"`
+	PriceHistory []mongoPriceChange `bson:"price_history"`
+	Quantity     int                `bson:"quantity"`
+	MinimumAge   int                `bson:"minimum_age"`
+	CategoryID   uuid.UUID          `bson:"category_id"`
+	Tags         []string           `bson:"tags"`
+
+	Variants  []mongoVariant  `bson:"variants"`
+	Modifiers []mongoModifier `bson:"modifiers"`
+}
+
+type mongoPriceChange struct {
+	Old         float64   `bson:"old"`
+	New         float64   `bson:"new"`
+	EffectiveAt time.Time `bson:"effective_at"`
+}
+
+type mongoVariant struct {
+	ID       uuid.UUID `bson:"id"`
+	Name     string    `bson:"name"`
+	Price    float64   `bson:"price"`
+	Quantity int       `bson:"quantity"`
+}
+
+type mongoModifier struct {
+	ID         uuid.UUID `bson:"id"`
+	Name       string    `bson:"name"`
+	PriceDelta float64   `bson:"price_delta"`
+}
+
+func NewFromProduct(p aggregate.Product) mongoProduct {
+	variants := make([]mongoVariant, 0, len(p.GetVariants()))
+	for _, v := range p.GetVariants() {
+		variants = append(variants, mongoVariant(v))
+	}
+
+	history := make([]mongoPriceChange, 0, len(p.GetPriceHistory()))
+	for _, change := range p.GetPriceHistory() {
+		history = append(history, mongoPriceChange{
+			Old:         change.Old(),
+			New:         change.New(),
+			EffectiveAt: change.EffectiveAt(),
+		})
+	}
+
+	modifiers := make([]mongoModifier, 0, len(p.GetModifiers()))
+	for _, m := range p.GetModifiers() {
+		modifiers = append(modifiers, mongoModifier(m))
+	}
+
+	return mongoProduct{
+		ID:           p.GetID(),
+		Name:         p.GetItem().Name,
+		Description:  p.GetItem().Description,
+		Price:        p.GetPrice(),
+		BasePrice:    p.GetBasePrice(),
+		PriceHistory: history,
+		Quantity:     p.GetQuantity(),
+		MinimumAge:   p.GetMinimumAge(),
+		CategoryID:   p.GetCategoryID(),
+		Tags:         p.GetTags(),
+		Variants:     variants,
+		Modifiers:    modifiers,
+	}
+}
+
+func (m *mongoProduct) ToAggregate() (aggregate.Product, error) {
+	p, err := aggregate.NewProduct(m.Name, m.Description, m.BasePrice,
+		aggregate.WithMinimumAge(m.MinimumAge),
+		aggregate.WithCategory(m.CategoryID),
+		aggregate.WithTags(m.Tags...),
+	)
+	if err != nil {
+		return aggregate.Product{}, err
+	}
+
+	p.SetID(m.ID)
+	p.SetQuantity(m.Quantity)
+
+	history := make([]valueobject.PriceChange, 0, len(m.PriceHistory))
+	for _, change := range m.PriceHistory {
+		history = append(history, valueobject.NewPriceChange(change.Old, change.New, change.EffectiveAt))
+	}
+	p.SetPriceHistory(history)
+
+	var variants []entity.Variant
+	for _, v := range m.Variants {
+		variants = append(variants, entity.Variant(v))
+	}
+	p.SetVariants(variants)
+
+	var modifiers []entity.Modifier
+	for _, mod := range m.Modifiers {
+		modifiers = append(modifiers, entity.Modifier(mod))
+	}
+	p.SetModifiers(modifiers)
+
+	return p, nil
+}
+
+func New(ctx context.Context, connectionString string) (product.ProductRepository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("learn-golang-ddd")
+	collection := db.Collection("products")
+
+	return &mongoRepository{
+		db:      db,
+		product: collection,
+	}, nil
+}
+
+func (r *mongoRepository) GetAll(ctx context.Context) ([]aggregate.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "id", Value: 1}}))
+}
+
+func (r *mongoRepository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var row mongoProduct
+	err := r.product.FindOne(ctx, bson.M{"id": id}).Decode(&row)
+	if err != nil {
+		return aggregate.Product{}, fmt.Errorf("product does not exists: %w; %w", err, product.ErrProductNotFound)
+	}
+
+	return row.ToAggregate()
+}
+
+func (r *mongoRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]aggregate.Product, []uuid.UUID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.find(ctx, bson.M{"id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make(map[uuid.UUID]aggregate.Product, len(rows))
+	for _, p := range rows {
+		found[p.GetID()] = p
+	}
+
+	// keep the order of ids and report each missing id once
+	var products []aggregate.Product
+	var missing []uuid.UUID
+	reported := map[uuid.UUID]bool{}
+
+	for _, id := range ids {
+		if p, ok := found[id]; ok {
+			products = append(products, p)
+			continue
+		}
+
+		if !reported[id] {
+			reported[id] = true
+			missing = append(missing, id)
+		}
+	}
+
+	return products, missing, nil
+}
+
+func (r *mongoRepository) Search(ctx context.Context, query product.Query) (product.Page, error) {
+	if err := query.Validate(); err != nil {
+		return product.Page{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// products with variants are priced and stocked by their variants
+	noVariants := bson.M{"variants.0": bson.M{"$exists": false}}
+
+	filter := bson.M{}
+	var and bson.A
+	if query.Text != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Text), Options: "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": pattern},
+			bson.M{"description": pattern},
+		}
+	}
+
+	price := bson.M{}
+	if query.MinPrice > 0 {
+		price["$gte"] = query.MinPrice
+	}
+	if query.MaxPrice > 0 {
+		price["$lte"] = query.MaxPrice
+	}
+	if len(price) > 0 {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"$and": bson.A{noVariants, bson.M{"effective_price": price}}},
+			bson.M{"variants": bson.M{"$elemMatch": bson.M{"price": price}}},
+		}})
+	}
+
+	if query.InStock {
+		and = append(and, bson.M{"$or": bson.A{
+			bson.M{"$and": bson.A{noVariants, bson.M{"quantity": bson.M{"$gt": 0}}}},
+			bson.M{"variants": bson.M{"$elemMatch": bson.M{"quantity": bson.M{"$gt": 0}}}},
+		}})
+	}
+
+	if len(and) > 0 {
+		filter["$and"] = and
+	}
+
+	if query.CategoryID != uuid.Nil {
+		filter["category_id"] = query.CategoryID
+	}
+
+	if len(query.Tags) > 0 {
+		tags := make([]string, 0, len(query.Tags))
+		for _, tag := range query.Tags {
+			tags = append(tags, strings.ToLower(strings.TrimSpace(tag)))
+		}
+		filter["tags"] = bson.M{"$all": tags}
+	}
+
+	field, direction := "name", 1
+	if query.SortBy == product.SortByPrice {
+		field = "effective_price"
+	}
+	if query.Descending {
+		direction = -1
+	}
+
+	// break ties on the id so pages stay stable between calls
+	page := bson.A{
+		bson.M{"$sort": bson.D{{Key: field, Value: direction}, {Key: "id", Value: 1}}},
+		bson.M{"$skip": query.Offset},
+	}
+	if query.Limit > 0 {
+		page = append(page, bson.M{"$limit": query.Limit})
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$addFields", Value: bson.M{"effective_price": effectivePrice(time.Now())}}},
+		{{Key: "$match", Value: filter}},
+		{{Key: "$facet", Value: bson.M{
+			"total":    bson.A{bson.M{"$count": "count"}},
+			"products": page,
+		}}},
+	}
+
+	cursor, err := r.product.Aggregate(ctx, pipeline)
+	if err != nil {
+		return product.Page{}, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+		Products []mongoProduct `bson:"products"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return product.Page{}, err
+	}
+
+	var total int
+	var products []aggregate.Product
+	if len(results) > 0 {
+		if len(results[0].Total) > 0 {
+			total = results[0].Total[0].Count
+		}
+		for _, row := range results[0].Products {
+			p, err := row.ToAggregate()
+			if err != nil {
+				return product.Page{}, err
+			}
+			products = append(products, p)
+		}
+	}
+
+	if products == nil {
+		products = []aggregate.Product{}
+	}
+
+	return product.Page{
+		Products: products,
+		Total:    total,
+	}, nil
+}
+
+// effectivePrice computes the price of the products at now, as PriceAt does:
+// the last change of the history effective by then, else the base price.
+// The history is kept sorted by effective time.
+func effectivePrice(now time.Time) bson.M {
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"changes": bson.M{"$filter": bson.M{
+			"input": bson.M{"$ifNull": bson.A{"$price_history", bson.A{}}},
+			"as":    "change",
+			"cond":  bson.M{"$lte": bson.A{"$$change.effective_at", now}},
+		}}},
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$size": "$$changes"}, 0}},
+			"$base_price",
+			bson.M{"$arrayElemAt": bson.A{"$$changes.new", -1}},
+		}},
+	}}
+}
+
+func (r *mongoRepository) Add(ctx context.Context, p aggregate.Product) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.product.InsertOne(ctx, NewFromProduct(p))
+	if err != nil {
+		return fmt.Errorf("failed to add a product: %w; %w", err, product.ErrFailedToAddProduct)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Update(ctx context.Context, p aggregate.Product) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := NewFromProduct(p)
+	filter := bson.M{"id": row.ID}
+	updateData := bson.M{
+		"$set": bson.M{
+			"name":          row.Name,
+			"description":   row.Description,
+			"price":         row.Price,
+			"base_price":    row.BasePrice,
+			"price_history": row.PriceHistory,
+			"quantity":      row.Quantity,
+			"minimum_age":   row.MinimumAge,
+			"category_id":   row.CategoryID,
+			"tags":          row.Tags,
+			"variants":      row.Variants,
+			"modifiers":     row.Modifiers,
+		},
+	}
+
+	res, err := r.product.UpdateOne(ctx, filter, updateData)
+	if err != nil {
+		return fmt.Errorf("failed to update a product: %w; %w", err, product.ErrUpdateProduct)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("product is not exists :%w", product.ErrUpdateProduct)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.product.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete a product: %w; %w", err, product.ErrDeleteProduct)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("product is not exists :%w", product.ErrDeleteProduct)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]aggregate.Product, error) {
+	cursor, err := r.product.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var products []aggregate.Product
+	for cursor.Next(ctx) {
+		var row mongoProduct
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+
+		p, err := row.ToAggregate()
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, p)
+	}
+
+	return products, cursor.Err()
+}
@@ -0,0 +1,182 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidQuery = errors.New("invalid product query")
+)
+
+type SortField string
+
+const (
+	SortByName  SortField = "name"
+	SortByPrice SortField = "price"
+)
+
+// Query filters, sorts and paginates products. The zero value matches every
+// product sorted by name. Ties are always broken by ID so pages are stable.
+type Query struct {
+	// Text is matched case-insensitively against the name and the description
+	Text string
+	// MinPrice and MaxPrice bound the price, a MaxPrice of 0 is unbounded.
+	// A product with variants matches when any variant is priced in bounds.
+	MinPrice float64
+	MaxPrice float64
+	// InStock only matches products with a positive quantity, or with a
+	// variant of positive quantity for products with variants
+	InStock bool
+	// CategoryID only matches products of the category, unless uuid.Nil
+	CategoryID uuid.UUID
+	// Tags only matches products having every tag
+	Tags []string
+
+	SortBy     SortField
+	Descending bool
+
+	// Offset skips the first products, a Limit of 0 returns all of them
+	Offset int
+	Limit  int
+}
+
+// Page is one page of the products matching a Query
+type Page struct {
+	Products []aggregate.Product
+	// Total is the number of products matching the query, across all pages
+	Total int
+}
+
+func (q Query) Validate() error {
+	switch {
+	case q.MinPrice < 0 || q.MaxPrice < 0:
+		return fmt.Errorf("price bounds must not be negative: %w", ErrInvalidQuery)
+	case q.MaxPrice > 0 && q.MinPrice > q.MaxPrice:
+		return fmt.Errorf("min price is greater than max price: %w", ErrInvalidQuery)
+	case q.Offset < 0 || q.Limit < 0:
+		return fmt.Errorf("offset and limit must not be negative: %w", ErrInvalidQuery)
+	case q.SortBy != "" && q.SortBy != SortByName && q.SortBy != SortByPrice:
+		return fmt.Errorf("unknown sort field %q: %w", q.SortBy, ErrInvalidQuery)
+	}
+
+	return nil
+}
+
+// Matches reports whether p satisfies the filters of the query
+func (q Query) Matches(p aggregate.Product) bool {
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		item := p.GetItem()
+		if !strings.Contains(strings.ToLower(item.Name), text) &&
+			!strings.Contains(strings.ToLower(item.Description), text) {
+			return false
+		}
+	}
+
+	if !q.matchesPrice(p) {
+		return false
+	}
+	if q.InStock && !inStock(p) {
+		return false
+	}
+	if q.CategoryID != uuid.Nil && p.GetCategoryID() != q.CategoryID {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !p.HasTag(tag) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchesPrice reports whether the price of p, or the price of any of its
+// variants, is within the bounds of the query
+func (q Query) matchesPrice(p aggregate.Product) bool {
+	inBounds := func(price float64) bool {
+		return price >= q.MinPrice && (q.MaxPrice == 0 || price <= q.MaxPrice)
+	}
+
+	variants := p.GetVariants()
+	if len(variants) == 0 {
+		return inBounds(p.GetPrice())
+	}
+	for _, v := range variants {
+		if inBounds(v.Price) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inStock reports whether p, or any of its variants, has a positive quantity
+func inStock(p aggregate.Product) bool {
+	variants := p.GetVariants()
+	if len(variants) == 0 {
+		return p.GetQuantity() > 0
+	}
+	for _, v := range variants {
+		if v.Quantity > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sort orders products as requested by the query
+func (q Query) Sort(products []aggregate.Product) {
+	sort.SliceStable(products, func(i, j int) bool {
+		a, b := products[i], products[j]
+
+		var cmp int
+		switch q.SortBy {
+		case SortByPrice:
+			cmp = compareFloat(a.GetPrice(), b.GetPrice())
+		default:
+			cmp = strings.Compare(a.GetItem().Name, b.GetItem().Name)
+		}
+
+		if q.Descending {
+			cmp = -cmp
+		}
+		if cmp == 0 {
+			return a.GetID().String() < b.GetID().String()
+		}
+
+		return cmp < 0
+	})
+}
+
+// Paginate applies the offset and limit of the query to sorted products
+func (q Query) Paginate(products []aggregate.Product) []aggregate.Product {
+	if q.Offset >= len(products) {
+		return []aggregate.Product{}
+	}
+
+	products = products[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(products) {
+		products = products[:q.Limit]
+	}
+
+	return products
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -1,7 +1,9 @@
 package product
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"golang-learn-ddd/aggregate"
 
 	"github.com/google/uuid"
@@ -14,10 +16,27 @@ var (
 	ErrDeleteProduct      = errors.New("failed to delete the product")
 )
 
+// MissingProductsError lists every product of a lookup that could not be found
+type MissingProductsError struct {
+	IDs []uuid.UUID
+}
+
+func (e *MissingProductsError) Error() string {
+	return fmt.Sprintf("%d product(s) not found %v", len(e.IDs), e.IDs)
+}
+
+func (e *MissingProductsError) Unwrap() error {
+	return ErrProductNotFound
+}
+
 type ProductRepository interface {
-	GetAll() ([]aggregate.Product, error)
-	GetByID(id uuid.UUID) (aggregate.Product, error)
-	Add(product aggregate.Product) error
-	Update(product aggregate.Product) error
-	Delete(id uuid.UUID) error
+	GetAll(ctx context.Context) ([]aggregate.Product, error)
+	GetByID(ctx context.Context, id uuid.UUID) (aggregate.Product, error)
+	// GetByIDs returns the products found, in the order of ids, and the ids
+	// that were not found. Missing products are not an error.
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]aggregate.Product, []uuid.UUID, error)
+	Search(ctx context.Context, query Query) (Page, error)
+	Add(ctx context.Context, product aggregate.Product) error
+	Update(ctx context.Context, product aggregate.Product) error
+	Delete(ctx context.Context, id uuid.UUID) error
 }
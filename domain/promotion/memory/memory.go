@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/promotion"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type memoryRepository struct {
+	promotions map[uuid.UUID]aggregate.Promotion
+	sync.Mutex
+}
+
+func New() promotion.PromotionRepository {
+	return &memoryRepository{
+		promotions: map[uuid.UUID]aggregate.Promotion{},
+	}
+}
+
+func (r *memoryRepository) GetByCode(ctx context.Context, code string) (aggregate.Promotion, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	code = aggregate.NormalizeCode(code)
+	for _, p := range r.promotions {
+		if p.GetCode() == code {
+			return copyPromotion(p), nil
+		}
+	}
+
+	return aggregate.Promotion{}, promotion.ErrPromotionNotFound
+}
+
+func (r *memoryRepository) Add(ctx context.Context, p aggregate.Promotion) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.promotions[p.GetID()]; ok {
+		return fmt.Errorf("promotion already exists :%w", promotion.ErrFailedToAddPromotion)
+	}
+	for _, existing := range r.promotions {
+		if existing.GetCode() == p.GetCode() {
+			return fmt.Errorf("promotion code already exists :%w", promotion.ErrFailedToAddPromotion)
+		}
+	}
+
+	r.promotions[p.GetID()] = copyPromotion(p)
+
+	return nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, p aggregate.Promotion) error {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.promotions[p.GetID()]
+	if !ok {
+		return fmt.Errorf("promotion is not exists :%w", promotion.ErrUpdatePromotion)
+	}
+	if stored.GetVersion() != p.GetVersion() {
+		return fmt.Errorf("promotion is at version %d, not %d :%w", stored.GetVersion(), p.GetVersion(), promotion.ErrConcurrentUpdate)
+	}
+
+	p = copyPromotion(p)
+	p.SetVersion(p.GetVersion() + 1)
+	r.promotions[p.GetID()] = p
+
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.promotions[id]; !ok {
+		return fmt.Errorf("promotion is not exists :%w", promotion.ErrDeletePromotion)
+	}
+
+	delete(r.promotions, id)
+
+	return nil
+}
+
+// copyPromotion keeps the usage map of the stored promotion from being
+// changed by callers until they Update it
+func copyPromotion(p aggregate.Promotion) aggregate.Promotion {
+	p.SetUsage(p.GetUsage())
+	return p
+}
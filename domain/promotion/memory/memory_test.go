@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/promotion"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_memoryRepository_GetByCode(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	summer, err := aggregate.NewPercentageOff("SUMMER10", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, summer); err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name        string
+		code        string
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name: "exact code",
+			code: "SUMMER10",
+		},
+		{
+			name: "code is case insensitive",
+			code: " summer10",
+		},
+		{
+			name:        "unknown code",
+			code:        "WINTER10",
+			expectedErr: promotion.ErrPromotionNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := repo.GetByCode(ctx, tc.code)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if err == nil && p.GetID() != summer.GetID() {
+				t.Errorf("expected promotion %v, got %v", summer.GetID(), p.GetID())
+			}
+		})
+	}
+}
+
+func Test_memoryRepository_Add(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	summer, err := aggregate.NewPercentageOff("SUMMER10", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, summer); err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate, err := aggregate.NewFixedAmountOff("summer10", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, duplicate); !errors.Is(err, promotion.ErrFailedToAddPromotion) {
+		t.Errorf("expected error %v, got %v", promotion.ErrFailedToAddPromotion, err)
+	}
+}
+
+func Test_memoryRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	p, err := aggregate.NewPercentageOff("ONCE", 10, aggregate.WithUsageLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+
+	customerID := uuid.New()
+	stored, err := repo.GetByCode(ctx, "ONCE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stored.Redeem(customerID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the usage is only saved on Update
+	unchanged, err := repo.GetByCode(ctx, "ONCE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.GetUsage()[customerID] != 0 {
+		t.Errorf("expected the stored usage to be untouched, got %v", unchanged.GetUsage()[customerID])
+	}
+
+	if err := repo.Update(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := repo.GetByCode(ctx, "ONCE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetUsage()[customerID] != 1 {
+		t.Errorf("expected usage %v, got %v", 1, updated.GetUsage()[customerID])
+	}
+
+	// a copy read before the update is stale
+	if err := repo.Update(ctx, stored); !errors.Is(err, promotion.ErrConcurrentUpdate) {
+		t.Errorf("expected error %v, got %v", promotion.ErrConcurrentUpdate, err)
+	}
+}
@@ -0,0 +1,185 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/promotion"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	db        *mongo.Database
+	promotion *mongo.Collection
+}
+
+// mongoPromotion internal type to store PromotionAggregate to mongodb
+type mongoPromotion struct {
+	ID         uuid.UUID    `bson:"id"`
+	Code       string       `bson:"code"`
+	Kind       string       `bson:"kind"`
+	Value      float64      `bson:"value"`
+	ProductID  uuid.UUID    `bson:"product_id"`
+	Buy        int          `bson:"buy"`
+	Free       int          `bson:"free"`
+	MinSpend   float64      `bson:"min_spend"`
+	ExpiresAt  time.Time    `bson:"expires_at"`
+	UsageLimit int          `bson:"usage_limit"`
+	Usage      []mongoUsage `bson:"usage"`
+	Version    int          `bson:"version"`
+}
+
+type mongoUsage struct {
+	CustomerID uuid.UUID `bson:"customer_id"`
+	Count      int       `bson:"count"`
+}
+
+func NewFromPromotion(p aggregate.Promotion) mongoPromotion {
+	productID, buy, free := p.GetBuyXGetY()
+
+	usage := make([]mongoUsage, 0, len(p.GetUsage()))
+	for customerID, count := range p.GetUsage() {
+		usage = append(usage, mongoUsage{CustomerID: customerID, Count: count})
+	}
+
+	return mongoPromotion{
+		ID:         p.GetID(),
+		Code:       p.GetCode(),
+		Kind:       string(p.GetKind()),
+		Value:      p.GetValue(),
+		ProductID:  productID,
+		Buy:        buy,
+		Free:       free,
+		MinSpend:   p.GetMinSpend(),
+		ExpiresAt:  p.GetExpiresAt(),
+		UsageLimit: p.GetUsageLimit(),
+		Usage:      usage,
+		Version:    p.GetVersion(),
+	}
+}
+
+func (m *mongoPromotion) ToAggregate() (aggregate.Promotion, error) {
+	cfgs := []aggregate.PromotionConfiguration{
+		aggregate.WithMinSpend(m.MinSpend),
+		aggregate.WithExpiry(m.ExpiresAt),
+		aggregate.WithUsageLimit(m.UsageLimit),
+	}
+
+	var p aggregate.Promotion
+	var err error
+
+	switch aggregate.PromotionKind(m.Kind) {
+	case aggregate.PercentageOff:
+		p, err = aggregate.NewPercentageOff(m.Code, m.Value, cfgs...)
+	case aggregate.FixedAmountOff:
+		p, err = aggregate.NewFixedAmountOff(m.Code, m.Value, cfgs...)
+	case aggregate.BuyXGetY:
+		p, err = aggregate.NewBuyXGetY(m.Code, m.ProductID, m.Buy, m.Free, cfgs...)
+	default:
+		err = fmt.Errorf("unknown promotion kind %q: %w", m.Kind, aggregate.ErrInvalidPromotion)
+	}
+	if err != nil {
+		return aggregate.Promotion{}, err
+	}
+
+	usage := make(map[uuid.UUID]int, len(m.Usage))
+	for _, u := range m.Usage {
+		usage[u.CustomerID] = u.Count
+	}
+
+	p.SetID(m.ID)
+	p.SetUsage(usage)
+	p.SetVersion(m.Version)
+
+	return p, nil
+}
+
+func New(ctx context.Context, connectionString string) (promotion.PromotionRepository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("learn-golang-ddd")
+	collection := db.Collection("promotions")
+
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().SetName("code").SetUnique(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the promotion code index: %w", err)
+	}
+
+	return &mongoRepository{
+		db:        db,
+		promotion: collection,
+	}, nil
+}
+
+func (r *mongoRepository) GetByCode(ctx context.Context, code string) (aggregate.Promotion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var row mongoPromotion
+	err := r.promotion.FindOne(ctx, bson.M{"code": aggregate.NormalizeCode(code)}).Decode(&row)
+	if err != nil {
+		return aggregate.Promotion{}, fmt.Errorf("promotion does not exists: %w; %w", err, promotion.ErrPromotionNotFound)
+	}
+
+	return row.ToAggregate()
+}
+
+func (r *mongoRepository) Add(ctx context.Context, p aggregate.Promotion) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.promotion.InsertOne(ctx, NewFromPromotion(p))
+	if err != nil {
+		return fmt.Errorf("failed to add a promotion: %w; %w", err, promotion.ErrFailedToAddPromotion)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Update(ctx context.Context, p aggregate.Promotion) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := NewFromPromotion(p)
+	filter := bson.M{"id": row.ID, "version": row.Version}
+	row.Version++
+
+	res, err := r.promotion.ReplaceOne(ctx, filter, row)
+	if err != nil {
+		return fmt.Errorf("failed to update a promotion: %w; %w", err, promotion.ErrUpdatePromotion)
+	}
+	if res.MatchedCount == 0 {
+		if n, err := r.promotion.CountDocuments(ctx, bson.M{"id": row.ID}); err == nil && n == 0 {
+			return fmt.Errorf("promotion is not exists :%w", promotion.ErrUpdatePromotion)
+		}
+		return fmt.Errorf("promotion is not at version %d :%w", row.Version-1, promotion.ErrConcurrentUpdate)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.promotion.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete a promotion: %w; %w", err, promotion.ErrDeletePromotion)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("promotion is not exists :%w", promotion.ErrDeletePromotion)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPromotionNotFound    = errors.New("promotion not found in repository")
+	ErrFailedToAddPromotion = errors.New("failed to add the promotion")
+	ErrUpdatePromotion      = errors.New("failed to update the promotion")
+	ErrDeletePromotion      = errors.New("failed to delete the promotion")
+	// ErrConcurrentUpdate is returned when the promotion changed since it was read,
+	// get it again and retry the update
+	ErrConcurrentUpdate = errors.New("the promotion was updated concurrently")
+)
+
+type PromotionRepository interface {
+	// GetByCode finds a promotion by its code, ignoring case
+	GetByCode(ctx context.Context, code string) (aggregate.Promotion, error)
+	Add(ctx context.Context, promotion aggregate.Promotion) error
+	Update(ctx context.Context, promotion aggregate.Promotion) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,85 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlReceipt = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"money": money,
+	"minus": func(amount float64) string { return money(-amount) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Invoice}}</title>
+<style>
+body { font-family: sans-serif; max-width: 28em; margin: auto; }
+header, footer { text-align: center; }
+table { width: 100%; border-collapse: collapse; }
+td.amount { text-align: right; }
+tbody { border-top: 1px solid; }
+tr.total { font-weight: bold; }
+.detail { padding-left: 1em; font-size: smaller; }
+</style>
+</head>
+<body>
+<header>
+{{with .Header.Name}}<h1>{{.}}</h1>{{end}}
+{{with .Header.Address}}<p>{{.}}</p>{{end}}
+{{with .Header.Phone}}<p>Tel. {{.}}</p>{{end}}
+{{with .Header.TaxID}}<p>Tax ID {{.}}</p>{{end}}
+</header>
+<p>Invoice <strong>{{.Invoice}}</strong>, {{.IssuedAt.Format "2006-01-02 15:04"}}<br>
+Order {{.OrderID}}{{with .Customer}}<br>
+Customer {{.}}{{end}}</p>
+<table>
+<tbody class="lines">
+{{- range .Lines}}
+<tr><td>{{.Name}}</td><td class="amount">{{money .UnitPrice}}</td></tr>
+{{- range .Modifiers}}
+<tr><td class="detail">+ {{.}}</td><td></td></tr>
+{{- end}}
+{{- if gt .Discount 0.0}}
+<tr><td class="detail">{{if .Rule}}{{.Rule}}{{else}}Discount{{end}}</td><td class="amount">{{minus .Discount}}</td></tr>
+{{- end}}
+{{- end}}
+</tbody>
+<tbody>
+<tr><td>Subtotal</td><td class="amount">{{money .Subtotal}}</td></tr>
+{{- range .Discounts}}
+<tr><td>{{.Label}}</td><td class="amount">{{minus .Amount}}</td></tr>
+{{- end}}
+{{- range .Taxes}}
+<tr><td>{{$.TaxLabel .}}</td><td class="amount">{{money .Amount}}</td></tr>
+{{- end}}
+</tbody>
+<tbody>
+<tr class="total"><td>Total</td><td class="amount">{{money .Total}}</td></tr>
+{{- if gt .ServiceCharge 0.0}}
+<tr><td>Service charge</td><td class="amount">{{money .ServiceCharge}}</td></tr>
+{{- end}}
+{{- if gt .Tip 0.0}}
+<tr><td>Tip</td><td class="amount">{{money .Tip}}</td></tr>
+{{- end}}
+<tr><td>{{.PaymentLabel}}</td><td class="amount">{{money .Paid}}</td></tr>
+{{- range .Refunds}}
+<tr><td>{{.Label}}</td><td class="amount">{{minus .Amount}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+<footer><p>Thank you!</p></footer>
+</body>
+</html>
+`))
+
+// HTMLRenderer prints receipts as a standalone HTML page, to email or show in a browser
+type HTMLRenderer struct{}
+
+func (HTMLRenderer) ContentType() string {
+	return "text/html; charset=utf-8"
+}
+
+func (HTMLRenderer) Render(w io.Writer, r Receipt) error {
+	return htmlReceipt.Execute(w, r)
+}
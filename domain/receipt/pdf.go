@@ -0,0 +1,80 @@
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	// pdfFontSize and pdfLeading are in points, Courier characters are 0.6 of the font size wide
+	pdfFontSize = 9
+	pdfLeading  = 11
+	pdfMargin   = 12
+)
+
+// PDFRenderer prints the text layout of receipts on a single page as long as
+// the receipt, in a standard font so the document needs no embedded font
+type PDFRenderer struct{}
+
+func (PDFRenderer) ContentType() string {
+	return "application/pdf"
+}
+
+func (PDFRenderer) Render(w io.Writer, r Receipt) error {
+	lines := textLines(r)
+	pageWidth := 2*pdfMargin + width*pdfFontSize*6/10
+	pageHeight := 2*pdfMargin + len(lines)*pdfLeading
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pageHeight-pdfMargin-pdfFontSize)
+	for _, l := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfText(l))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>", pageWidth, pageHeight),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = doc.Len()
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// pdfText escapes a line for a PDF string, characters out of Latin-1 are
+// printed as question marks
+func pdfText(s string) string {
+	var b bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r > 0xff || (r >= 0x7f && r < 0xa0):
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+
+	return b.String()
+}
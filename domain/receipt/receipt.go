@@ -0,0 +1,193 @@
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidReceipt = errors.New("a receipt needs a paid order and a positive invoice number")
+)
+
+// Header is what the receipts print about the tavern
+type Header struct {
+	Name    string
+	Address string
+	Phone   string
+	TaxID   string
+}
+
+// Line is one order line as printed
+type Line struct {
+	Name      string
+	Modifiers []string
+	UnitPrice float64
+	Discount  float64
+	// Rule is the pricing rule that granted the discount, if any
+	Rule  string
+	Total float64
+}
+
+// Adjustment is an amount taken off or given back, with what it is for
+type Adjustment struct {
+	Label  string
+	Amount float64
+}
+
+// Receipt is the itemized invoice of a paid order, printed by a Renderer
+type Receipt struct {
+	Header        Header
+	InvoiceNumber int
+	OrderID       uuid.UUID
+	Customer      string
+	// IssuedAt is when the order was paid
+	IssuedAt time.Time
+
+	Lines    []Line
+	Subtotal float64
+	// Discounts are taken off the whole order, after the subtotal
+	Discounts    []Adjustment
+	Taxes        []aggregate.TaxLine
+	TaxInclusive bool
+	Total        float64
+
+	// ServiceCharge and Tip are paid on top of the total
+	ServiceCharge float64
+	Tip           float64
+
+	PaymentMethod aggregate.PaymentMethod
+	// Paid is what the payers were charged: the total, the service charge and the tip
+	Paid    float64
+	Refunds []Adjustment
+}
+
+// Renderer prints receipts in a format
+type Renderer interface {
+	Render(w io.Writer, r Receipt) error
+	// ContentType is the media type of the rendered receipts
+	ContentType() string
+}
+
+// details are what receipts print beyond the order
+type details struct {
+	customer  string
+	modifiers map[uuid.UUID]string
+}
+
+type Configuration func(d *details) error
+
+// WithCustomer prints the name of the customer
+func WithCustomer(name string) Configuration {
+	return func(d *details) error {
+		d.customer = name
+		return nil
+	}
+}
+
+// WithModifierNames names the modifiers of the lines by id, modifiers
+// without a name are not printed
+func WithModifierNames(names map[uuid.UUID]string) Configuration {
+	return func(d *details) error {
+		for id, name := range names {
+			d.modifiers[id] = name
+		}
+		return nil
+	}
+}
+
+// New builds the receipt of a paid order, numbered number by the invoice repository
+func New(header Header, number int, o aggregate.Order, cfgs ...Configuration) (Receipt, error) {
+	if number <= 0 || o.GetStatus() == aggregate.OrderUnpaid {
+		return Receipt{}, ErrInvalidReceipt
+	}
+
+	d := details{modifiers: map[uuid.UUID]string{}}
+	for _, cfg := range cfgs {
+		if err := cfg(&d); err != nil {
+			return Receipt{}, err
+		}
+	}
+
+	r := Receipt{
+		Header:        header,
+		InvoiceNumber: number,
+		OrderID:       o.GetID(),
+		Customer:      d.customer,
+		IssuedAt:      o.GetPaidAt(),
+		Subtotal:      o.GetSubtotal(),
+		Taxes:         o.GetTaxes(),
+		TaxInclusive:  o.IsTaxInclusive(),
+		Total:         o.GetTotal(),
+		ServiceCharge: o.GetServiceCharge(),
+		Tip:           o.GetTip(),
+		PaymentMethod: o.GetPaymentMethod(),
+		Paid:          aggregate.RoundMoney(o.GetCharged() + o.GetServiceCharge() + o.GetTip()),
+	}
+
+	for _, l := range o.GetLines() {
+		line := Line{
+			Name:      l.Name,
+			UnitPrice: l.UnitPrice,
+			Discount:  l.Discount,
+			Rule:      l.Rule,
+			Total:     l.Total(),
+		}
+		for _, id := range l.ModifierIDs {
+			if name, ok := d.modifiers[id]; ok {
+				line.Modifiers = append(line.Modifiers, name)
+			}
+		}
+		r.Lines = append(r.Lines, line)
+	}
+
+	if o.GetTierDiscount() > 0 {
+		r.Discounts = append(r.Discounts, Adjustment{Label: fmt.Sprintf("Membership %s", o.GetTier()), Amount: o.GetTierDiscount()})
+	}
+	if o.GetCouponDiscount() > 0 {
+		r.Discounts = append(r.Discounts, Adjustment{Label: fmt.Sprintf("Coupon %s", o.GetCoupon()), Amount: o.GetCouponDiscount()})
+	}
+	if o.GetPointsDiscount() > 0 {
+		r.Discounts = append(r.Discounts, Adjustment{Label: fmt.Sprintf("%d loyalty points", o.GetPointsRedeemed()), Amount: o.GetPointsDiscount()})
+	}
+
+	for _, refund := range o.GetRefunds() {
+		label := "Refund"
+		if refund.Reason != "" {
+			label = fmt.Sprintf("Refund (%s)", refund.Reason)
+		}
+		r.Refunds = append(r.Refunds, Adjustment{Label: label, Amount: refund.Amount()})
+	}
+
+	return r, nil
+}
+
+// Invoice is the printed invoice number
+func (r Receipt) Invoice() string {
+	return fmt.Sprintf("%06d", r.InvoiceNumber)
+}
+
+// TaxLabel names a tax line, with its base when the tax is added on top of the prices
+func (r Receipt) TaxLabel(t aggregate.TaxLine) string {
+	if r.TaxInclusive {
+		return fmt.Sprintf("incl. %s %g%%", t.Name, t.Rate)
+	}
+
+	return fmt.Sprintf("%s %g%% on %.2f", t.Name, t.Rate, t.Base)
+}
+
+// PaymentLabel tells how the order was paid
+func (r Receipt) PaymentLabel() string {
+	switch r.PaymentMethod {
+	case aggregate.PaymentCard:
+		return "Paid by card"
+	case aggregate.PaymentWallet:
+		return "Paid from wallet"
+	default:
+		return "Paid on account"
+	}
+}
@@ -0,0 +1,236 @@
+package receipt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/valueobject"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func paidOrder(t *testing.T, foam uuid.UUID) aggregate.Order {
+	t.Helper()
+
+	customerID := uuid.New()
+	paidAt := time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)
+
+	o, err := aggregate.NewOrder(customerID, []aggregate.OrderLine{
+		{ProductID: uuid.New(), ModifierIDs: []uuid.UUID{foam}, Name: "Beer (pint)", UnitPrice: 6.5, Discount: 1.5, Rule: "Happy hour"},
+		{ProductID: uuid.New(), Name: "Peanuts", UnitPrice: 3},
+	}, paidAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o.SetID(uuid.MustParse("7c9e6679-7425-40de-944b-e07fc1f90ae7"))
+	o.ApplyCoupon("SPRING", 1)
+	o.ApplyTax([]aggregate.TaxLine{{Name: "VAT", Rate: 10, Base: 7, Amount: 0.7}}, false)
+
+	payment := valueobject.NewTransaction(valueobject.TransactionPayment, 770, customerID, uuid.New(), paidAt)
+	if err := o.Pay(aggregate.PaymentCard, []valueobject.Transaction{payment}, paidAt); err != nil {
+		t.Fatal(err)
+	}
+
+	refund, err := o.RefundLines([]int{1}, "stale", paidAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.AddRefund(refund); err != nil {
+		t.Fatal(err)
+	}
+
+	return o
+}
+
+func TestReceipt_New(t *testing.T) {
+	foam := uuid.New()
+	o := paidOrder(t, foam)
+
+	unpaid, err := aggregate.NewOrder(uuid.New(), o.GetLines(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(Header{}, 1, unpaid); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("expected error %v, got %v", ErrInvalidReceipt, err)
+	}
+	if _, err := New(Header{}, 0, o); !errors.Is(err, ErrInvalidReceipt) {
+		t.Errorf("expected error %v, got %v", ErrInvalidReceipt, err)
+	}
+
+	r, err := New(Header{}, 42, o, WithModifierNames(map[uuid.UUID]string{foam: "Extra foam"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Invoice() != "000042" || r.Total != 7.7 || r.Paid != 7.7 || len(r.Refunds) != 1 || r.Refunds[0].Amount != 2.89 {
+		t.Errorf("unexpected receipt %+v", r)
+	}
+	if len(r.Lines[0].Modifiers) != 1 || r.Lines[0].Modifiers[0] != "Extra foam" {
+		t.Errorf("expected the modifier to be named, got %v", r.Lines[0].Modifiers)
+	}
+}
+
+func TestReceipt_Text(t *testing.T) {
+	foam := uuid.New()
+	r, err := New(
+		Header{Name: "The Golden Tankard", Address: "1 Main Street", TaxID: "FR123"},
+		42,
+		paidOrder(t, foam),
+		WithCustomer("Miku"),
+		WithModifierNames(map[uuid.UUID]string{foam: "Extra foam"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := (TextRenderer{}).Render(&b, r); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `            The Golden Tankard
+              1 Main Street
+               Tax ID FR123
+------------------------------------------
+Invoice 000042            2024-03-01 18:30
+Order
+7c9e6679-7425-40de-944b-e07fc1f90ae7
+Customer                              Miku
+------------------------------------------
+Beer (pint)                           6.50
+  + Extra foam
+  Happy hour                         -1.50
+Peanuts                               3.00
+------------------------------------------
+Subtotal                              8.00
+Coupon SPRING                        -1.00
+VAT 10% on 7.00                       0.70
+------------------------------------------
+TOTAL                                 7.70
+Paid by card                          7.70
+Refund (stale)                       -2.89
+
+                Thank you!
+`
+	if b.String() != expected {
+		t.Errorf("expected receipt\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestReceipt_ServiceChargeAndTip(t *testing.T) {
+	o := paidOrder(t, uuid.New())
+	payment := o.GetPayments()[0]
+	if err := o.AddExtras([]valueobject.Transaction{
+		valueobject.NewTransaction(valueobject.TransactionServiceCharge, 77, payment.From(), payment.To(), payment.CreatedAt()),
+		valueobject.NewTransaction(valueobject.TransactionTip, 100, payment.From(), payment.To(), payment.CreatedAt()),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := New(Header{Name: "The Golden Tankard"}, 42, o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// what the payer was charged adds up from the printed amounts
+	if r.ServiceCharge != 0.77 || r.Tip != 1 || r.Paid != 9.47 {
+		t.Errorf("unexpected receipt %+v", r)
+	}
+
+	var b bytes.Buffer
+	if err := (TextRenderer{}).Render(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	expected := `TOTAL                                 7.70
+Service charge                        0.77
+Tip                                   1.00
+Paid by card                          9.47
+`
+	if !strings.Contains(b.String(), expected) {
+		t.Errorf("expected the receipt to contain\n%s\ngot\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := (HTMLRenderer{}).Render(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`<td>Service charge</td><td class="amount">0.77</td>`,
+		`<td>Tip</td><td class="amount">1.00</td>`,
+	} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("expected the receipt to contain %q, got\n%s", expected, b.String())
+		}
+	}
+}
+
+func TestReceipt_HTML(t *testing.T) {
+	r, err := New(Header{Name: "Ale & Tales"}, 7, paidOrder(t, uuid.New()), WithCustomer("<script>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := (HTMLRenderer{}).Render(&b, r); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"<title>Invoice 000007</title>",
+		"<h1>Ale &amp; Tales</h1>",
+		"Customer &lt;script&gt;",
+		`<td class="detail">Happy hour</td><td class="amount">-1.50</td>`,
+		`<tr class="total"><td>Total</td><td class="amount">7.70</td></tr>`,
+		`<td>Refund (stale)</td><td class="amount">-2.89</td>`,
+	} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("expected the receipt to contain %q, got\n%s", expected, b.String())
+		}
+	}
+}
+
+func TestReceipt_PDF(t *testing.T) {
+	r, err := New(Header{Name: "Café (main)"}, 7, paidOrder(t, uuid.New()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := (PDFRenderer{}).Render(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	doc := b.Bytes()
+
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatalf("expected a PDF document, got\n%s", doc)
+	}
+	for _, expected := range []string{"Caf\xe9 \\(main\\)) Tj", "(TOTAL                                 7.70) Tj"} {
+		if !bytes.Contains(doc, []byte(expected)) {
+			t.Errorf("expected the document to contain %q", expected)
+		}
+	}
+
+	// every cross-reference points at its object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	if startxref == nil {
+		t.Fatal("expected a startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(doc[xref:], []byte("xref\n")) {
+		t.Fatalf("expected the xref table at %d", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(doc[xref:], -1)
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 objects, got %d", len(entries))
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if !bytes.HasPrefix(doc[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))) {
+			t.Errorf("expected object %d at offset %d", i+1, offset)
+		}
+	}
+}
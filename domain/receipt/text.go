@@ -0,0 +1,126 @@
+package receipt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// width is the number of characters of a text receipt line, enough for an order id
+const width = 42
+
+// TextRenderer prints receipts as plain text for receipt printers
+type TextRenderer struct{}
+
+func (TextRenderer) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+func (TextRenderer) Render(w io.Writer, r Receipt) error {
+	for _, line := range textLines(r) {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// textLines lays the receipt out in lines of at most width characters,
+// shared by the text and PDF renderers
+func textLines(r Receipt) []string {
+	var lines []string
+	add := func(l ...string) {
+		lines = append(lines, l...)
+	}
+	rule := strings.Repeat("-", width)
+
+	for _, h := range []string{r.Header.Name, r.Header.Address, labelled("Tel.", r.Header.Phone), labelled("Tax ID", r.Header.TaxID)} {
+		if h != "" {
+			add(center(h))
+		}
+	}
+	add(rule)
+	add(row("Invoice "+r.Invoice(), r.IssuedAt.Format("2006-01-02 15:04")))
+	add("Order", r.OrderID.String())
+	if r.Customer != "" {
+		add(row("Customer", r.Customer))
+	}
+	add(rule)
+
+	for _, l := range r.Lines {
+		add(row(l.Name, money(l.UnitPrice)))
+		for _, m := range l.Modifiers {
+			add("  + " + m)
+		}
+		if l.Discount > 0 {
+			rule := l.Rule
+			if rule == "" {
+				rule = "Discount"
+			}
+			add(row("  "+rule, money(-l.Discount)))
+		}
+	}
+	add(rule)
+
+	add(row("Subtotal", money(r.Subtotal)))
+	for _, d := range r.Discounts {
+		add(row(d.Label, money(-d.Amount)))
+	}
+	for _, t := range r.Taxes {
+		add(row(r.TaxLabel(t), money(t.Amount)))
+	}
+	add(rule)
+
+	add(row("TOTAL", money(r.Total)))
+	if r.ServiceCharge > 0 {
+		add(row("Service charge", money(r.ServiceCharge)))
+	}
+	if r.Tip > 0 {
+		add(row("Tip", money(r.Tip)))
+	}
+	add(row(r.PaymentLabel(), money(r.Paid)))
+	for _, refund := range r.Refunds {
+		add(row(refund.Label, money(-refund.Amount)))
+	}
+	add("", center("Thank you!"))
+
+	return lines
+}
+
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return label + " " + value
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
+
+// row aligns value to the right of label, cutting label when both do not fit
+func row(label, value string) string {
+	value = cut(value, width)
+	label = cut(label, width-utf8.RuneCountInString(value)-1)
+
+	return label + strings.Repeat(" ", width-utf8.RuneCountInString(label)-utf8.RuneCountInString(value)) + value
+}
+
+func cut(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return string([]rune(s)[:max(n, 0)])
+}
+
+func center(s string) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return strings.Repeat(" ", (width-n)/2) + s
+	}
+
+	return s
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/tab"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+type memoryRepository struct {
+	tabs map[uuid.UUID]aggregate.Tab
+	sync.Mutex
+}
+
+func New() tab.TabRepository {
+	return &memoryRepository{
+		tabs: map[uuid.UUID]aggregate.Tab{},
+	}
+}
+
+func (r *memoryRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if t, ok := r.tabs[id]; ok {
+		return copyTab(t), nil
+	}
+
+	return aggregate.Tab{}, tab.ErrTabNotFound
+}
+
+func (r *memoryRepository) GetAll(ctx context.Context) ([]aggregate.Tab, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	tabs := make([]aggregate.Tab, 0, len(r.tabs))
+	for _, t := range r.tabs {
+		if t.IsOpen() {
+			tabs = append(tabs, copyTab(t))
+		}
+	}
+
+	sort.Slice(tabs, func(i, j int) bool {
+		if !tabs[i].GetOpenedAt().Equal(tabs[j].GetOpenedAt()) {
+			return tabs[i].GetOpenedAt().Before(tabs[j].GetOpenedAt())
+		}
+		return tabs[i].GetID().String() < tabs[j].GetID().String()
+	})
+
+	return tabs, nil
+}
+
+func (r *memoryRepository) Add(ctx context.Context, t aggregate.Tab) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.tabs[t.GetID()]; ok {
+		return fmt.Errorf("tab already exists :%w", tab.ErrFailedToAddTab)
+	}
+
+	r.tabs[t.GetID()] = copyTab(t)
+
+	return nil
+}
+
+func (r *memoryRepository) Update(ctx context.Context, t aggregate.Tab) error {
+	r.Lock()
+	defer r.Unlock()
+
+	stored, ok := r.tabs[t.GetID()]
+	if !ok {
+		return fmt.Errorf("tab is not exists :%w", tab.ErrUpdateTab)
+	}
+	if stored.GetVersion() != t.GetVersion() {
+		return fmt.Errorf("tab is at version %d, not %d :%w", stored.GetVersion(), t.GetVersion(), tab.ErrConcurrentUpdate)
+	}
+
+	t = copyTab(t)
+	t.SetVersion(t.GetVersion() + 1)
+	r.tabs[t.GetID()] = t
+
+	return nil
+}
+
+func (r *memoryRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, ok := r.tabs[id]; !ok {
+		return fmt.Errorf("tab is not exists :%w", tab.ErrDeleteTab)
+	}
+
+	delete(r.tabs, id)
+
+	return nil
+}
+
+// copyTab keeps the orders of the stored tab from being changed by callers
+// until they Update it
+func copyTab(t aggregate.Tab) aggregate.Tab {
+	t.SetOrders(t.GetOrders())
+	return t
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/tab"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_memoryRepository_GetAll(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+	now := time.Now()
+
+	var expected []string
+	for i, table := range []string{"3", "1", "2"} {
+		tb, err := aggregate.NewTab(uuid.New(), table, now.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Add(ctx, tb); err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, table)
+	}
+
+	// a closed tab is kept until it is settled, but is not listed
+	closed, err := aggregate.NewTab(uuid.New(), "4", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := closed.Close(now); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, closed); err != nil {
+		t.Fatal(err)
+	}
+
+	tabs, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tables []string
+	for _, tb := range tabs {
+		tables = append(tables, tb.GetTable())
+	}
+	if !reflect.DeepEqual(tables, expected) {
+		t.Errorf("expected tabs in opening order %v, got %v", expected, tables)
+	}
+}
+
+func Test_memoryRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := New()
+
+	customerID := uuid.New()
+	tb, err := aggregate.NewTab(customerID, "7", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Add(ctx, tb); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := aggregate.NewOrder(customerID, []aggregate.OrderLine{{ProductID: uuid.New(), UnitPrice: 3}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := repo.Get(ctx, tb.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stored.AddOrder(order); err != nil {
+		t.Fatal(err)
+	}
+
+	// the orders are only saved on Update
+	unchanged, err := repo.Get(ctx, tb.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unchanged.GetOrders()) != 0 {
+		t.Errorf("expected the stored tab to be untouched, got %d orders", len(unchanged.GetOrders()))
+	}
+
+	if err := repo.Update(ctx, stored); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := repo.Get(ctx, tb.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.GetTotal() != 3 {
+		t.Errorf("expected total %v, got %v", 3, updated.GetTotal())
+	}
+
+	// the tab read before the update is stale, its order would be lost
+	if err := repo.Update(ctx, unchanged); !errors.Is(err, tab.ErrConcurrentUpdate) {
+		t.Errorf("expected error %v, got %v", tab.ErrConcurrentUpdate, err)
+	}
+
+	if err := repo.Delete(ctx, tb.GetID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, tb.GetID()); !errors.Is(err, tab.ErrTabNotFound) {
+		t.Errorf("expected error %v, got %v", tab.ErrTabNotFound, err)
+	}
+}
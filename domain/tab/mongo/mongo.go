@@ -0,0 +1,182 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	orderMongo "golang-learn-ddd/domain/order/mongo"
+	"golang-learn-ddd/domain/tab"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	db  *mongo.Database
+	tab *mongo.Collection
+}
+
+// mongoTab internal type to store TabAggregate to mongodb
+type mongoTab struct {
+	ID         uuid.UUID               `bson:"id"`
+	CustomerID uuid.UUID               `bson:"customer_id"`
+	Table      string                  `bson:"table"`
+	Orders     []orderMongo.MongoOrder `bson:"orders"`
+	OpenedAt   time.Time               `bson:"opened_at"`
+	ClosedAt   time.Time               `bson:"closed_at,omitempty"`
+	Version    int                     `bson:"version"`
+}
+
+func NewFromTab(t aggregate.Tab) mongoTab {
+	orders := make([]orderMongo.MongoOrder, 0, len(t.GetOrders()))
+	for _, o := range t.GetOrders() {
+		orders = append(orders, orderMongo.NewFromOrder(o))
+	}
+
+	return mongoTab{
+		ID:         t.GetID(),
+		CustomerID: t.GetCustomerID(),
+		Table:      t.GetTable(),
+		Orders:     orders,
+		OpenedAt:   t.GetOpenedAt(),
+		ClosedAt:   t.GetClosedAt(),
+		Version:    t.GetVersion(),
+	}
+}
+
+func (m *mongoTab) ToAggregate() (aggregate.Tab, error) {
+	t, err := aggregate.NewTab(m.CustomerID, m.Table, m.OpenedAt)
+	if err != nil {
+		return aggregate.Tab{}, err
+	}
+	t.SetID(m.ID)
+	t.SetVersion(m.Version)
+
+	orders := make([]aggregate.Order, 0, len(m.Orders))
+	for _, row := range m.Orders {
+		o, err := row.ToAggregate()
+		if err != nil {
+			return aggregate.Tab{}, err
+		}
+		orders = append(orders, o)
+	}
+	t.SetOrders(orders)
+
+	if !m.ClosedAt.IsZero() {
+		if err := t.Close(m.ClosedAt); err != nil {
+			return aggregate.Tab{}, err
+		}
+	}
+
+	return t, nil
+}
+
+func New(ctx context.Context, connectionString string) (tab.TabRepository, error) {
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	if err != nil {
+		return nil, err
+	}
+
+	db := client.Database("learn-golang-ddd")
+	tabs := db.Collection("tabs")
+
+	return &mongoRepository{
+		db:  db,
+		tab: tabs,
+	}, nil
+}
+
+func (r *mongoRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Tab, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var row mongoTab
+	err := r.tab.FindOne(ctx, bson.M{"id": id}).Decode(&row)
+	if err != nil {
+		return aggregate.Tab{}, fmt.Errorf("tab does not exists: %w; %w", err, tab.ErrTabNotFound)
+	}
+
+	return row.ToAggregate()
+}
+
+func (r *mongoRepository) GetAll(ctx context.Context) ([]aggregate.Tab, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "opened_at", Value: 1}, {Key: "id", Value: 1}})
+	// closed_at is left out of the open tabs
+	open := bson.M{"closed_at": bson.M{"$in": bson.A{nil, time.Time{}}}}
+	cursor, err := r.tab.Find(ctx, open, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tabs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	tabs := make([]aggregate.Tab, 0)
+	for cursor.Next(ctx) {
+		var row mongoTab
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+
+		t, err := row.ToAggregate()
+		if err != nil {
+			return nil, err
+		}
+		tabs = append(tabs, t)
+	}
+
+	return tabs, cursor.Err()
+}
+
+func (r *mongoRepository) Add(ctx context.Context, t aggregate.Tab) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.tab.InsertOne(ctx, NewFromTab(t))
+	if err != nil {
+		return fmt.Errorf("failed to add a tab: %w; %w", err, tab.ErrFailedToAddTab)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Update(ctx context.Context, t aggregate.Tab) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	row := NewFromTab(t)
+	filter := bson.M{"id": row.ID, "version": row.Version}
+	row.Version++
+
+	res, err := r.tab.ReplaceOne(ctx, filter, row)
+	if err != nil {
+		return fmt.Errorf("failed to update a tab: %w; %w", err, tab.ErrUpdateTab)
+	}
+	if res.MatchedCount == 0 {
+		if n, err := r.tab.CountDocuments(ctx, bson.M{"id": row.ID}); err == nil && n == 0 {
+			return fmt.Errorf("tab is not exists :%w", tab.ErrUpdateTab)
+		}
+		return fmt.Errorf("tab is not at version %d :%w", row.Version-1, tab.ErrConcurrentUpdate)
+	}
+
+	return nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.tab.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete a tab: %w; %w", err, tab.ErrDeleteTab)
+	}
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("tab is not exists :%w", tab.ErrDeleteTab)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package tab
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTabNotFound    = errors.New("tab not found in repository")
+	ErrFailedToAddTab = errors.New("failed to add the tab")
+	ErrUpdateTab      = errors.New("failed to update the tab")
+	ErrDeleteTab      = errors.New("failed to delete the tab")
+	// ErrConcurrentUpdate is returned when the tab changed since it was read,
+	// get it again and retry the update
+	ErrConcurrentUpdate = errors.New("the tab was updated concurrently")
+)
+
+// TabRepository keeps the open tabs, and the closed tabs until they are
+// settled and deleted
+type TabRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (aggregate.Tab, error)
+	// GetAll returns the open tabs ordered by the time they were opened
+	GetAll(ctx context.Context) ([]aggregate.Tab, error)
+	Add(ctx context.Context, tab aggregate.Tab) error
+	Update(ctx context.Context, tab aggregate.Tab) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,144 @@
+package tax
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPolicy = errors.New("invalid tax policy")
+)
+
+// Policy sets the tax breakdown of an order
+type Policy interface {
+	Apply(order *aggregate.Order)
+}
+
+// Rounding decides when tax amounts are rounded to the cent
+type Rounding int
+
+const (
+	// RoundPerRate sums the exact tax of every line and rounds once per rate
+	RoundPerRate Rounding = iota
+	// RoundPerLine rounds the tax of every line before summing them
+	RoundPerLine
+)
+
+type rate struct {
+	name    string
+	percent float64
+}
+
+// RatePolicy taxes every line at a default rate, or at the rate of its category
+type RatePolicy struct {
+	rate       rate
+	categories map[uuid.UUID]rate
+	inclusive  bool
+	rounding   Rounding
+}
+
+type RateConfiguration func(p *RatePolicy) error
+
+// NewRatePolicy taxes lines at percent (0-100), exclusive of the prices by default
+func NewRatePolicy(name string, percent float64, cfgs ...RateConfiguration) (*RatePolicy, error) {
+	if name == "" || percent < 0 || percent > 100 {
+		return nil, ErrInvalidPolicy
+	}
+
+	p := &RatePolicy{
+		rate:       rate{name: name, percent: percent},
+		categories: map[uuid.UUID]rate{},
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// WithCategoryRate taxes the products of a category at their own rate, use 0 for exempt categories
+func WithCategoryRate(categoryID uuid.UUID, name string, percent float64) RateConfiguration {
+	return func(p *RatePolicy) error {
+		if categoryID == uuid.Nil || name == "" || percent < 0 || percent > 100 {
+			return ErrInvalidPolicy
+		}
+
+		p.categories[categoryID] = rate{name: name, percent: percent}
+		return nil
+	}
+}
+
+// Inclusive treats prices as already including the tax, which is then
+// extracted from them instead of added on top
+func Inclusive() RateConfiguration {
+	return func(p *RatePolicy) error {
+		p.inclusive = true
+		return nil
+	}
+}
+
+func WithRounding(rounding Rounding) RateConfiguration {
+	return func(p *RatePolicy) error {
+		if rounding != RoundPerRate && rounding != RoundPerLine {
+			return ErrInvalidPolicy
+		}
+
+		p.rounding = rounding
+		return nil
+	}
+}
+
+// Apply taxes the lines after their discounts, the coupon and the loyalty
+// points of the order are shared between the lines in proportion of their totals
+func (p *RatePolicy) Apply(order *aggregate.Order) {
+	subtotal := order.GetSubtotal()
+	orderShare := 0.0
+	if subtotal > 0 {
+		orderShare = order.GetOrderDiscount() / subtotal
+	}
+
+	var taxes []aggregate.TaxLine
+	index := map[rate]int{}
+	for _, l := range order.GetLines() {
+		r, ok := p.categories[l.CategoryID]
+		if !ok {
+			r = p.rate
+		}
+
+		amount := l.Total() * (1 - orderShare)
+		var tax float64
+		if p.inclusive {
+			tax = amount - amount/(1+r.percent/100)
+		} else {
+			tax = amount * r.percent / 100
+		}
+		if p.rounding == RoundPerLine {
+			tax = aggregate.RoundMoney(tax)
+		}
+
+		i, ok := index[r]
+		if !ok {
+			i = len(taxes)
+			index[r] = i
+			taxes = append(taxes, aggregate.TaxLine{Name: r.name, Rate: r.percent})
+		}
+		taxes[i].Amount += tax
+		if p.inclusive {
+			taxes[i].Base += amount - tax
+		} else {
+			taxes[i].Base += amount
+		}
+	}
+
+	for i := range taxes {
+		taxes[i].Base = aggregate.RoundMoney(taxes[i].Base)
+		taxes[i].Amount = aggregate.RoundMoney(taxes[i].Amount)
+	}
+
+	order.ApplyTax(taxes, p.inclusive)
+}
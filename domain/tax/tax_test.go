@@ -0,0 +1,126 @@
+package tax
+
+import (
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestTax_RatePolicy(t *testing.T) {
+	drinks, food, water := uuid.New(), uuid.New(), uuid.New()
+
+	line := func(categoryID uuid.UUID, price float64) aggregate.OrderLine {
+		return aggregate.OrderLine{ProductID: uuid.New(), CategoryID: categoryID, UnitPrice: price}
+	}
+
+	type testCase struct {
+		name     string
+		cfgs     []RateConfiguration
+		lines    []aggregate.OrderLine
+		coupon   float64
+		expected []aggregate.TaxLine
+		total    float64
+	}
+	tests := []testCase{
+		{
+			name:  "exclusive rates per category",
+			cfgs:  []RateConfiguration{WithCategoryRate(food, "reduced", 5)},
+			lines: []aggregate.OrderLine{line(drinks, 10), line(food, 5), line(drinks, 2)},
+			expected: []aggregate.TaxLine{
+				{Name: "VAT", Rate: 10, Base: 12, Amount: 1.2},
+				{Name: "reduced", Rate: 5, Base: 5, Amount: 0.25},
+			},
+			total: 18.45,
+		},
+		{
+			name:     "inclusive prices",
+			cfgs:     []RateConfiguration{Inclusive()},
+			lines:    []aggregate.OrderLine{line(drinks, 11)},
+			expected: []aggregate.TaxLine{{Name: "VAT", Rate: 10, Base: 10, Amount: 1}},
+			total:    11,
+		},
+		{
+			name:     "coupon lowers the taxed amount",
+			lines:    []aggregate.OrderLine{line(drinks, 10), line(food, 10)},
+			coupon:   5,
+			expected: []aggregate.TaxLine{{Name: "VAT", Rate: 10, Base: 15, Amount: 1.5}},
+			total:    16.5,
+		},
+		{
+			name:     "exempt category",
+			cfgs:     []RateConfiguration{WithCategoryRate(water, "exempt", 0)},
+			lines:    []aggregate.OrderLine{line(water, 1)},
+			expected: []aggregate.TaxLine{{Name: "exempt", Rate: 0, Base: 1, Amount: 0}},
+			total:    1,
+		},
+		{
+			name:     "rounded once per rate",
+			cfgs:     []RateConfiguration{WithCategoryRate(food, "reduced", 5)},
+			lines:    []aggregate.OrderLine{line(food, 0.15), line(food, 0.15), line(food, 0.15)},
+			expected: []aggregate.TaxLine{{Name: "reduced", Rate: 5, Base: 0.45, Amount: 0.02}},
+			total:    0.47,
+		},
+		{
+			name:     "rounded per line",
+			cfgs:     []RateConfiguration{WithCategoryRate(food, "reduced", 5), WithRounding(RoundPerLine)},
+			lines:    []aggregate.OrderLine{line(food, 0.15), line(food, 0.15), line(food, 0.15)},
+			expected: []aggregate.TaxLine{{Name: "reduced", Rate: 5, Base: 0.45, Amount: 0.03}},
+			total:    0.48,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := NewRatePolicy("VAT", 10, tc.cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			order, err := aggregate.NewOrder(uuid.New(), tc.lines, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.coupon > 0 {
+				order.ApplyCoupon("COUPON", tc.coupon)
+			}
+
+			policy.Apply(&order)
+
+			if !reflect.DeepEqual(order.GetTaxes(), tc.expected) {
+				t.Errorf("expected taxes %+v, got %+v", tc.expected, order.GetTaxes())
+			}
+			if order.GetTotal() != tc.total {
+				t.Errorf("expected total %v, got %v", tc.total, order.GetTotal())
+			}
+		})
+	}
+}
+
+func TestTax_NewRatePolicy(t *testing.T) {
+	type testCase struct {
+		name        string
+		rate        float64
+		cfgs        []RateConfiguration
+		expectedErr error
+	}
+	tests := []testCase{
+		{name: "valid", rate: 10},
+		{name: "negative rate", rate: -1, expectedErr: ErrInvalidPolicy},
+		{name: "rate above 100", rate: 101, expectedErr: ErrInvalidPolicy},
+		{name: "category without id", rate: 10, cfgs: []RateConfiguration{WithCategoryRate(uuid.Nil, "food", 5)}, expectedErr: ErrInvalidPolicy},
+		{name: "unknown rounding", rate: 10, cfgs: []RateConfiguration{WithRounding(Rounding(7))}, expectedErr: ErrInvalidPolicy},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewRatePolicy("VAT", tc.rate, tc.cfgs...)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
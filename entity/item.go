@@ -8,4 +8,8 @@ type Item struct {
 	ID          uuid.UUID
 	Name        string
 	Description string
+	// CategoryID is the menu section of the item, uuid.Nil if uncategorized
+	CategoryID uuid.UUID
+	// Tags are free-form, lowercased labels such as "spicy" or "vegan"
+	Tags []string
 }
@@ -1,10 +1,16 @@
 package entity
 
 import (
+	"golang-learn-ddd/valueobject"
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type Person struct {
-	ID   uuid.UUID
-	Name string
+	ID          uuid.UUID
+	Name        string
+	Email       valueobject.Email
+	PhoneNumber valueobject.PhoneNumber
+	DateOfBirth time.Time
 }
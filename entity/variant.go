@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"github.com/google/uuid"
+)
+
+// Variant is a sellable version of an item, such as a pint or a half-pint of beer
+type Variant struct {
+	ID       uuid.UUID
+	Name     string
+	Price    float64
+	Quantity int
+}
+
+// Modifier is an optional add-on to an item, such as extra noodles
+type Modifier struct {
+	ID         uuid.UUID
+	Name       string
+	PriceDelta float64
+}
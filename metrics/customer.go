@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// customerRepository records latency and errors of any customer.CustomerRepository
+type customerRepository struct {
+	next    customer.CustomerRepository
+	metrics *Metrics
+}
+
+func NewCustomerRepository(next customer.CustomerRepository, m *Metrics) customer.CustomerRepository {
+	return &customerRepository{
+		next:    next,
+		metrics: m,
+	}
+}
+
+func (r *customerRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Customer, error) {
+	start := time.Now()
+	c, err := r.next.Get(ctx, id)
+	r.metrics.observeRepository("customer", "get", start, err)
+
+	return c, err
+}
+
+func (r *customerRepository) Add(ctx context.Context, c aggregate.Customer) error {
+	start := time.Now()
+	err := r.next.Add(ctx, c)
+	r.metrics.observeRepository("customer", "add", start, err)
+
+	return err
+}
+
+func (r *customerRepository) Update(ctx context.Context, c aggregate.Customer) error {
+	start := time.Now()
+	err := r.next.Update(ctx, c)
+	r.metrics.observeRepository("customer", "update", start, err)
+
+	return err
+}
+
+func (r *customerRepository) Delete(ctx context.Context, c aggregate.Customer) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, c)
+	r.metrics.observeRepository("customer", "delete", start, err)
+
+	return err
+}
+
+func (r *customerRepository) List(ctx context.Context, offset, limit int) (customer.Page, error) {
+	start := time.Now()
+	page, err := r.next.List(ctx, offset, limit)
+	r.metrics.observeRepository("customer", "list", start, err)
+
+	return page, err
+}
+
+func (r *customerRepository) FindByName(ctx context.Context, prefix string) ([]aggregate.Customer, error) {
+	start := time.Now()
+	customers, err := r.next.FindByName(ctx, prefix)
+	r.metrics.observeRepository("customer", "find_by_name", start, err)
+
+	return customers, err
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Reasons an order can fail with, used as the "reason" label of the failed orders counter
+const (
+	ReasonCustomerNotFound   = "customer_not_found"
+	ReasonProductNotFound    = "product_not_found"
+	ReasonAgeRestricted      = "age_restricted"
+	ReasonCouponRejected     = "coupon_rejected"
+	ReasonRedemptionRejected = "redemption_rejected"
+	ReasonOther              = "other"
+)
+
+// Metrics holds every collector of the tavern and the registry they are exposed from.
+// A nil *Metrics is valid and records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	ordersCreated       prometheus.Counter
+	ordersFailed        *prometheus.CounterVec
+	createOrderDuration prometheus.Histogram
+
+	repositoryDuration *prometheus.HistogramVec
+	repositoryErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		ordersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "tavern",
+			Name:      "orders_created_total",
+			Help:      "Number of orders created successfully.",
+		}),
+		ordersFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tavern",
+			Name:      "orders_failed_total",
+			Help:      "Number of orders that failed, by reason.",
+		}, []string{"reason"}),
+		createOrderDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "tavern",
+			Name:      "create_order_duration_seconds",
+			Help:      "Latency of OrderService.CreateOrder.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "tavern",
+			Name:      "repository_call_duration_seconds",
+			Help:      "Latency of repository calls, by repository and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "method"}),
+		repositoryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tavern",
+			Name:      "repository_errors_total",
+			Help:      "Number of repository calls that returned an error, by repository and method.",
+		}, []string{"repository", "method"}),
+	}
+
+	m.registry.MustRegister(
+		m.ordersCreated,
+		m.ordersFailed,
+		m.createOrderDuration,
+		m.repositoryDuration,
+		m.repositoryErrors,
+	)
+
+	return m
+}
+
+// Registry returns the registry holding the tavern collectors
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler exposes the collectors in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) OrderCreated(d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.ordersCreated.Inc()
+	m.createOrderDuration.Observe(d.Seconds())
+}
+
+func (m *Metrics) OrderFailed(reason string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.ordersFailed.WithLabelValues(reason).Inc()
+	m.createOrderDuration.Observe(d.Seconds())
+}
+
+func (m *Metrics) observeRepository(repository, method string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+
+	m.repositoryDuration.WithLabelValues(repository, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.repositoryErrors.WithLabelValues(repository, method).Inc()
+	}
+}
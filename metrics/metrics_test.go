@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	customerMemory "golang-learn-ddd/domain/customer/memory"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics_CustomerRepository(t *testing.T) {
+	m := New()
+	repo := NewCustomerRepository(customerMemory.New(), m)
+
+	cust, err := aggregate.NewCustomer("Adhiana")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.Get(context.Background(), cust.GetID()); err != nil {
+		t.Fatal(err)
+	}
+
+	// errors from the wrapped repository are passed through untouched
+	if _, err := repo.Get(context.Background(), uuid.New()); !errors.Is(err, customer.ErrCustomerNotFound) {
+		t.Errorf("expected error %v, got %v", customer.ErrCustomerNotFound, err)
+	}
+
+	if got := testutil.ToFloat64(m.repositoryErrors.WithLabelValues("customer", "get")); got != 1 {
+		t.Errorf("expected 1 repository error, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.repositoryErrors.WithLabelValues("customer", "add")); got != 0 {
+		t.Errorf("expected 0 repository errors, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.repositoryDuration); got != 2 {
+		t.Errorf("expected 2 observed repository methods, got %v", got)
+	}
+}
+
+func TestMetrics_Orders(t *testing.T) {
+	m := New()
+
+	m.OrderCreated(10 * time.Millisecond)
+	m.OrderFailed(ReasonProductNotFound, time.Millisecond)
+	m.OrderFailed(ReasonProductNotFound, time.Millisecond)
+
+	if got := testutil.ToFloat64(m.ordersCreated); got != 1 {
+		t.Errorf("expected 1 order created, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.ordersFailed.WithLabelValues(ReasonProductNotFound)); got != 2 {
+		t.Errorf("expected 2 orders failed, got %v", got)
+	}
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"tavern_orders_created_total 1",
+		`tavern_orders_failed_total{reason="product_not_found"} 2`,
+		"tavern_create_order_duration_seconds_count 3",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected exposition to contain %q", want)
+		}
+	}
+}
+
+func TestMetrics_Nil(t *testing.T) {
+	var m *Metrics
+
+	// a nil *Metrics must be safe to use
+	m.OrderCreated(time.Millisecond)
+	m.OrderFailed(ReasonOther, time.Millisecond)
+	m.observeRepository("customer", "get", time.Now(), nil)
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/product"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// productRepository records latency and errors of any product.ProductRepository
+type productRepository struct {
+	next    product.ProductRepository
+	metrics *Metrics
+}
+
+func NewProductRepository(next product.ProductRepository, m *Metrics) product.ProductRepository {
+	return &productRepository{
+		next:    next,
+		metrics: m,
+	}
+}
+
+func (r *productRepository) GetAll(ctx context.Context) ([]aggregate.Product, error) {
+	start := time.Now()
+	products, err := r.next.GetAll(ctx)
+	r.metrics.observeRepository("product", "get_all", start, err)
+
+	return products, err
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Product, error) {
+	start := time.Now()
+	p, err := r.next.GetByID(ctx, id)
+	r.metrics.observeRepository("product", "get_by_id", start, err)
+
+	return p, err
+}
+
+func (r *productRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]aggregate.Product, []uuid.UUID, error) {
+	start := time.Now()
+	products, missing, err := r.next.GetByIDs(ctx, ids)
+	r.metrics.observeRepository("product", "get_by_ids", start, err)
+
+	return products, missing, err
+}
+
+func (r *productRepository) Search(ctx context.Context, query product.Query) (product.Page, error) {
+	start := time.Now()
+	page, err := r.next.Search(ctx, query)
+	r.metrics.observeRepository("product", "search", start, err)
+
+	return page, err
+}
+
+func (r *productRepository) Add(ctx context.Context, p aggregate.Product) error {
+	start := time.Now()
+	err := r.next.Add(ctx, p)
+	r.metrics.observeRepository("product", "add", start, err)
+
+	return err
+}
+
+func (r *productRepository) Update(ctx context.Context, p aggregate.Product) error {
+	start := time.Now()
+	err := r.next.Update(ctx, p)
+	r.metrics.observeRepository("product", "update", start, err)
+
+	return err
+}
+
+func (r *productRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	start := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.metrics.observeRepository("product", "delete", start, err)
+
+	return err
+}
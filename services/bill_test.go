@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	"golang-learn-ddd/domain/customer/memory"
+	"golang-learn-ddd/domain/membership"
+	"golang-learn-ddd/domain/payment/fake"
+	"golang-learn-ddd/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// stuckCustomerRepository fails to update the customer stuck, when set
+type stuckCustomerRepository struct {
+	customer.CustomerRepository
+	stuck *uuid.UUID
+}
+
+func (r stuckCustomerRepository) Update(ctx context.Context, c aggregate.Customer) error {
+	if c.GetID() == *r.stuck {
+		return customer.ErrUpdateCustomer
+	}
+
+	return r.CustomerRepository.Update(ctx, c)
+}
+
+func Test_TavernServiceBillAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer := products[0]
+
+	type testCase struct {
+		name   string
+		wallet bool
+		card   bool
+	}
+	tests := []testCase{
+		{name: "on account"},
+		{name: "from the wallets", wallet: true},
+		{name: "by card", card: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stuck := uuid.Nil
+			cfgs := []OrderConfiguration{
+				WithCustomerRepository(stuckCustomerRepository{memory.New(), &stuck}),
+				WithMemoryProductRepository(products),
+				WithMemoryOrderRepository(),
+			}
+			gateway, err := fake.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.card {
+				cfgs = append(cfgs, WithOrderPaymentGateway(gateway))
+			}
+			os, err := NewOrderService(cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tavern, err := NewTavernService(WithOrderService(os))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := map[string]uuid.UUID{}
+			for _, name := range []string{"Miku", "Rin"} {
+				c, err := aggregate.NewCustomer(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.customerRepo.Add(ctx, c); err != nil {
+					t.Fatal(err)
+				}
+				if err := tavern.Deposit(ctx, c.GetID(), 100); err != nil {
+					t.Fatal(err)
+				}
+				ids[name] = c.GetID()
+			}
+
+			opts := []OrderOption{SplitEvenly(ids["Miku"], ids["Rin"]), WithTip(1)}
+			if tc.wallet {
+				opts = append(opts, PayFromWallet())
+			}
+
+			// Miku is charged and recorded first, then Rin cannot be
+			stuck = ids["Rin"]
+			if _, err := tavern.Order(ctx, ids["Miku"], ItemsOf(beer.GetID()), opts...); !errors.Is(err, customer.ErrUpdateCustomer) {
+				t.Fatalf("expected error %v, got %v", customer.ErrUpdateCustomer, err)
+			}
+			stuck = uuid.Nil
+
+			for name, id := range ids {
+				c, err := os.customerRepo.Get(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if c.GetBalance() != 100 {
+					t.Errorf("expected %s to have a balance of 100, got %v", name, c.GetBalance())
+				}
+
+				var net int
+				for _, tr := range c.GetTransactions() {
+					switch tr.Kind() {
+					case valueobject.TransactionRefund, valueobject.TransactionTipRefund:
+						net -= tr.Amount()
+					case valueobject.TransactionPayment, valueobject.TransactionServiceCharge, valueobject.TransactionTip:
+						net += tr.Amount()
+					}
+				}
+				if net != 0 {
+					t.Errorf("expected %s to be given back every charge, %d cents are still charged", name, net)
+				}
+				// the tip given back is not taken off what was spent
+				if spend := membership.LifetimeSpend(c); spend != 0 {
+					t.Errorf("expected %s to have spent nothing, got %v", name, spend)
+				}
+
+				if tc.card && len(gateway.Payments(id)) != 1 {
+					t.Errorf("expected the card of %s to be charged once, got %+v", name, gateway.Payments(id))
+				}
+				for _, p := range gateway.Payments(id) {
+					if p.Captured != p.Refunded {
+						t.Errorf("expected the card of %s to be given back, got %+v", name, p)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/idempotency"
+
+	"github.com/google/uuid"
+)
+
+// orderOnce places the order unless its idempotency key was given before, in
+// which case the order first placed is returned. The key is reserved before
+// the order is placed so concurrent retries do not bill twice. It is freed
+// when the order fails without charging anybody, or else kept with the failure
+// so the retries do not bill again what is settled by hand.
+func (s *TavernService) orderOnce(ctx context.Context, customer uuid.UUID, items []OrderItem, req orderRequest, opts []OrderOption) (aggregate.Order, error) {
+	if s.resultRepo == nil {
+		return aggregate.Order{}, fmt.Errorf("orders are not kept by idempotency key: %w", idempotency.ErrReserveKey)
+	}
+
+	// keys are chosen by the clients, every customer has their own
+	key := fmt.Sprintf("%s:%s", customer, req.idempotencyKey)
+	fingerprint := fingerprintOrder(customer, items, req)
+
+	res, reserved, err := s.resultRepo.Reserve(ctx, key, fingerprint, s.OrderService.now())
+	if err != nil {
+		s.logger.Error("failed to reserve the idempotency key", "customer_id", customer, "key", req.idempotencyKey, "error", err)
+		return aggregate.Order{}, err
+	}
+	if !reserved {
+		switch {
+		case res.Fingerprint != fingerprint:
+			return aggregate.Order{}, idempotency.ErrKeyReused
+		case !res.Done:
+			return aggregate.Order{}, idempotency.ErrRequestInProgress
+		case res.Err != "":
+			return aggregate.Order{}, fmt.Errorf("%s: %w", res.Err, idempotency.ErrRequestFailed)
+		}
+
+		s.logger.Info("replay the order", "customer_id", customer, "key", req.idempotencyKey, "order_id", res.Order.GetID())
+		return res.Order, nil
+	}
+
+	order, err := s.order(ctx, customer, items, req, opts)
+	if err != nil {
+		if errors.Is(err, ErrNotCharged) {
+			if err := s.resultRepo.Release(ctx, key); err != nil {
+				s.logger.Error("failed to release the idempotency key", "customer_id", customer, "key", req.idempotencyKey, "error", err)
+			}
+			return aggregate.Order{}, err
+		}
+
+		res.Done = true
+		res.Err = err.Error()
+		if err := s.resultRepo.Save(ctx, res); err != nil {
+			s.logger.Error("failed to save the failure of the idempotency key", "customer_id", customer, "key", req.idempotencyKey, "error", err)
+		}
+		return aggregate.Order{}, err
+	}
+
+	// the order is billed: a result that cannot be saved keeps the key
+	// reserved until it expires, which is safer than billing a retry again
+	res.Done = true
+	res.Order = order
+	if err := s.resultRepo.Save(ctx, res); err != nil {
+		s.logger.Error("failed to save the order of the idempotency key", "customer_id", customer, "key", req.idempotencyKey, "order_id", order.GetID(), "error", err)
+	}
+
+	return order, nil
+}
+
+// fingerprintOrder identifies what is ordered and how it is billed, the way
+// the bill is split is left out
+func fingerprintOrder(customer uuid.UUID, items []OrderItem, req orderRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%v|%s|%t|%v|%t|%d|%v",
+		customer, items, req.coupon, req.takeaway, req.tip, req.wallet, req.points, req.freeItems)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	customerMemory "golang-learn-ddd/domain/customer/memory"
+	"golang-learn-ddd/domain/idempotency"
+	"golang-learn-ddd/domain/payment/fake"
+	"golang-learn-ddd/valueobject"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernServiceIdempotentOrder(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer, peanut := products[0], products[1]
+	now := time.Date(2024, 3, 1, 18, 0, 0, 0, time.UTC)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os), WithMemoryResultRepository(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Miku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// payments counts the orders billed to the customer
+	payments := func() int {
+		c, err := os.customerRepo.Get(ctx, cust.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		for _, tr := range c.GetTransactions() {
+			if tr.Kind() == valueobject.TransactionPayment {
+				n++
+			}
+		}
+		return n
+	}
+
+	// the client retries concurrently, only one order is placed
+	var wg sync.WaitGroup
+	orderIDs := make(chan uuid.UUID, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			o, err := tavern.Order(ctx, cust.GetID(), ItemsOf(beer.GetID()), WithIdempotencyKey("first"))
+			switch {
+			case err == nil:
+				orderIDs <- o.GetID()
+			case !errors.Is(err, idempotency.ErrRequestInProgress):
+				t.Errorf("expected the order or error %v, got %v", idempotency.ErrRequestInProgress, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(orderIDs)
+	if payments() != 1 {
+		t.Fatalf("expected the customer to be billed once, got %d", payments())
+	}
+
+	first, err := tavern.Order(ctx, cust.GetID(), ItemsOf(beer.GetID()), WithIdempotencyKey("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := range orderIDs {
+		if id != first.GetID() {
+			t.Errorf("expected the retries to get order %s, got %s", first.GetID(), id)
+		}
+	}
+
+	if _, err := tavern.Order(ctx, cust.GetID(), ItemsOf(peanut.GetID()), WithIdempotencyKey("first")); !errors.Is(err, idempotency.ErrKeyReused) {
+		t.Errorf("expected error %v, got %v", idempotency.ErrKeyReused, err)
+	}
+
+	// a failed order bills nobody, so it can be retried with its key
+	if _, err := tavern.Order(ctx, cust.GetID(), ItemsOf(peanut.GetID()), PayFromWallet(), WithIdempotencyKey("wallet")); !errors.Is(err, aggregate.ErrInsufficientFunds) {
+		t.Errorf("expected error %v, got %v", aggregate.ErrInsufficientFunds, err)
+	}
+	if err := tavern.Deposit(ctx, cust.GetID(), 20); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.Order(ctx, cust.GetID(), ItemsOf(peanut.GetID()), PayFromWallet(), WithIdempotencyKey("wallet")); err != nil {
+		t.Fatal(err)
+	}
+	if payments() != 2 {
+		t.Errorf("expected the customer to be billed twice, got %d", payments())
+	}
+
+	// once expired, the key places a new order
+	now = now.Add(time.Hour)
+	again, err := tavern.Order(ctx, cust.GetID(), ItemsOf(beer.GetID()), WithIdempotencyKey("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.GetID() == first.GetID() || payments() != 3 {
+		t.Errorf("expected a new order once the key expired, got order %s and %d payments", again.GetID(), payments())
+	}
+
+	// keys belong to their customer
+	other, err := aggregate.NewCustomer("Rin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+	o, err := tavern.Order(ctx, other.GetID(), ItemsOf(peanut.GetID()), WithIdempotencyKey("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.GetCustomerID() != other.GetID() {
+		t.Errorf("expected an order of %s, got one of %s", other.GetID(), o.GetCustomerID())
+	}
+
+	unkept, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unkept.Order(ctx, cust.GetID(), ItemsOf(beer.GetID()), WithIdempotencyKey("first")); !errors.Is(err, idempotency.ErrReserveKey) {
+		t.Errorf("expected error %v, got %v", idempotency.ErrReserveKey, err)
+	}
+}
+
+// brokenCustomerRepository fails to update the customer stuck, and takes the
+// payment gateway down with it when down is set
+type brokenCustomerRepository struct {
+	customer.CustomerRepository
+	stuck   *uuid.UUID
+	gateway *fake.Gateway
+	down    bool
+}
+
+func (r brokenCustomerRepository) Update(ctx context.Context, c aggregate.Customer) error {
+	if c.GetID() == *r.stuck {
+		if r.down {
+			r.gateway.FailNext(maxGatewayAttempts)
+		}
+		return customer.ErrUpdateCustomer
+	}
+
+	return r.CustomerRepository.Update(ctx, c)
+}
+
+func Test_TavernServiceIdempotentSplitOrder(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer := products[0]
+
+	type testCase struct {
+		name string
+		// down takes the gateway down when the second payer fails, so the
+		// first cannot be refunded
+		down bool
+		// charges is how many times the card of the first payer is charged
+		charges     int
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:        "given back, the key is free again",
+			charges:     2,
+			expectedErr: nil,
+		},
+		{
+			name:        "held, the key keeps the failure",
+			down:        true,
+			charges:     1,
+			expectedErr: idempotency.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway, err := fake.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			stuck := uuid.Nil
+			os, err := NewOrderService(
+				WithCustomerRepository(brokenCustomerRepository{customerMemory.New(), &stuck, gateway, tc.down}),
+				WithMemoryProductRepository(products),
+				WithOrderPaymentGateway(gateway),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tavern, err := NewTavernService(WithOrderService(os), WithMemoryResultRepository(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := map[string]uuid.UUID{}
+			for _, name := range []string{"Miku", "Rin"} {
+				c, err := aggregate.NewCustomer(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.customerRepo.Add(ctx, c); err != nil {
+					t.Fatal(err)
+				}
+				ids[name] = c.GetID()
+			}
+
+			order := func() error {
+				_, err := tavern.Order(ctx, ids["Miku"], ItemsOf(beer.GetID()), SplitEvenly(ids["Miku"], ids["Rin"]), WithIdempotencyKey("split"))
+				return err
+			}
+
+			// the second payer is not recorded
+			stuck = ids["Rin"]
+			err = order()
+			if errors.Is(err, ErrNotCharged) == tc.down {
+				t.Fatalf("expected the failure to leave the bill charged: %t, got %v", tc.down, err)
+			}
+
+			// the retry is billed only when nothing was charged
+			stuck = uuid.Nil
+			if err := order(); !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+
+			var charges int
+			for _, p := range gateway.Payments(ids["Miku"]) {
+				if p.Captured > 0 {
+					charges++
+				}
+			}
+			if charges != tc.charges {
+				t.Errorf("expected %d charges of the first payer, got %d", tc.charges, charges)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package services
+
+// Logger is the subset of *slog.Logger the services rely on, so a
+// *slog.Logger (or any compatible logger) can be injected directly.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// noopLogger discards everything, it is the default for every service
+type noopLogger struct{}
+
+func (noopLogger) Debug(msg string, args ...any) {}
+func (noopLogger) Info(msg string, args ...any)  {}
+func (noopLogger) Warn(msg string, args ...any)  {}
+func (noopLogger) Error(msg string, args ...any) {}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/loyalty"
+	"golang-learn-ddd/domain/payment"
+	"golang-learn-ddd/domain/payment/fake"
+	"golang-learn-ddd/domain/tab"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernServiceLoyalty(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	peanut, bakso := products[1], products[2]
+
+	now := time.Date(2023, time.June, 2, 12, 0, 0, 0, time.UTC)
+
+	// a point per unit spent, each point is worth 0.10, points last 30 days
+	program, err := loyalty.NewProgram(1, 0.1, loyalty.WithValidity(30*24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithLoyaltyProgram(program),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Meiko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	points := func() int {
+		t.Helper()
+		c, err := os.customerRepo.Get(ctx, cust.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c.GetPoints(now)
+	}
+
+	// 8 x 14 earns 112 points
+	for i := 0; i < 8; i++ {
+		if _, err := tavern.Order(ctx, cust.GetID(), ItemsOf(peanut.GetID(), bakso.GetID())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := points(); got != 112 {
+		t.Fatalf("expected %d points, got %d", 112, got)
+	}
+
+	// a free bakso costs 15 points, 50 points take 5.00 off the peanuts
+	order, err := os.CreateOrder(ctx, cust.GetID(), ItemsOf(peanut.GetID(), bakso.GetID()),
+		RedeemForItem(bakso.GetID()),
+		RedeemPoints(50),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTotal() != 7.5 || order.GetPointsRedeemed() != 50 {
+		t.Errorf("expected total 7.5 for 50 points, got %v for %d", order.GetTotal(), order.GetPointsRedeemed())
+	}
+	if got := points(); got != 47 {
+		t.Errorf("expected %d points, got %d", 47, got)
+	}
+
+	// only the points needed to pay the order are spent
+	order, err = os.CreateOrder(ctx, cust.GetID(), ItemsOf(bakso.GetID()), RedeemPoints(40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTotal() != 0 || order.GetPointsRedeemed() != 15 {
+		t.Errorf("expected a free order for 15 points, got %v for %d", order.GetTotal(), order.GetPointsRedeemed())
+	}
+
+	if _, err := os.CreateOrder(ctx, cust.GetID(), ItemsOf(peanut.GetID()), RedeemPoints(100)); !errors.Is(err, aggregate.ErrInsufficientPoints) {
+		t.Errorf("expected error %v, got %v", aggregate.ErrInsufficientPoints, err)
+	}
+	if _, err := os.CreateOrder(ctx, cust.GetID(), ItemsOf(peanut.GetID()), RedeemForItem(bakso.GetID())); !errors.Is(err, ErrRedemptionRejected) {
+		t.Errorf("expected error %v, got %v", ErrRedemptionRejected, err)
+	}
+
+	// points expire after 30 days
+	now = now.Add(31 * 24 * time.Hour)
+	if got := points(); got != 0 {
+		t.Errorf("expected the points to expire, got %d", got)
+	}
+}
+
+func Test_TavernServiceUnbilledRedemptions(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer, peanut, bakso := products[0], products[1], products[2]
+
+	type testCase struct {
+		name string
+		// order places the order redeeming the coupon and points, the
+		// customers are Meiko with 112 points and Kaito
+		order       func(tavern *TavernService, gateway *fake.Gateway, ids map[string]uuid.UUID) error
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name: "points short after the coupon",
+			order: func(tavern *TavernService, gateway *fake.Gateway, ids map[string]uuid.UUID) error {
+				_, err := tavern.OrderService.CreateOrder(ctx, ids["Meiko"], ItemsOf(beer.GetID()), WithCoupon("ONCE"), RedeemPoints(500))
+				return err
+			},
+			expectedErr: aggregate.ErrInsufficientPoints,
+		},
+		{
+			name: "wallet short",
+			order: func(tavern *TavernService, gateway *fake.Gateway, ids map[string]uuid.UUID) error {
+				_, err := tavern.Order(ctx, ids["Meiko"], ItemsOf(beer.GetID()), WithCoupon("ONCE"), RedeemPoints(20), PayFromWallet())
+				return err
+			},
+			expectedErr: aggregate.ErrInsufficientFunds,
+		},
+		{
+			name: "card declined",
+			order: func(tavern *TavernService, gateway *fake.Gateway, ids map[string]uuid.UUID) error {
+				if err := fake.DeclineCustomers(ids["Meiko"])(gateway); err != nil {
+					t.Fatal(err)
+				}
+				_, err := tavern.Order(ctx, ids["Meiko"], ItemsOf(beer.GetID()), WithCoupon("ONCE"), RedeemPoints(20))
+				return err
+			},
+			expectedErr: payment.ErrPaymentDeclined,
+		},
+		{
+			name: "invalid split",
+			order: func(tavern *TavernService, gateway *fake.Gateway, ids map[string]uuid.UUID) error {
+				_, err := tavern.Order(ctx, ids["Meiko"], ItemsOf(beer.GetID()), WithCoupon("ONCE"), RedeemPoints(20), SplitEvenly(ids["Meiko"], ids["Meiko"]))
+				return err
+			},
+			expectedErr: ErrInvalidSplit,
+		},
+		{
+			name: "tab not updated",
+			order: func(tavern *TavernService, gateway *fake.Gateway, ids map[string]uuid.UUID) error {
+				opened, err := tavern.OpenTab(ctx, ids["Meiko"], "7")
+				if err != nil {
+					t.Fatal(err)
+				}
+				tavern.tabRepo = stuckTabRepository{tavern.tabRepo}
+				_, err = tavern.AddToTab(ctx, opened.GetID(), ItemsOf(beer.GetID()), WithCoupon("ONCE"), RedeemPoints(20))
+				return err
+			},
+			expectedErr: tab.ErrUpdateTab,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			program, err := loyalty.NewProgram(1, 0.1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			once, err := aggregate.NewPercentageOff("ONCE", 10, aggregate.WithUsageLimit(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			gateway, err := fake.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			os, err := NewOrderService(
+				WithMemoryCustomerRepository(),
+				WithMemoryProductRepository(products),
+				WithMemoryPromotionRepository([]aggregate.Promotion{once}),
+				WithLoyaltyProgram(program),
+				WithOrderPaymentGateway(gateway),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tavern, err := NewTavernService(WithOrderService(os), WithMemoryTabRepository())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := map[string]uuid.UUID{}
+			for _, name := range []string{"Meiko", "Kaito"} {
+				c, err := aggregate.NewCustomer(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.customerRepo.Add(ctx, c); err != nil {
+					t.Fatal(err)
+				}
+				ids[name] = c.GetID()
+			}
+
+			// 8 x 14 earns 112 points
+			for i := 0; i < 8; i++ {
+				if _, err := tavern.Order(ctx, ids["Meiko"], ItemsOf(peanut.GetID(), bakso.GetID())); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := tc.order(tavern, gateway, ids); !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+
+			c, err := os.customerRepo.Get(ctx, ids["Meiko"])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.GetPoints(os.now()); got != 112 {
+				t.Errorf("expected the points to be kept, got %d", got)
+			}
+
+			// the coupon is still there for another order
+			if _, err := os.CreateOrder(ctx, ids["Kaito"], ItemsOf(beer.GetID()), WithCoupon("ONCE")); err != nil {
+				t.Errorf("expected the coupon to be unused, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/category"
+	"golang-learn-ddd/domain/product"
+
+	"github.com/google/uuid"
+)
+
+type MenuConfiguration func(ms *MenuService) error
+
+// MenuService renders the products grouped by category
+type MenuService struct {
+	categoryRepo category.CategoryRepository
+	productRepo  product.ProductRepository
+}
+
+// MenuSection is one category of the menu with its products sorted by name
+type MenuSection struct {
+	Category aggregate.Category
+	Products []aggregate.Product
+}
+
+func NewMenuService(cfgs ...MenuConfiguration) (*MenuService, error) {
+	ms := &MenuService{}
+
+	for _, cfg := range cfgs {
+		if err := cfg(ms); err != nil {
+			return nil, err
+		}
+	}
+
+	return ms, nil
+}
+
+func WithCategoryRepository(categoryRepo category.CategoryRepository) MenuConfiguration {
+	return func(ms *MenuService) error {
+		ms.categoryRepo = categoryRepo
+		return nil
+	}
+}
+
+func WithMenuProductRepository(productRepo product.ProductRepository) MenuConfiguration {
+	return func(ms *MenuService) error {
+		ms.productRepo = productRepo
+		return nil
+	}
+}
+
+// Menu returns a section per category, in category order, skipping empty ones.
+// Products without a known category are listed last in an "Other" section.
+// A query for a category only returns its section.
+func (ms *MenuService) Menu(ctx context.Context, query product.Query) ([]MenuSection, error) {
+	categories, err := ms.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the menu shows everything matching, sections are built from a single search
+	query.Offset, query.Limit = 0, 0
+
+	page, err := ms.productRepo.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := map[uuid.UUID][]aggregate.Product{}
+	for _, p := range page.Products {
+		byCategory[p.GetCategoryID()] = append(byCategory[p.GetCategoryID()], p)
+	}
+
+	var sections []MenuSection
+	for _, c := range categories {
+		if products, ok := byCategory[c.GetID()]; ok {
+			sections = append(sections, MenuSection{Category: c, Products: products})
+			delete(byCategory, c.GetID())
+		}
+	}
+
+	var others []aggregate.Product
+	for _, p := range page.Products {
+		if _, ok := byCategory[p.GetCategoryID()]; ok {
+			others = append(others, p)
+		}
+	}
+
+	if len(others) > 0 {
+		other, err := aggregate.NewCategory("Other", 0)
+		if err != nil {
+			return nil, err
+		}
+		other.SetID(uuid.Nil)
+
+		sections = append(sections, MenuSection{Category: other, Products: others})
+	}
+
+	return sections, nil
+}
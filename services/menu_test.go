@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/category"
+	categoryMemory "golang-learn-ddd/domain/category/memory"
+	"golang-learn-ddd/domain/product"
+	productMemory "golang-learn-ddd/domain/product/memory"
+	"reflect"
+	"testing"
+)
+
+func Test_MenuService(t *testing.T) {
+	ctx := context.Background()
+
+	categoryRepo := categoryMemory.New()
+	productRepo := productMemory.New()
+
+	drinks, _ := aggregate.NewCategory("Drinks", 1)
+	food, _ := aggregate.NewCategory("Food", 2)
+	snacks, _ := aggregate.NewCategory("Snacks", 3)
+	for _, c := range []aggregate.Category{snacks, food, drinks} {
+		if err := categoryRepo.Add(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []struct {
+		name string
+		cfgs []aggregate.ProductConfiguration
+	}{
+		{"Beer", []aggregate.ProductConfiguration{aggregate.WithCategory(drinks.GetID()), aggregate.WithTags("Cold")}},
+		{"Bakso Kuah", []aggregate.ProductConfiguration{aggregate.WithCategory(food.GetID()), aggregate.WithTags("spicy", "hot")}},
+		{"Ale", []aggregate.ProductConfiguration{aggregate.WithCategory(drinks.GetID())}},
+		{"Mystery Box", nil},
+	} {
+		prod, err := aggregate.NewProduct(p.name, p.name, 1, p.cfgs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := productRepo.Add(ctx, prod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ms, err := NewMenuService(
+		WithCategoryRepository(categoryRepo),
+		WithMenuProductRepository(productRepo),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tavern, err := NewTavernService(WithMenuService(ms))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testCase struct {
+		name     string
+		query    product.Query
+		expected map[string][]string
+		sections []string
+	}
+	tests := []testCase{
+		{
+			name:     "full menu, snacks is empty",
+			query:    product.Query{},
+			sections: []string{"Drinks", "Food", "Other"},
+			expected: map[string][]string{
+				"Drinks": {"Ale", "Beer"},
+				"Food":   {"Bakso Kuah"},
+				"Other":  {"Mystery Box"},
+			},
+		},
+		{
+			name:     "one category",
+			query:    product.Query{CategoryID: drinks.GetID()},
+			sections: []string{"Drinks"},
+			expected: map[string][]string{
+				"Drinks": {"Ale", "Beer"},
+			},
+		},
+		{
+			name:     "filtered by tag",
+			query:    product.Query{Tags: []string{"SPICY"}},
+			sections: []string{"Food"},
+			expected: map[string][]string{
+				"Food": {"Bakso Kuah"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			menu, err := tavern.Menu(ctx, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var sections []string
+			got := map[string][]string{}
+			for _, section := range menu {
+				sections = append(sections, section.Category.GetName())
+				for _, p := range section.Products {
+					got[section.Category.GetName()] = append(got[section.Category.GetName()], p.GetItem().Name)
+				}
+			}
+
+			if !reflect.DeepEqual(sections, tt.sections) {
+				t.Errorf("expected sections %v, got %v", tt.sections, sections)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected menu %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	unlisted, err := NewTavernService()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unlisted.Menu(ctx, product.Query{}); !errors.Is(err, category.ErrCategoryNotFound) {
+		t.Errorf("expected error %v, got %v", category.ErrCategoryNotFound, err)
+	}
+}
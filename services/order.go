@@ -2,26 +2,159 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"golang-learn-ddd/aggregate"
 	"golang-learn-ddd/domain/customer"
 	customerMemory "golang-learn-ddd/domain/customer/memory"
 	customerMongo "golang-learn-ddd/domain/customer/mongo"
+	"golang-learn-ddd/domain/loyalty"
+	"golang-learn-ddd/domain/membership"
+	"golang-learn-ddd/domain/order"
+	orderMemory "golang-learn-ddd/domain/order/memory"
+	orderMongo "golang-learn-ddd/domain/order/mongo"
+	"golang-learn-ddd/domain/payment"
+	"golang-learn-ddd/domain/policy"
+	"golang-learn-ddd/domain/pricing"
 	"golang-learn-ddd/domain/product"
 	productMemory "golang-learn-ddd/domain/product/memory"
+	productMongo "golang-learn-ddd/domain/product/mongo"
+	"golang-learn-ddd/domain/promotion"
+	promotionMemory "golang-learn-ddd/domain/promotion/memory"
+	promotionMongo "golang-learn-ddd/domain/promotion/mongo"
+	"golang-learn-ddd/domain/tax"
+	"golang-learn-ddd/metrics"
+	"golang-learn-ddd/tracing"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var (
+	ErrCouponRejected     = errors.New("coupon code rejected")
+	ErrRedemptionRejected = errors.New("loyalty points redemption rejected")
 )
 
 type OrderConfiguration func(os *OrderService) error
 
+// OrderOption sets optional details of a single order
+type OrderOption func(r *orderRequest)
+
+type orderRequest struct {
+	coupon   string
+	takeaway bool
+	tip      float64
+	split    splitter
+	wallet   bool
+
+	// points and freeItems are loyalty points redeemed for a discount or free products
+	points    int
+	freeItems []uuid.UUID
+
+	// idempotencyKey identifies the order across the retries of the client
+	idempotencyKey string
+}
+
+// WithCoupon applies a promotion code to the order
+func WithCoupon(code string) OrderOption {
+	return func(r *orderRequest) {
+		r.coupon = code
+	}
+}
+
+// AsTakeaway marks the order as taken away, which may waive the service charge
+func AsTakeaway() OrderOption {
+	return func(r *orderRequest) {
+		r.takeaway = true
+	}
+}
+
+// RedeemPoints takes the value of the loyalty points off the order, only the
+// points needed are spent when they are worth more than the order
+func RedeemPoints(points int) OrderOption {
+	return func(r *orderRequest) {
+		r.points = points
+	}
+}
+
+// RedeemForItem spends loyalty points to get one unit of the ordered product for free
+func RedeemForItem(productID uuid.UUID) OrderOption {
+	return func(r *orderRequest) {
+		r.freeItems = append(r.freeItems, productID)
+	}
+}
+
+// PayFromWallet charges the bill to the prepaid credit of the payers,
+// billing fails with aggregate.ErrInsufficientFunds when it is too low
+func PayFromWallet() OrderOption {
+	return func(r *orderRequest) {
+		r.wallet = true
+	}
+}
+
+// WithTip adds a tip for the staff when the order is billed
+func WithTip(amount float64) OrderOption {
+	return func(r *orderRequest) {
+		r.tip = amount
+	}
+}
+
+// WithIdempotencyKey makes the retries of an order with the same key get the
+// order first placed instead of billing again, see TavernService.Order
+func WithIdempotencyKey(key string) OrderOption {
+	return func(r *orderRequest) {
+		r.idempotencyKey = key
+	}
+}
+
+// OrderItem is one unit of a product to order, in an optional variant and with optional modifiers
+type OrderItem struct {
+	ProductID   uuid.UUID
+	VariantID   uuid.UUID
+	ModifierIDs []uuid.UUID
+}
+
+// ItemsOf orders one unit of each product, without variant nor modifiers
+func ItemsOf(productIDs ...uuid.UUID) []OrderItem {
+	items := make([]OrderItem, 0, len(productIDs))
+	for _, id := range productIDs {
+		items = append(items, OrderItem{ProductID: id})
+	}
+
+	return items
+}
+
 type OrderService struct {
 	customerRepo customer.CustomerRepository
 	productRepo  product.ProductRepository
+
+	logger  Logger
+	metrics *metrics.Metrics
+	tracer  trace.Tracer
+
+	now     func() time.Time
+	pricing *pricing.Engine
+
+	promotionRepo promotion.PromotionRepository
+	tax           tax.Policy
+	loyalty       *loyalty.Program
+	membership    *membership.Evaluator
+
+	// orderRepo keeps the paid orders, they cannot be refunded without it
+	orderRepo order.OrderRepository
+	// gateway charges the cards of the customers, bills are only recorded without it
+	gateway payment.PaymentGateway
 }
 
 func NewOrderService(cfgs ...OrderConfiguration) (*OrderService, error) {
-	os := &OrderService{}
+	os := &OrderService{
+		logger: noopLogger{},
+		tracer: noop.NewTracerProvider().Tracer(tracing.InstrumentationName),
+		now:    time.Now,
+	}
 
 	// Loop through all cfgs and apply them
 	for _, cfg := range cfgs {
@@ -65,7 +198,7 @@ func WithMemoryProductRepository(products []aggregate.Product) OrderConfiguratio
 		repo := productMemory.New()
 
 		for _, p := range products {
-			if err := repo.Add(p); err != nil {
+			if err := repo.Add(context.Background(), p); err != nil {
 				return err
 			}
 		}
@@ -75,31 +208,517 @@ func WithMemoryProductRepository(products []aggregate.Product) OrderConfiguratio
 	}
 }
 
-func (os *OrderService) CreateOrder(customerID uuid.UUID, productsIDs []uuid.UUID) (float64, error) {
+func WithMongoProductRepository(ctx context.Context, connectionString string) OrderConfiguration {
+	return func(os *OrderService) error {
+		repo, err := productMongo.New(ctx, connectionString)
+		if err != nil {
+			return err
+		}
+
+		os.productRepo = repo
+
+		return nil
+	}
+}
+
+func WithProductRepository(productRepo product.ProductRepository) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.productRepo = productRepo
+		return nil
+	}
+}
+
+func WithLogger(logger Logger) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.logger = logger
+		return nil
+	}
+}
+
+// WithMetrics records orders created/failed and CreateOrder latency,
+// wrap the repositories with metrics.NewCustomerRepository and
+// metrics.NewProductRepository to instrument them as well
+func WithMetrics(m *metrics.Metrics) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.metrics = m
+		return nil
+	}
+}
+
+// WithTracer traces CreateOrder, wrap the repositories with
+// tracing.NewCustomerRepository and tracing.NewProductRepository
+// to get a span for each lookup as well
+func WithTracer(tracer trace.Tracer) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.tracer = tracer
+		return nil
+	}
+}
+
+func WithMemoryPromotionRepository(promotions []aggregate.Promotion) OrderConfiguration {
+	return func(os *OrderService) error {
+		repo := promotionMemory.New()
+
+		for _, p := range promotions {
+			if err := repo.Add(context.Background(), p); err != nil {
+				return err
+			}
+		}
+
+		os.promotionRepo = repo
+		return nil
+	}
+}
+
+func WithMongoPromotionRepository(ctx context.Context, connectionString string) OrderConfiguration {
+	return func(os *OrderService) error {
+		repo, err := promotionMongo.New(ctx, connectionString)
+		if err != nil {
+			return err
+		}
+
+		os.promotionRepo = repo
+
+		return nil
+	}
+}
+
+func WithPromotionRepository(promotionRepo promotion.PromotionRepository) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.promotionRepo = promotionRepo
+		return nil
+	}
+}
+
+// WithClock replaces time.Now, the time of an order decides its prices and discounts
+func WithClock(now func() time.Time) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.now = now
+		return nil
+	}
+}
+
+// WithPricingRules discounts order lines, the best matching rule applies to each line
+func WithPricingRules(rules ...pricing.Rule) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.pricing = pricing.NewEngine(rules...)
+		return nil
+	}
+}
+
+// WithTaxPolicy adds taxes to orders, after their discounts and coupon
+func WithTaxPolicy(policy tax.Policy) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.tax = policy
+		return nil
+	}
+}
+
+// WithLoyaltyProgram lets customers redeem loyalty points when ordering,
+// TavernService credits the points earned once orders are paid
+func WithLoyaltyProgram(program *loyalty.Program) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.loyalty = program
+		return nil
+	}
+}
+
+// WithMembership gives orders the discount and the kitchen priority of the
+// tier of their customer, TavernService re-evaluates the tier on every bill
+func WithMembership(evaluator *membership.Evaluator) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.membership = evaluator
+		return nil
+	}
+}
+
+func WithMemoryOrderRepository() OrderConfiguration {
+	return WithOrderRepository(orderMemory.New())
+}
+
+func WithMongoOrderRepository(ctx context.Context, connectionString string) OrderConfiguration {
+	return func(os *OrderService) error {
+		repo, err := orderMongo.New(ctx, connectionString)
+		if err != nil {
+			return err
+		}
+
+		os.orderRepo = repo
+
+		return nil
+	}
+}
+
+// WithOrderRepository keeps the orders once TavernService bills them, so they can be refunded
+func WithOrderRepository(orderRepo order.OrderRepository) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.orderRepo = orderRepo
+		return nil
+	}
+}
+
+// WithOrderPaymentGateway refunds card payments back to the cards, taverns
+// without a gateway of their own charge the bills through it as well
+func WithOrderPaymentGateway(gateway payment.PaymentGateway) OrderConfiguration {
+	return func(os *OrderService) error {
+		os.gateway = gateway
+		return nil
+	}
+}
+
+func (os *OrderService) CreateOrder(ctx context.Context, customerID uuid.UUID, items []OrderItem, opts ...OrderOption) (aggregate.Order, error) {
+	req := orderRequest{}
+	for _, opt := range opts {
+		opt(&req)
+	}
+
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, os.tracer, "OrderService.CreateOrder",
+		attribute.Stringer("customer.id", customerID),
+		attribute.Int("product.count", len(items)),
+	)
+
+	order, err := os.createOrder(ctx, customerID, items, req)
+	tracing.End(span, err)
+	if err != nil {
+		os.metrics.OrderFailed(failureReason(err), time.Since(start))
+		return aggregate.Order{}, err
+	}
+
+	os.metrics.OrderCreated(time.Since(start))
+
+	return order, nil
+}
+
+func (os *OrderService) createOrder(ctx context.Context, customerID uuid.UUID, items []OrderItem, req orderRequest) (aggregate.Order, error) {
+	now := os.now()
+
+	productsIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		productsIDs = append(productsIDs, item.ProductID)
+	}
+
 	// Fetch the customer
-	c, err := os.customerRepo.Get(customerID)
+	c, err := os.customerRepo.Get(ctx, customerID)
+	if err != nil {
+		os.logger.Error("failed to fetch customer", "customer_id", customerID, "error", err)
+		return aggregate.Order{}, err
+	}
+
+	// Get all products at once, reporting every missing one
+	products, missing, err := os.productRepo.GetByIDs(ctx, productsIDs)
+	if err != nil {
+		os.logger.Error("failed to fetch products", "customer_id", customerID, "product_ids", productsIDs, "error", err)
+		return aggregate.Order{}, err
+	}
+	if len(missing) > 0 {
+		err := &product.MissingProductsError{IDs: missing}
+		os.logger.Error("failed to fetch products", "customer_id", customerID, "missing_product_ids", missing, "error", err)
+		return aggregate.Order{}, err
+	}
+
+	if err := policy.CheckAgeRestriction(c, products, now); err != nil {
+		os.logger.Error("order rejected", "customer_id", customerID, "product_ids", productsIDs, "error", err)
+		return aggregate.Order{}, err
+	}
+
+	// products are returned in the order of the items
+	lines := make([]aggregate.OrderLine, 0, len(items))
+	for i, p := range products {
+		price, err := p.PriceFor(now, items[i].VariantID, items[i].ModifierIDs)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", p.GetItem().Name, err)
+			os.logger.Error("order rejected", "customer_id", customerID, "product_id", p.GetID(), "error", err)
+			return aggregate.Order{}, err
+		}
+
+		line := aggregate.OrderLine{
+			ProductID:   p.GetID(),
+			VariantID:   items[i].VariantID,
+			ModifierIDs: items[i].ModifierIDs,
+			CategoryID:  p.GetCategoryID(),
+			Name:        p.GetItem().Name,
+			UnitPrice:   price,
+		}
+		os.pricing.Apply(&line, p, now)
+
+		lines = append(lines, line)
+	}
+
+	freePoints, err := os.freeItems(lines, req.freeItems)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrRedemptionRejected, err)
+		os.logger.Error("order rejected", "customer_id", customerID, "error", err)
+		return aggregate.Order{}, err
+	}
+
+	order, err := aggregate.NewOrder(c.GetID(), lines, now)
+	if err != nil {
+		os.logger.Error("order rejected", "customer_id", customerID, "error", err)
+		return aggregate.Order{}, err
+	}
+
+	if os.membership != nil {
+		benefits := os.membership.Benefits(membership.Tier(c.GetTier()))
+		if benefits.Discount > 0 {
+			order.ApplyTierDiscount(c.GetTier(), order.GetSubtotal()*benefits.Discount/100)
+		}
+		order.SetPriority(benefits.Priority)
+	}
+
+	// the coupon and the points are checked before either is redeemed, so a
+	// rejected order spends neither
+	var promo aggregate.Promotion
+	if req.coupon != "" {
+		promo, err = os.applyCoupon(ctx, &order, req.coupon, now)
+		if err != nil {
+			err = fmt.Errorf("%w %q: %w", ErrCouponRejected, req.coupon, err)
+			os.logger.Error("order rejected", "customer_id", customerID, "coupon", req.coupon, "error", err)
+			return aggregate.Order{}, err
+		}
+	}
+
+	var points int
+	if freePoints > 0 || req.points > 0 {
+		points, err = os.applyPoints(&order, freePoints, req.points)
+		if err == nil && c.GetPoints(now) < points {
+			err = aggregate.ErrInsufficientPoints
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: %w", ErrRedemptionRejected, err)
+			os.logger.Error("order rejected", "customer_id", customerID, "points", req.points, "error", err)
+			return aggregate.Order{}, err
+		}
+	}
+
+	if req.coupon != "" {
+		err := os.updatePromotion(ctx, promo, func(p *aggregate.Promotion) error {
+			return p.Redeem(customerID)
+		})
+		if err != nil {
+			err = fmt.Errorf("%w %q: %w", ErrCouponRejected, req.coupon, err)
+			os.logger.Error("order rejected", "customer_id", customerID, "coupon", req.coupon, "error", err)
+			return aggregate.Order{}, err
+		}
+	}
+
+	if points > 0 {
+		err := os.updateCustomer(ctx, customerID, func(c *aggregate.Customer) error {
+			return c.RedeemPoints(points, order.GetID(), now)
+		})
+		if err != nil {
+			os.releaseRedemptions(ctx, order)
+			err = fmt.Errorf("%w: %w", ErrRedemptionRejected, err)
+			os.logger.Error("order rejected", "customer_id", customerID, "points", req.points, "error", err)
+			return aggregate.Order{}, err
+		}
+	}
+
+	if os.tax != nil {
+		os.tax.Apply(&order)
+	}
+
+	os.logger.Info("order created",
+		"order_id", order.GetID(),
+		"customer_id", c.GetID(),
+		"product_ids", productsIDs,
+		"products", len(products),
+		"coupon", order.GetCoupon(),
+		"points_redeemed", order.GetPointsRedeemed(),
+		"discount", order.GetDiscount(),
+		"tax", order.GetTax(),
+		"total", order.GetTotal(),
+	)
+
+	return order, nil
+}
+
+// applyCoupon discounts the order with the promotion, whose use is recorded
+// once the order is accepted
+func (os *OrderService) applyCoupon(ctx context.Context, order *aggregate.Order, code string, at time.Time) (aggregate.Promotion, error) {
+	if os.promotionRepo == nil {
+		return aggregate.Promotion{}, promotion.ErrPromotionNotFound
+	}
+
+	promo, err := os.promotionRepo.GetByCode(ctx, code)
+	if err != nil {
+		return aggregate.Promotion{}, err
+	}
+
+	discount, err := promo.Discount(*order, at)
 	if err != nil {
-		return 0, err
+		return aggregate.Promotion{}, err
+	}
+
+	order.ApplyCoupon(promo.GetCode(), discount)
+
+	return promo, nil
+}
+
+// freeItems makes one line of each product free, the most expensive one,
+// and returns the loyalty points it costs
+func (os *OrderService) freeItems(lines []aggregate.OrderLine, productIDs []uuid.UUID) (int, error) {
+	if len(productIDs) == 0 {
+		return 0, nil
+	}
+	if os.loyalty == nil {
+		return 0, loyalty.ErrInvalidProgram
+	}
+
+	var points int
+	for _, productID := range productIDs {
+		free := -1
+		for i, l := range lines {
+			if l.ProductID == productID && l.Total() > 0 && (free < 0 || l.Total() > lines[free].Total()) {
+				free = i
+			}
+		}
+		if free < 0 {
+			return 0, fmt.Errorf("product %s is not part of the order", productID)
+		}
+
+		points += os.loyalty.PointsFor(lines[free].Total())
+		lines[free].Discount = lines[free].UnitPrice
+		lines[free].Rule = "loyalty points"
+	}
+
+	return points, nil
+}
+
+// applyPoints discounts the order with the points redeemed, the discount never
+// goes beyond what is left to pay. It returns every point to spend, with the
+// points of the free items.
+func (os *OrderService) applyPoints(order *aggregate.Order, freePoints, points int) (int, error) {
+	if os.loyalty == nil {
+		return 0, loyalty.ErrInvalidProgram
+	}
+	if points < 0 {
+		return 0, aggregate.ErrInvalidPoints
+	}
+
+	if points > 0 {
+		discount := os.loyalty.Value(points)
+		if due := aggregate.RoundMoney(order.GetSubtotal() - order.GetOrderDiscount()); discount > due {
+			discount = due
+			points = os.loyalty.PointsFor(due)
+		}
+		if points > 0 {
+			order.ApplyPoints(points, discount)
+		}
+	}
+
+	return freePoints + points, nil
+}
+
+// releaseRedemptions takes back the coupon and the points redeemed for an
+// order that is never paid. Failures are only logged, the order fails anyway.
+func (os *OrderService) releaseRedemptions(ctx context.Context, order aggregate.Order) {
+	customerID := order.GetCustomerID()
+
+	if order.GetCoupon() != "" {
+		promo, err := os.promotionRepo.GetByCode(ctx, order.GetCoupon())
+		if err == nil {
+			err = os.updatePromotion(ctx, promo, func(p *aggregate.Promotion) error {
+				p.Unredeem(customerID)
+				return nil
+			})
+		}
+		if err != nil {
+			os.logger.Error("failed to take back the coupon", "order_id", order.GetID(), "customer_id", customerID, "coupon", order.GetCoupon(), "error", err)
+		}
+	}
+
+	err := os.updateCustomer(ctx, customerID, func(c *aggregate.Customer) error {
+		return c.RestorePoints(order.GetID(), os.now())
+	})
+	if err != nil && !errors.Is(err, aggregate.ErrInvalidPoints) {
+		os.logger.Error("failed to restore the points", "order_id", order.GetID(), "customer_id", customerID, "error", err)
 	}
+}
 
-	// Get each product
-	var products []aggregate.Product
-	var total float64
+// maxUpdateAttempts bounds the retries of an update racing with others
+const maxUpdateAttempts = 5
 
-	for _, id := range productsIDs {
-		p, err := os.productRepo.GetByID(id)
+// update gets the latest version of an aggregate, applies change to it and
+// stores it. Whenever the store fails with conflict because another update
+// got there first, change is applied again to a fresh copy.
+func update[T any](get func() (T, error), change func(*T) error, store func(T) error, conflict error) error {
+	var err error
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		var v T
+		v, err = get()
 		if err != nil {
-			return 0, err
+			return err
+		}
+
+		if err := change(&v); err != nil {
+			return err
 		}
 
-		products = append(products, p)
-		total += p.GetPrice()
+		err = store(v)
+		if !errors.Is(err, conflict) {
+			return err
+		}
 	}
 
-	fmt.Printf("Customer: %s has ordered %d products with total of $%.2f\n", c.GetID(), len(products), total)
-	for _, p := range products {
-		fmt.Printf("\t - %s\n", p.GetItem().Name)
+	return err
+}
+
+// updateCustomer changes the customer, so concurrent charges of a wallet
+// never spend the same credit twice
+func (os *OrderService) updateCustomer(ctx context.Context, customerID uuid.UUID, change func(c *aggregate.Customer) error) error {
+	return updateCustomer(ctx, os.customerRepo, customerID, change)
+}
+
+func updateCustomer(ctx context.Context, customerRepo customer.CustomerRepository, customerID uuid.UUID, change func(c *aggregate.Customer) error) error {
+	return update(
+		func() (aggregate.Customer, error) { return customerRepo.Get(ctx, customerID) },
+		change,
+		func(c aggregate.Customer) error { return customerRepo.Update(ctx, c) },
+		customer.ErrConcurrentUpdate,
+	)
+}
+
+// updatePromotion changes the promotion, so its usage limit holds under
+// concurrent orders
+func (os *OrderService) updatePromotion(ctx context.Context, promo aggregate.Promotion, change func(p *aggregate.Promotion) error) error {
+	return update(
+		func() (aggregate.Promotion, error) { return os.promotionRepo.GetByCode(ctx, promo.GetCode()) },
+		change,
+		func(p aggregate.Promotion) error { return os.promotionRepo.Update(ctx, p) },
+		promotion.ErrConcurrentUpdate,
+	)
+}
+
+// maxGatewayAttempts is how many times a call failing at the payment gateway is
+// made, with the same idempotency key so it is never charged twice
+const maxGatewayAttempts = 3
+
+// callGateway retries the call while the gateway fails and ctx is not done
+func (os *OrderService) callGateway(ctx context.Context, call func() (payment.Payment, error)) (p payment.Payment, err error) {
+	for attempt := 0; attempt < maxGatewayAttempts; attempt++ {
+		p, err = call()
+		if !errors.Is(err, payment.ErrPaymentFailed) || ctx.Err() != nil {
+			break
+		}
 	}
 
-	return total, nil
+	return p, err
+}
+
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, customer.ErrCustomerNotFound):
+		return metrics.ReasonCustomerNotFound
+	case errors.Is(err, product.ErrProductNotFound):
+		return metrics.ReasonProductNotFound
+	case errors.Is(err, policy.ErrAgeRestricted):
+		return metrics.ReasonAgeRestricted
+	case errors.Is(err, ErrCouponRejected):
+		return metrics.ReasonCouponRejected
+	case errors.Is(err, ErrRedemptionRejected):
+		return metrics.ReasonRedemptionRejected
+	default:
+		return metrics.ReasonOther
+	}
 }
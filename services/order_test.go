@@ -1,10 +1,26 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/policy"
+	"golang-learn-ddd/domain/pricing"
+	"golang-learn-ddd/domain/product"
+	"golang-learn-ddd/domain/promotion"
+	"golang-learn-ddd/domain/tax"
+	"golang-learn-ddd/metrics"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func init_products(t *testing.T) []aggregate.Product {
@@ -45,7 +61,7 @@ func TestOrder_NewOrderService(t *testing.T) {
 		t.Error(err)
 	}
 
-	if err := os.customerRepo.Add(cust); err != nil {
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
 		t.Error(err)
 	}
 
@@ -54,7 +70,552 @@ func TestOrder_NewOrderService(t *testing.T) {
 		products[2].GetID(),
 	}
 
-	if _, err := os.CreateOrder(cust.GetID(), orders); err != nil {
+	if _, err := os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(orders...)); err != nil {
 		t.Error(err)
 	}
 }
+
+func TestOrder_CreateOrderLogsFields(t *testing.T) {
+	products := init_products(t)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository(products),
+		WithMemoryCustomerRepository(),
+		WithLogger(logger),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(products[0].GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single json log entry, got %q: %v", buf.String(), err)
+	}
+
+	if entry["msg"] != "order created" {
+		t.Errorf("expected msg %q, got %v", "order created", entry["msg"])
+	}
+	if entry["customer_id"] != cust.GetID().String() {
+		t.Errorf("expected customer_id %v, got %v", cust.GetID(), entry["customer_id"])
+	}
+	if entry["total"] != order.GetTotal() {
+		t.Errorf("expected total %v, got %v", order.GetTotal(), entry["total"])
+	}
+
+	// failures are logged with the error attached
+	buf.Reset()
+	if _, err := os.CreateOrder(context.Background(), uuid.New(), nil); err == nil {
+		t.Fatal("expected an error for an unknown customer")
+	}
+
+	entry = map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single json log entry, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "ERROR" || entry["error"] == nil {
+		t.Errorf("expected an error entry with the error field, got %v", entry)
+	}
+}
+
+func TestOrder_CreateOrderMetrics(t *testing.T) {
+	products := init_products(t)
+	m := metrics.New()
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository(products),
+		WithMemoryCustomerRepository(),
+		WithMetrics(m),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(products[0].GetID())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.CreateOrder(context.Background(), uuid.New(), ItemsOf(products[0].GetID())); err == nil {
+		t.Fatal("expected an error for an unknown customer")
+	}
+	if _, err := os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(uuid.New())); err == nil {
+		t.Fatal("expected an error for an unknown product")
+	}
+
+	expected := `
+# HELP tavern_orders_failed_total Number of orders that failed, by reason.
+# TYPE tavern_orders_failed_total counter
+tavern_orders_failed_total{reason="customer_not_found"} 1
+tavern_orders_failed_total{reason="product_not_found"} 1
+# HELP tavern_orders_created_total Number of orders created successfully.
+# TYPE tavern_orders_created_total counter
+tavern_orders_created_total 1
+`
+	if err := testutil.GatherAndCompare(m.Registry(), strings.NewReader(expected),
+		"tavern_orders_failed_total", "tavern_orders_created_total"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOrder_CreateOrderMissingProducts(t *testing.T) {
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository(products),
+		WithMemoryCustomerRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	missing1, missing2 := uuid.New(), uuid.New()
+	orders := []uuid.UUID{
+		missing1,
+		products[0].GetID(),
+		missing2,
+		missing1,
+	}
+
+	_, err = os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(orders...))
+	if !errors.Is(err, product.ErrProductNotFound) {
+		t.Fatalf("expected error %v, got %v", product.ErrProductNotFound, err)
+	}
+
+	var missingErr *product.MissingProductsError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("expected a %T, got %T", missingErr, err)
+	}
+
+	expected := []uuid.UUID{missing1, missing2}
+	if !reflect.DeepEqual(missingErr.IDs, expected) {
+		t.Errorf("expected missing products %v, got %v", expected, missingErr.IDs)
+	}
+}
+
+func TestOrder_CreateOrderAgeRestricted(t *testing.T) {
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 99.92, aggregate.WithMinimumAge(21))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository([]aggregate.Product{beer}),
+		WithMemoryCustomerRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	adult, err := aggregate.NewCustomer("Adhiana", aggregate.WithDateOfBirth(time.Now().AddDate(-30, 0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	minor, err := aggregate.NewCustomer("SeeU", aggregate.WithDateOfBirth(time.Now().AddDate(-16, 0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []aggregate.Customer{adult, minor} {
+		if err := os.customerRepo.Add(context.Background(), c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := os.CreateOrder(context.Background(), adult.GetID(), ItemsOf(beer.GetID())); err != nil {
+		t.Errorf("expected the adult order to succeed, got %v", err)
+	}
+
+	if _, err := os.CreateOrder(context.Background(), minor.GetID(), ItemsOf(beer.GetID())); !errors.Is(err, policy.ErrAgeRestricted) {
+		t.Errorf("expected error %v, got %v", policy.ErrAgeRestricted, err)
+	}
+}
+
+func TestOrder_CreateOrderVariantsAndModifiers(t *testing.T) {
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 0,
+		aggregate.WithVariant("Pint", 6, 10),
+		aggregate.WithVariant("Half-pint", 3.5, 10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakso, err := aggregate.NewProduct("Bakso Kuah", "Bakso Kuah pedah hot jeletot", 1.5,
+		aggregate.WithModifier("Extra noodles", 0.75),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository([]aggregate.Product{beer, bakso}),
+		WithMemoryCustomerRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	items := []OrderItem{
+		{ProductID: beer.GetID(), VariantID: beer.GetVariants()[0].ID},
+		{ProductID: beer.GetID(), VariantID: beer.GetVariants()[1].ID},
+		{ProductID: bakso.GetID(), ModifierIDs: []uuid.UUID{bakso.GetModifiers()[0].ID}},
+	}
+
+	order, err := os.CreateOrder(context.Background(), cust.GetID(), items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTotal() != 11.75 {
+		t.Errorf("expected total %v, got %v", 11.75, order.GetTotal())
+	}
+
+	// beer comes in pints or half-pints only
+	_, err = os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(beer.GetID()))
+	if !errors.Is(err, aggregate.ErrVariantRequired) {
+		t.Errorf("expected error %v, got %v", aggregate.ErrVariantRequired, err)
+	}
+}
+
+func TestOrder_CreateOrderUsesEffectivePrice(t *testing.T) {
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := beer.ChangePrice(6, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := beer.ChangePrice(10, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository([]aggregate.Product{beer}),
+		WithMemoryCustomerRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(beer.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTotal() != 6 {
+		t.Errorf("expected the price effective now %v, got %v", 6, order.GetTotal())
+	}
+}
+
+func TestOrder_CreateOrderHappyHour(t *testing.T) {
+	drinks, err := aggregate.NewCategory("Drinks", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 10, aggregate.WithCategory(drinks.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakso, err := aggregate.NewProduct("Bakso Kuah", "Bakso Kuah pedah hot jeletot", 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	happyHour, err := pricing.NewTimeWindowRule("happy hour", "17:00", "19:00", 30,
+		pricing.ForCategory(drinks.GetID()),
+		pricing.InLocation(time.UTC),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2023, time.June, 2, 17, 30, 0, 0, time.UTC)
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository([]aggregate.Product{beer, bakso}),
+		WithMemoryCustomerRepository(),
+		WithPricingRules(happyHour),
+		WithClock(func() time.Time { return now }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(beer.GetID(), bakso.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := order.GetLines()
+	if lines[0].Rule != "happy hour" || lines[0].Discount != 3 {
+		t.Errorf("expected the beer to get 3 off from happy hour, got %v from %q", lines[0].Discount, lines[0].Rule)
+	}
+	if lines[1].Rule != "" || lines[1].Discount != 0 {
+		t.Errorf("expected no discount on food, got %v from %q", lines[1].Discount, lines[1].Rule)
+	}
+	if order.GetTotal() != 8.5 {
+		t.Errorf("expected total %v, got %v", 8.5, order.GetTotal())
+	}
+	if !order.GetCreatedAt().Equal(now) {
+		t.Errorf("expected the order to be created at %v, got %v", now, order.GetCreatedAt())
+	}
+
+	// after happy hour
+	now = now.Add(2 * time.Hour)
+	order, err = os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(beer.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTotal() != 10 {
+		t.Errorf("expected total %v, got %v", 10, order.GetTotal())
+	}
+}
+
+func TestOrder_CreateOrderWithCoupon(t *testing.T) {
+	products := init_products(t)
+
+	tenOff, err := aggregate.NewPercentageOff("TENOFF", 10, aggregate.WithUsageLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bigSpender, err := aggregate.NewFixedAmountOff("BIGSPENDER", 20, aggregate.WithMinSpend(200))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository(products),
+		WithMemoryCustomerRepository(),
+		WithMemoryPromotionRepository([]aggregate.Promotion{tenOff, bigSpender}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	items := ItemsOf(products[1].GetID(), products[2].GetID())
+
+	order, err := os.CreateOrder(context.Background(), cust.GetID(), items, WithCoupon("tenoff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetCoupon() != "TENOFF" || order.GetCouponDiscount() != 1.4 {
+		t.Errorf("expected 1.4 off from TENOFF, got %v from %q", order.GetCouponDiscount(), order.GetCoupon())
+	}
+	if order.GetTotal() != 12.6 {
+		t.Errorf("expected total %v, got %v", 12.6, order.GetTotal())
+	}
+
+	type testCase struct {
+		name        string
+		coupon      string
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:        "usage limit reached",
+			coupon:      "TENOFF",
+			expectedErr: aggregate.ErrUsageLimitReached,
+		},
+		{
+			name:        "minimum spend not met",
+			coupon:      "BIGSPENDER",
+			expectedErr: aggregate.ErrMinSpendNotMet,
+		},
+		{
+			name:        "unknown code",
+			coupon:      "NOPE",
+			expectedErr: promotion.ErrPromotionNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := os.CreateOrder(context.Background(), cust.GetID(), items, WithCoupon(tc.coupon))
+			if !errors.Is(err, tc.expectedErr) || !errors.Is(err, ErrCouponRejected) {
+				t.Errorf("expected error %v, got %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestOrder_CreateOrderWithCouponConcurrently(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+
+	once, err := aggregate.NewPercentageOff("ONCE", 10, aggregate.WithUsageLimit(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository(products),
+		WithMemoryCustomerRepository(),
+		WithMemoryPromotionRepository([]aggregate.Promotion{once}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := os.CreateOrder(ctx, cust.GetID(), ItemsOf(products[1].GetID()), WithCoupon("ONCE"))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	// the other orders either saw the coupon used or kept losing the race
+	var redeemed int
+	for err := range errs {
+		switch {
+		case err == nil:
+			redeemed++
+		case !errors.Is(err, aggregate.ErrUsageLimitReached) && !errors.Is(err, promotion.ErrConcurrentUpdate):
+			t.Errorf("expected error %v, got %v", aggregate.ErrUsageLimitReached, err)
+		}
+	}
+	if redeemed != 1 {
+		t.Errorf("expected the coupon to be used once, got %d", redeemed)
+	}
+
+	stored, err := os.promotionRepo.GetByCode(ctx, "ONCE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.GetUsage()[cust.GetID()] != 1 {
+		t.Errorf("expected a usage of 1, got %d", stored.GetUsage()[cust.GetID()])
+	}
+}
+
+func TestOrder_CreateOrderWithTax(t *testing.T) {
+	food, err := aggregate.NewCategory("Food", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beer, err := aggregate.NewProduct("Beer", "Halal Beer", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bakso, err := aggregate.NewProduct("Bakso Kuah", "Bakso Kuah pedah hot jeletot", 4, aggregate.WithCategory(food.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vat, err := tax.NewRatePolicy("VAT", 11, tax.WithCategoryRate(food.GetID(), "food", 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryProductRepository([]aggregate.Product{beer, bakso}),
+		WithMemoryCustomerRepository(),
+		WithTaxPolicy(vat),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Senyamiku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(context.Background(), cust.GetID(), ItemsOf(beer.GetID(), bakso.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []aggregate.TaxLine{
+		{Name: "VAT", Rate: 11, Base: 10, Amount: 1.1},
+		{Name: "food", Rate: 5, Base: 4, Amount: 0.2},
+	}
+	if !reflect.DeepEqual(order.GetTaxes(), expected) {
+		t.Errorf("expected taxes %+v, got %+v", expected, order.GetTaxes())
+	}
+	if order.GetSubtotal() != 14 || order.GetTax() != 1.3 || order.GetTotal() != 15.3 {
+		t.Errorf("expected subtotal 14, tax 1.3 and total 15.3, got %v, %v and %v",
+			order.GetSubtotal(), order.GetTax(), order.GetTotal())
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/payment"
+	"golang-learn-ddd/domain/payment/fake"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernServicePaymentGateway(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer := products[0]
+
+	type testCase struct {
+		name string
+		cfgs []fake.Configuration
+		// declined names the customers whose card is declined
+		declined []string
+		opts     func(ids map[string]uuid.UUID) []OrderOption
+		// expected is the amount captured from every customer
+		expected    map[string]int
+		calls       int
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name: "charged",
+			opts: func(ids map[string]uuid.UUID) []OrderOption {
+				return []OrderOption{WithTip(1)}
+			},
+			expected: map[string]int{"Miku": 10092},
+			calls:    2,
+		},
+		{
+			name: "split between cards",
+			opts: func(ids map[string]uuid.UUID) []OrderOption {
+				return []OrderOption{SplitEvenly(ids["Miku"], ids["Rin"])}
+			},
+			expected: map[string]int{"Miku": 4996, "Rin": 4996},
+			calls:    4,
+		},
+		{
+			name:     "declined card charges nobody",
+			declined: []string{"Rin"},
+			opts: func(ids map[string]uuid.UUID) []OrderOption {
+				return []OrderOption{SplitEvenly(ids["Miku"], ids["Rin"])}
+			},
+			// Miku is authorized, Rin declined, then Miku voided
+			calls:       3,
+			expectedErr: payment.ErrPaymentDeclined,
+		},
+		{
+			name: "failures retried with the same key",
+			cfgs: []fake.Configuration{fake.WithFailures(2)},
+			opts: func(ids map[string]uuid.UUID) []OrderOption {
+				return nil
+			},
+			expected: map[string]int{"Miku": 9992},
+			calls:    2,
+		},
+		{
+			name: "gateway down",
+			cfgs: []fake.Configuration{fake.WithFailures(maxGatewayAttempts)},
+			opts: func(ids map[string]uuid.UUID) []OrderOption {
+				return nil
+			},
+			expectedErr: payment.ErrPaymentFailed,
+		},
+		{
+			name: "wallet bypasses the gateway",
+			opts: func(ids map[string]uuid.UUID) []OrderOption {
+				return []OrderOption{PayFromWallet()}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os, err := NewOrderService(
+				WithMemoryCustomerRepository(),
+				WithMemoryProductRepository(products),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := map[string]uuid.UUID{}
+			for _, name := range []string{"Miku", "Rin"} {
+				c, err := aggregate.NewCustomer(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := c.Deposit(100, os.now()); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.customerRepo.Add(ctx, c); err != nil {
+					t.Fatal(err)
+				}
+				ids[name] = c.GetID()
+			}
+
+			cfgs := tc.cfgs
+			for _, name := range tc.declined {
+				cfgs = append(cfgs, fake.DeclineCustomers(ids[name]))
+			}
+			gateway, err := fake.New(cfgs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tavern, err := NewTavernService(WithOrderService(os), WithPaymentGateway(gateway))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = tavern.Order(ctx, ids["Miku"], ItemsOf(beer.GetID()), tc.opts(ids)...)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if gateway.Calls() != tc.calls {
+				t.Errorf("expected %d calls to the gateway, got %d", tc.calls, gateway.Calls())
+			}
+
+			for name, id := range ids {
+				var captured int
+				for _, p := range gateway.Payments(id) {
+					captured += p.Captured
+					if p.Status == payment.Authorized {
+						t.Errorf("expected the authorization of %s to be captured or voided, got %+v", name, p)
+					}
+				}
+				if captured != tc.expected[name] {
+					t.Errorf("expected %d cents captured from %s, got %d", tc.expected[name], name, captured)
+				}
+
+				c, err := os.customerRepo.Get(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var recorded int
+				for _, tr := range c.GetTransactions() {
+					if tr.From() != id || tr.Reference() == "" {
+						continue
+					}
+					recorded += tr.Amount()
+					if p, ok := gateway.Payment(tr.Reference()); !ok || p.CustomerID != id {
+						t.Errorf("expected transaction of %s to reference their payment, got %q", name, tr.Reference())
+					}
+				}
+				if recorded != tc.expected[name] {
+					t.Errorf("expected %d cents recorded for %s, got %d", tc.expected[name], name, recorded)
+				}
+			}
+		})
+	}
+}
+
+func Test_TavernServicePaymentGatewayOfTheTavern(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+
+	gateway, err := fake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithMemoryOrderRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the gateway may be given before the order service, and only the tavern it is given to charges cards
+	card, err := NewTavernService(WithPaymentGateway(gateway), WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Miku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		tavern   *TavernService
+		method   aggregate.PaymentMethod
+		payments int
+	}{
+		{account, aggregate.PaymentAccount, 0},
+		{card, aggregate.PaymentCard, 1},
+		{account, aggregate.PaymentAccount, 1},
+	} {
+		o, err := tc.tavern.Order(ctx, cust.GetID(), ItemsOf(products[0].GetID()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		kept, err := os.orderRepo.Get(ctx, o.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept.GetPaymentMethod() != tc.method {
+			t.Errorf("expected the order paid %q, got %q", tc.method, kept.GetPaymentMethod())
+		}
+		if got := len(gateway.Payments(cust.GetID())); got != tc.payments {
+			t.Errorf("expected %d card payments, got %d", tc.payments, got)
+		}
+	}
+}
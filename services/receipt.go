@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/invoice"
+	"golang-learn-ddd/domain/order"
+	"golang-learn-ddd/domain/receipt"
+	"golang-learn-ddd/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Receipt builds the itemized receipt of a paid order, render it with one
+// of the receipt renderers. The order keeps its invoice number, the first
+// receipt of an order numbers it.
+func (s *TavernService) Receipt(ctx context.Context, orderID uuid.UUID) (r receipt.Receipt, err error) {
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.Receipt", attribute.Stringer("order.id", orderID))
+	defer func() { tracing.End(span, err) }()
+
+	orderRepo := s.OrderService.orderRepo
+	if orderRepo == nil {
+		return receipt.Receipt{}, fmt.Errorf("orders are not kept: %w", order.ErrOrderNotFound)
+	}
+	if s.invoiceRepo == nil {
+		return receipt.Receipt{}, fmt.Errorf("no invoice repository: %w", invoice.ErrNumberInvoice)
+	}
+
+	o, err := orderRepo.Get(ctx, orderID)
+	if err != nil {
+		return receipt.Receipt{}, err
+	}
+
+	var cfgs []receipt.Configuration
+	// a receipt without the name of the customer is still a receipt
+	if c, err := s.OrderService.customerRepo.Get(ctx, o.GetCustomerID()); err == nil {
+		cfgs = append(cfgs, receipt.WithCustomer(c.GetName()))
+	} else {
+		s.logger.Error("failed to name the customer on the receipt", "order_id", orderID, "customer_id", o.GetCustomerID(), "error", err)
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(o.GetLines()))
+	for _, l := range o.GetLines() {
+		productIDs = append(productIDs, l.ProductID)
+	}
+	products, _, err := s.OrderService.productRepo.GetByIDs(ctx, productIDs)
+	if err != nil {
+		return receipt.Receipt{}, err
+	}
+	modifiers := map[uuid.UUID]string{}
+	for _, p := range products {
+		for _, m := range p.GetModifiers() {
+			modifiers[m.ID] = m.Name
+		}
+	}
+	cfgs = append(cfgs, receipt.WithModifierNames(modifiers))
+
+	// only paid orders are numbered, so unpaid ones leave no gap
+	number := 0
+	if o.GetStatus() != aggregate.OrderUnpaid {
+		number, err = s.invoiceRepo.Number(ctx, s.id, orderID)
+		if err != nil {
+			s.logger.Error("failed to number the invoice", "order_id", orderID, "error", err)
+			return receipt.Receipt{}, err
+		}
+	}
+
+	return receipt.New(s.header, number, o, cfgs...)
+}
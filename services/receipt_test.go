@@ -0,0 +1,103 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/invoice"
+	"golang-learn-ddd/domain/order"
+	"golang-learn-ddd/domain/receipt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernReceipt(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer, peanut := products[0], products[1]
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithMemoryOrderRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(
+		WithOrderService(os),
+		WithReceiptHeader(receipt.Header{Name: "The Golden Tankard"}),
+		WithMemoryInvoiceRepository(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Miku")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	var orders []aggregate.Order
+	for _, items := range [][]OrderItem{ItemsOf(beer.GetID()), ItemsOf(beer.GetID(), peanut.GetID())} {
+		o, err := os.CreateOrder(ctx, cust.GetID(), items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tavern.bill(ctx, cust.GetID(), []aggregate.Order{o}, orderRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		orders = append(orders, o)
+	}
+
+	// the second order asks first, and its number does not change afterwards
+	for _, tc := range []struct {
+		order  aggregate.Order
+		number int
+	}{
+		{orders[1], 1},
+		{orders[0], 2},
+		{orders[1], 1},
+	} {
+		r, err := tavern.Receipt(ctx, tc.order.GetID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.InvoiceNumber != tc.number {
+			t.Errorf("expected invoice %d for order %s, got %d", tc.number, tc.order.GetID(), r.InvoiceNumber)
+		}
+		if r.Customer != "Miku" || r.Total != tc.order.GetTotal() || len(r.Lines) != len(tc.order.GetLines()) {
+			t.Errorf("unexpected receipt %+v", r)
+		}
+	}
+
+	r, err := tavern.Receipt(ctx, orders[1].GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := (receipt.TextRenderer{}).Render(&b, r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "Invoice 000001") || !strings.Contains(b.String(), "The Golden Tankard") {
+		t.Errorf("unexpected receipt\n%s", b.String())
+	}
+
+	if _, err := tavern.Receipt(ctx, uuid.New()); !errors.Is(err, order.ErrOrderNotFound) {
+		t.Errorf("expected error %v, got %v", order.ErrOrderNotFound, err)
+	}
+
+	unnumbered, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unnumbered.Receipt(ctx, orders[0].GetID()); !errors.Is(err, invoice.ErrNumberInvoice) {
+		t.Errorf("expected error %v, got %v", invoice.ErrNumberInvoice, err)
+	}
+}
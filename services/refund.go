@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/order"
+	"golang-learn-ddd/domain/payment"
+	"golang-learn-ddd/tracing"
+	"golang-learn-ddd/valueobject"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RefundOption chooses what a refund gives back, everything left to refund by default
+type RefundOption func(r *refundRequest)
+
+type refundRequest struct {
+	lines  []int
+	amount float64
+}
+
+// RefundLines refunds the lines of the order, given by index
+func RefundLines(lines ...int) RefundOption {
+	return func(r *refundRequest) {
+		r.lines = append(r.lines, lines...)
+	}
+}
+
+// RefundAmount refunds an amount of money, whatever the lines
+func RefundAmount(amount float64) RefundOption {
+	return func(r *refundRequest) {
+		r.amount = amount
+	}
+}
+
+// Refund gives back money paid for an order to its payers, from the tavern.
+// Card payments are refunded through the payment gateway and wallet payments
+// go back to the wallet. The order has to be kept by an order repository.
+func (os *OrderService) Refund(ctx context.Context, orderID uuid.UUID, reason string, opts ...RefundOption) (refund aggregate.Refund, err error) {
+	ctx, span := tracing.Start(ctx, os.tracer, "OrderService.Refund", attribute.Stringer("order.id", orderID))
+	defer func() { tracing.End(span, err) }()
+
+	req := refundRequest{}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if len(req.lines) > 0 && req.amount != 0 {
+		return aggregate.Refund{}, fmt.Errorf("refund either lines or an amount: %w", aggregate.ErrInvalidRefund)
+	}
+	if os.orderRepo == nil {
+		return aggregate.Refund{}, fmt.Errorf("orders are not kept: %w", order.ErrOrderNotFound)
+	}
+
+	now := os.now()
+
+	// the refund is recorded before any money moves, so concurrent refunds
+	// of the order cannot give back the same money twice
+	var method aggregate.PaymentMethod
+	err = os.updateOrder(ctx, orderID, func(o *aggregate.Order) error {
+		var err error
+		switch {
+		case len(req.lines) > 0:
+			refund, err = o.RefundLines(req.lines, reason, now)
+		case req.amount != 0:
+			refund, err = o.RefundAmount(req.amount, reason, now)
+		default:
+			refund, err = o.RefundAmount(o.GetCharged()-o.GetRefunded(), reason, now)
+		}
+		if err != nil {
+			return err
+		}
+		method = o.GetPaymentMethod()
+		return o.AddRefund(refund)
+	})
+	if err != nil {
+		os.logger.Error("failed to refund the order", "order_id", orderID, "error", err)
+		return aggregate.Refund{}, err
+	}
+
+	given, err := os.giveBack(ctx, method, refund, now)
+	if err != nil {
+		os.logger.Error("failed to give the refund back", "order_id", orderID, "refund_id", refund.ID, "error", err)
+		os.reviseRefund(ctx, orderID, refund, given)
+		return aggregate.Refund{}, err
+	}
+
+	os.logger.Info("refund the order",
+		"order_id", orderID,
+		"refund_id", refund.ID,
+		"amount", refund.Amount(),
+		"lines", refund.Lines,
+		"reason", reason,
+	)
+
+	return refund, nil
+}
+
+// giveBack moves the money of the refund to every payer and returns the
+// transactions given back, up to the first that could not be
+func (os *OrderService) giveBack(ctx context.Context, method aggregate.PaymentMethod, refund aggregate.Refund, now time.Time) ([]valueobject.Transaction, error) {
+	given := make([]valueobject.Transaction, 0, len(refund.Transactions))
+	for i, t := range refund.Transactions {
+		if method == aggregate.PaymentCard {
+			if os.gateway == nil {
+				return given, fmt.Errorf("no gateway to refund payment %s: %w", t.Reference(), payment.ErrPaymentFailed)
+			}
+
+			key := fmt.Sprintf("%s:%d:refund", refund.ID, i)
+			_, err := os.callGateway(ctx, func() (payment.Payment, error) {
+				return os.gateway.Refund(ctx, key, t.Reference(), t.Amount())
+			})
+			if err != nil {
+				return given, fmt.Errorf("failed to refund %d cents of payment %s: %w", t.Amount(), t.Reference(), err)
+			}
+		}
+
+		err := os.updateCustomer(ctx, t.To(), func(c *aggregate.Customer) error {
+			if method == aggregate.PaymentWallet {
+				if err := c.Deposit(float64(t.Amount())/100, now); err != nil {
+					return err
+				}
+			}
+			c.AddTransaction(t)
+			return nil
+		})
+		if err != nil {
+			if method == aggregate.PaymentCard {
+				// the card is refunded already, only the record of the customer is missing
+				os.logger.Error("failed to record the refund", "customer_id", t.To(), "refund_id", refund.ID, "error", err)
+				given = append(given, t)
+				continue
+			}
+			return given, fmt.Errorf("failed to refund customer %s: %w", t.To(), err)
+		}
+
+		given = append(given, t)
+	}
+
+	return given, nil
+}
+
+// reviseRefund keeps only what was given back of a refund that failed
+func (os *OrderService) reviseRefund(ctx context.Context, orderID uuid.UUID, refund aggregate.Refund, given []valueobject.Transaction) {
+	err := os.updateOrder(ctx, orderID, func(o *aggregate.Order) error {
+		o.CancelRefund(refund.ID)
+		if len(given) == 0 {
+			return nil
+		}
+
+		refund.Lines = nil
+		refund.Transactions = given
+		return o.AddRefund(refund)
+	})
+	if err != nil {
+		os.logger.Error("failed to revise the refund", "order_id", orderID, "refund_id", refund.ID, "given", len(given), "error", err)
+	}
+}
+
+// updateOrder changes the order, so concurrent refunds never give back
+// the same money twice
+func (os *OrderService) updateOrder(ctx context.Context, orderID uuid.UUID, change func(o *aggregate.Order) error) error {
+	return update(
+		func() (aggregate.Order, error) { return os.orderRepo.Get(ctx, orderID) },
+		change,
+		func(o aggregate.Order) error { return os.orderRepo.Update(ctx, o) },
+		order.ErrConcurrentUpdate,
+	)
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/order"
+	"golang-learn-ddd/domain/payment"
+	"golang-learn-ddd/domain/payment/fake"
+	"golang-learn-ddd/valueobject"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_OrderServiceRefund(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer, peanut := products[0], products[1]
+
+	type step struct {
+		opts []RefundOption
+		// expected is what the customer gets back
+		expected    float64
+		status      aggregate.OrderStatus
+		expectedErr error
+	}
+
+	type testCase struct {
+		name   string
+		wallet bool
+		steps  []step
+		// balance is the wallet of the customer after the refunds
+		balance float64
+	}
+	tests := []testCase{
+		{
+			name: "line then the rest",
+			steps: []step{
+				{opts: []RefundOption{RefundLines(1)}, expected: 12.5, status: aggregate.OrderPartiallyRefunded},
+				{opts: []RefundOption{RefundLines(1)}, expectedErr: aggregate.ErrInvalidRefund},
+				{opts: []RefundOption{RefundAmount(200)}, expectedErr: aggregate.ErrInvalidRefund},
+				{expected: 99.92, status: aggregate.OrderRefunded},
+				{expectedErr: aggregate.ErrInvalidRefund},
+			},
+			balance: 200,
+		},
+		{
+			name:   "back to the wallet",
+			wallet: true,
+			steps: []step{
+				{opts: []RefundOption{RefundAmount(10)}, expected: 10, status: aggregate.OrderPartiallyRefunded},
+			},
+			balance: 200 - 112.42 + 10,
+		},
+		{
+			name: "lines or an amount",
+			steps: []step{
+				{opts: []RefundOption{RefundLines(0), RefundAmount(10)}, expectedErr: aggregate.ErrInvalidRefund},
+			},
+			balance: 200,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os, err := NewOrderService(
+				WithMemoryCustomerRepository(),
+				WithMemoryProductRepository(products),
+				WithMemoryOrderRepository(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tavern, err := NewTavernService(WithOrderService(os))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cust, err := aggregate.NewCustomer("Miku")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := cust.Deposit(200, os.now()); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.customerRepo.Add(ctx, cust); err != nil {
+				t.Fatal(err)
+			}
+
+			o, err := os.CreateOrder(ctx, cust.GetID(), ItemsOf(beer.GetID(), peanut.GetID()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tavern.bill(ctx, cust.GetID(), []aggregate.Order{o}, orderRequest{wallet: tc.wallet}); err != nil {
+				t.Fatal(err)
+			}
+
+			var refunded int
+			for i, s := range tc.steps {
+				refund, err := os.Refund(ctx, o.GetID(), "spilled", s.opts...)
+				if !errors.Is(err, s.expectedErr) {
+					t.Fatalf("step %d: expected error %v, got %v", i, s.expectedErr, err)
+				}
+				if err != nil {
+					continue
+				}
+				refunded += valueobject.Cents(refund.Amount())
+
+				if refund.Amount() != s.expected {
+					t.Errorf("step %d: expected a refund of %v, got %v", i, s.expected, refund.Amount())
+				}
+				stored, err := os.orderRepo.Get(ctx, o.GetID())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stored.GetStatus() != s.status {
+					t.Errorf("step %d: expected status %q, got %q", i, s.status, stored.GetStatus())
+				}
+			}
+
+			cust, err = os.customerRepo.Get(ctx, cust.GetID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var received int
+			for _, tr := range cust.GetTransactions() {
+				if tr.Kind() == valueobject.TransactionRefund && tr.To() == cust.GetID() && tr.From() == tavern.GetID() {
+					received += tr.Amount()
+				}
+			}
+			if received != refunded {
+				t.Errorf("expected the customer to receive %d cents, got %d", refunded, received)
+			}
+			if cust.GetBalance() != aggregate.RoundMoney(tc.balance) {
+				t.Errorf("expected a balance of %v, got %v", tc.balance, cust.GetBalance())
+			}
+		})
+	}
+
+	t.Run("unknown order", func(t *testing.T) {
+		os, err := NewOrderService(WithMemoryOrderRepository())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := os.Refund(ctx, uuid.New(), ""); !errors.Is(err, order.ErrOrderNotFound) {
+			t.Errorf("expected error %v, got %v", order.ErrOrderNotFound, err)
+		}
+	})
+}
+
+func Test_OrderServiceRefundToCard(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer := products[0]
+
+	gateway, err := fake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithMemoryOrderRepository(),
+		WithOrderPaymentGateway(gateway),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := map[string]uuid.UUID{}
+	for _, name := range []string{"Miku", "Rin"} {
+		c, err := aggregate.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.customerRepo.Add(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = c.GetID()
+	}
+
+	o, err := os.CreateOrder(ctx, ids["Miku"], ItemsOf(beer.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := orderRequest{}
+	SplitEvenly(ids["Miku"], ids["Rin"])(&req)
+	if err := tavern.bill(ctx, ids["Miku"], []aggregate.Order{o}, req); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Refund(ctx, o.GetID(), "flat", RefundAmount(10)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the second refund fails at the gateway, nothing of it is kept
+	gateway.FailNext(maxGatewayAttempts)
+	if _, err := os.Refund(ctx, o.GetID(), "flat"); !errors.Is(err, payment.ErrPaymentFailed) {
+		t.Errorf("expected error %v, got %v", payment.ErrPaymentFailed, err)
+	}
+
+	for name, id := range ids {
+		payments := gateway.Payments(id)
+		if len(payments) != 1 || payments[0].Captured != 4996 || payments[0].Refunded != 500 {
+			t.Errorf("expected 5.00 of 49.96 refunded to the card of %s, got %+v", name, payments)
+		}
+	}
+
+	stored, err := os.orderRepo.Get(ctx, o.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.GetRefunded() != 10 || stored.GetStatus() != aggregate.OrderPartiallyRefunded || stored.GetPaymentMethod() != aggregate.PaymentCard {
+		t.Errorf("expected 10 refunded by card, got %v refunded, status %q and method %q",
+			stored.GetRefunded(), stored.GetStatus(), stored.GetPaymentMethod())
+	}
+}
+
+// lostOrderRepository fails to keep the orders
+type lostOrderRepository struct {
+	order.OrderRepository
+}
+
+func (r lostOrderRepository) Add(ctx context.Context, o aggregate.Order) error {
+	return order.ErrFailedToAddOrder
+}
+
+func Test_TavernServiceOrderNotKept(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	peanut := products[1]
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithOrderRepository(lostOrderRepository{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Luka")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// the order is paid, the caller hears it could not be kept
+	_, err = tavern.Order(ctx, cust.GetID(), ItemsOf(peanut.GetID()))
+	if !errors.Is(err, ErrOrderNotKept) || !errors.Is(err, order.ErrFailedToAddOrder) || errors.Is(err, ErrNotCharged) {
+		t.Fatalf("expected error %v, got %v", ErrOrderNotKept, err)
+	}
+
+	c, err := os.customerRepo.Get(ctx, cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(c.GetTransactions()); n != 1 {
+		t.Errorf("expected the payment to be recorded, got %d transactions", n)
+	}
+}
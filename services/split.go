@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/valueobject"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSplit = errors.New("the split does not match the bill")
+)
+
+// ItemRef points to one line of an order, to split a bill by item
+type ItemRef struct {
+	OrderID uuid.UUID
+	Line    int
+}
+
+// splitter returns the payers of the orders and the weight of each of them,
+// total is the price of the orders in cents
+type splitter func(orders []aggregate.Order, total int) ([]uuid.UUID, []int, error)
+
+// SplitEvenly shares the bill equally between the customers
+func SplitEvenly(customerIDs ...uuid.UUID) OrderOption {
+	return func(r *orderRequest) {
+		r.split = func(orders []aggregate.Order, total int) ([]uuid.UUID, []int, error) {
+			if err := checkPayers(customerIDs); err != nil {
+				return nil, nil, err
+			}
+
+			weights := make([]int, len(customerIDs))
+			for i := range weights {
+				weights[i] = 1
+			}
+
+			return customerIDs, weights, nil
+		}
+	}
+}
+
+// SplitByItem makes each customer pay for the lines they had, every line of
+// the bill has to be assigned to exactly one customer. Coupons, taxes and the
+// service charge are shared in proportion of the lines.
+func SplitByItem(items map[uuid.UUID][]ItemRef) OrderOption {
+	return func(r *orderRequest) {
+		r.split = func(orders []aggregate.Order, total int) ([]uuid.UUID, []int, error) {
+			lines := map[ItemRef]int{}
+			for _, o := range orders {
+				for i, l := range o.GetLines() {
+					lines[ItemRef{OrderID: o.GetID(), Line: i}] = valueobject.Cents(l.Total())
+				}
+			}
+
+			payers := sortedPayers(items)
+			if err := checkPayers(payers); err != nil {
+				return nil, nil, err
+			}
+			weights := make([]int, len(payers))
+			assigned := map[ItemRef]bool{}
+			for i, payer := range payers {
+				for _, ref := range items[payer] {
+					price, ok := lines[ref]
+					if !ok || assigned[ref] {
+						return nil, nil, fmt.Errorf("line %d of order %s is unknown or paid twice: %w", ref.Line, ref.OrderID, ErrInvalidSplit)
+					}
+
+					assigned[ref] = true
+					weights[i] += price
+				}
+			}
+			if len(assigned) != len(lines) {
+				return nil, nil, fmt.Errorf("%d lines are not paid: %w", len(lines)-len(assigned), ErrInvalidSplit)
+			}
+
+			return payers, weights, nil
+		}
+	}
+}
+
+// SplitByAmount makes each customer pay a custom amount of the orders, the
+// amounts have to sum to their total. The service charge and the tip are
+// shared in proportion of the amounts.
+func SplitByAmount(amounts map[uuid.UUID]float64) OrderOption {
+	return func(r *orderRequest) {
+		r.split = func(orders []aggregate.Order, total int) ([]uuid.UUID, []int, error) {
+			payers := sortedPayers(amounts)
+			if err := checkPayers(payers); err != nil {
+				return nil, nil, err
+			}
+			weights := make([]int, len(payers))
+
+			var sum int
+			for i, payer := range payers {
+				if amounts[payer] < 0 {
+					return nil, nil, fmt.Errorf("amount of customer %s is negative: %w", payer, ErrInvalidSplit)
+				}
+
+				weights[i] = valueobject.Cents(amounts[payer])
+				sum += weights[i]
+			}
+			if sum != total {
+				return nil, nil, fmt.Errorf("amounts sum to %d cents instead of %d: %w", sum, total, ErrInvalidSplit)
+			}
+
+			return payers, weights, nil
+		}
+	}
+}
+
+func checkPayers(payers []uuid.UUID) error {
+	if len(payers) == 0 {
+		return fmt.Errorf("no customer to pay: %w", ErrInvalidSplit)
+	}
+
+	seen := map[uuid.UUID]bool{}
+	for _, p := range payers {
+		if seen[p] {
+			return fmt.Errorf("customer %s pays twice: %w", p, ErrInvalidSplit)
+		}
+		seen[p] = true
+	}
+
+	return nil
+}
+
+// sortedPayers orders the payers by ID, so the cents left by the allocation
+// always go to the same customers
+func sortedPayers[T any](shares map[uuid.UUID]T) []uuid.UUID {
+	payers := make([]uuid.UUID, 0, len(shares))
+	for p := range shares {
+		payers = append(payers, p)
+	}
+
+	sort.Slice(payers, func(i, j int) bool {
+		return payers[i].String() < payers[j].String()
+	})
+
+	return payers
+}
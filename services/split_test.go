@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/valueobject"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernServiceSplitBill(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer, peanut, bakso := products[0], products[1], products[2]
+
+	type payments map[string]map[valueobject.TransactionKind]int
+
+	type testCase struct {
+		name        string
+		split       func(tab aggregate.Tab, ids map[string]uuid.UUID) OrderOption
+		expected    payments
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name: "evenly",
+			split: func(tab aggregate.Tab, ids map[string]uuid.UUID) OrderOption {
+				return SplitEvenly(ids["Miku"], ids["Rin"], ids["Len"])
+			},
+			// 113.92 with 10% service charge of 11.39 and a 1.00 tip
+			expected: payments{
+				"Miku": {valueobject.TransactionPayment: 3798, valueobject.TransactionServiceCharge: 380, valueobject.TransactionTip: 34},
+				"Rin":  {valueobject.TransactionPayment: 3797, valueobject.TransactionServiceCharge: 380, valueobject.TransactionTip: 33},
+				"Len":  {valueobject.TransactionPayment: 3797, valueobject.TransactionServiceCharge: 379, valueobject.TransactionTip: 33},
+			},
+		},
+		{
+			name: "by item",
+			split: func(tab aggregate.Tab, ids map[string]uuid.UUID) OrderOption {
+				drinks, food := tab.GetOrders()[0], tab.GetOrders()[1]
+				return SplitByItem(map[uuid.UUID][]ItemRef{
+					ids["Miku"]: {{OrderID: drinks.GetID(), Line: 0}},
+					ids["Rin"]:  {{OrderID: food.GetID(), Line: 0}, {OrderID: food.GetID(), Line: 1}},
+				})
+			},
+			expected: payments{
+				"Miku": {valueobject.TransactionPayment: 9992, valueobject.TransactionServiceCharge: 999, valueobject.TransactionTip: 88},
+				"Rin":  {valueobject.TransactionPayment: 1400, valueobject.TransactionServiceCharge: 140, valueobject.TransactionTip: 12},
+			},
+		},
+		{
+			name: "by amount",
+			split: func(tab aggregate.Tab, ids map[string]uuid.UUID) OrderOption {
+				return SplitByAmount(map[uuid.UUID]float64{
+					ids["Miku"]: 100,
+					ids["Len"]:  13.92,
+				})
+			},
+			expected: payments{
+				"Miku": {valueobject.TransactionPayment: 10000, valueobject.TransactionServiceCharge: 1000, valueobject.TransactionTip: 88},
+				"Len":  {valueobject.TransactionPayment: 1392, valueobject.TransactionServiceCharge: 139, valueobject.TransactionTip: 12},
+			},
+		},
+		{
+			name: "amounts not matching the bill",
+			split: func(tab aggregate.Tab, ids map[string]uuid.UUID) OrderOption {
+				return SplitByAmount(map[uuid.UUID]float64{ids["Miku"]: 100, ids["Len"]: 10})
+			},
+			expectedErr: ErrInvalidSplit,
+		},
+		{
+			name: "line left unpaid",
+			split: func(tab aggregate.Tab, ids map[string]uuid.UUID) OrderOption {
+				return SplitByItem(map[uuid.UUID][]ItemRef{
+					ids["Miku"]: {{OrderID: tab.GetOrders()[0].GetID(), Line: 0}},
+				})
+			},
+			expectedErr: ErrInvalidSplit,
+		},
+		{
+			name: "same customer twice",
+			split: func(tab aggregate.Tab, ids map[string]uuid.UUID) OrderOption {
+				return SplitEvenly(ids["Miku"], ids["Miku"])
+			},
+			expectedErr: ErrInvalidSplit,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os, err := NewOrderService(
+				WithMemoryCustomerRepository(),
+				WithMemoryProductRepository(products),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tavern, err := NewTavernService(
+				WithOrderService(os),
+				WithMemoryTabRepository(),
+				WithPercentageServiceCharge(10, false),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := map[string]uuid.UUID{}
+			for _, name := range []string{"Miku", "Rin", "Len"} {
+				c, err := aggregate.NewCustomer(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := os.customerRepo.Add(ctx, c); err != nil {
+					t.Fatal(err)
+				}
+				ids[name] = c.GetID()
+			}
+
+			tab, err := tavern.OpenTab(ctx, ids["Miku"], "1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tavern.AddToTab(ctx, tab.GetID(), ItemsOf(beer.GetID())); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tavern.AddToTab(ctx, tab.GetID(), ItemsOf(peanut.GetID(), bakso.GetID())); err != nil {
+				t.Fatal(err)
+			}
+			tab, err = tavern.tabRepo.Get(ctx, tab.GetID())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = tavern.CloseTab(ctx, tab.GetID(), WithTip(1), tc.split(tab, ids))
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+
+			got := payments{}
+			for name, id := range ids {
+				c, err := os.customerRepo.Get(ctx, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, tr := range c.GetTransactions() {
+					if got[name] == nil {
+						got[name] = map[valueobject.TransactionKind]int{}
+					}
+					got[name][tr.Kind()] += tr.Amount()
+				}
+			}
+
+			if tc.expectedErr != nil {
+				if len(got) != 0 {
+					t.Errorf("expected nobody to be charged, got %v", got)
+				}
+				if _, err := tavern.tabRepo.Get(ctx, tab.GetID()); err != nil {
+					t.Errorf("expected the tab to stay open, got %v", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected payments %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
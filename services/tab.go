@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/tab"
+	"golang-learn-ddd/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OpenTab starts a running bill for the customer at the table
+func (s *TavernService) OpenTab(ctx context.Context, customerID uuid.UUID, table string) (t aggregate.Tab, err error) {
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.OpenTab",
+		attribute.Stringer("customer.id", customerID),
+		attribute.String("tab.table", table),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	if s.tabRepo == nil {
+		return aggregate.Tab{}, fmt.Errorf("tabs are not kept: %w", tab.ErrFailedToAddTab)
+	}
+	if _, err := s.OrderService.customerRepo.Get(ctx, customerID); err != nil {
+		s.logger.Error("failed to open a tab", "customer_id", customerID, "table", table, "error", err)
+		return aggregate.Tab{}, err
+	}
+
+	t, err = aggregate.NewTab(customerID, table, s.OrderService.now())
+	if err != nil {
+		return aggregate.Tab{}, err
+	}
+
+	if err := s.tabRepo.Add(ctx, t); err != nil {
+		s.logger.Error("failed to open a tab", "customer_id", customerID, "table", table, "error", err)
+		return aggregate.Tab{}, err
+	}
+
+	s.logger.Info("tab opened", "tab_id", t.GetID(), "customer_id", customerID, "table", table)
+
+	return t, nil
+}
+
+// AddToTab orders the items for the customer of the tab, without billing them
+func (s *TavernService) AddToTab(ctx context.Context, tabID uuid.UUID, items []OrderItem, opts ...OrderOption) (order aggregate.Order, err error) {
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.AddToTab", attribute.Stringer("tab.id", tabID))
+	defer func() { tracing.End(span, err) }()
+
+	if s.tabRepo == nil {
+		return aggregate.Order{}, fmt.Errorf("tabs are not kept: %w", tab.ErrTabNotFound)
+	}
+	t, err := s.tabRepo.Get(ctx, tabID)
+	if err != nil {
+		s.logger.Error("failed to add to the tab", "tab_id", tabID, "error", err)
+		return aggregate.Order{}, err
+	}
+	if !t.IsOpen() {
+		return aggregate.Order{}, aggregate.ErrTabClosed
+	}
+
+	order, err = s.OrderService.CreateOrder(ctx, t.GetCustomerID(), items, opts...)
+	if err != nil {
+		s.logger.Error("failed to add to the tab", "tab_id", tabID, "customer_id", t.GetCustomerID(), "error", err)
+		return aggregate.Order{}, err
+	}
+
+	err = s.updateTab(ctx, tabID, func(t *aggregate.Tab) error {
+		return t.AddOrder(order)
+	})
+	if err != nil {
+		// the order never reaches the bill, its coupon and points are not spent
+		s.OrderService.releaseRedemptions(ctx, order)
+		s.logger.Error("failed to add to the tab", "tab_id", tabID, "order_id", order.GetID(), "error", err)
+		return aggregate.Order{}, err
+	}
+
+	return order, nil
+}
+
+// CloseTab settles every order of the tab in one charge, with the service
+// charge and the tip given with WithTip, and forgets the tab
+func (s *TavernService) CloseTab(ctx context.Context, tabID uuid.UUID, opts ...OrderOption) (t aggregate.Tab, err error) {
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.CloseTab", attribute.Stringer("tab.id", tabID))
+	defer func() { tracing.End(span, err) }()
+
+	req := orderRequest{}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if req.tip < 0 {
+		return aggregate.Tab{}, ErrInvalidTip
+	}
+	if s.tabRepo == nil {
+		return aggregate.Tab{}, fmt.Errorf("tabs are not kept: %w", tab.ErrTabNotFound)
+	}
+
+	// the tab is closed before it is billed, so a concurrent close or a
+	// retry finds it closed instead of billing it again
+	err = s.updateTab(ctx, tabID, func(closing *aggregate.Tab) error {
+		if err := closing.Close(s.OrderService.now()); err != nil {
+			return err
+		}
+		t = *closing
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("failed to close the tab", "tab_id", tabID, "error", err)
+		return aggregate.Tab{}, err
+	}
+
+	if err := s.bill(ctx, t.GetCustomerID(), t.GetOrders(), req); err != nil {
+		if !errors.Is(err, ErrNotCharged) {
+			// some of the bill could not be given back, the tab stays closed
+			// so it is not billed again before it is settled by hand
+			s.logger.Error("failed to settle the tab", "tab_id", tabID, "customer_id", t.GetCustomerID(), "error", err)
+			return aggregate.Tab{}, err
+		}
+
+		// nothing was charged, the tab takes orders again and can be closed later
+		if err := s.updateTab(ctx, tabID, func(t *aggregate.Tab) error {
+			t.Reopen()
+			return nil
+		}); err != nil {
+			s.logger.Error("failed to reopen the unsettled tab", "tab_id", tabID, "error", err)
+		}
+		return aggregate.Tab{}, err
+	}
+
+	// the tab is settled whether or not it is forgotten, a closed tab is never billed again
+	if err := s.tabRepo.Delete(ctx, tabID); err != nil {
+		s.logger.Error("failed to forget the settled tab", "tab_id", tabID, "error", err)
+	}
+
+	s.earnPoints(ctx, t.GetCustomerID(), t.GetOrders()...)
+
+	s.logger.Info("tab closed", "tab_id", tabID, "customer_id", t.GetCustomerID(), "orders", len(t.GetOrders()), "total", t.GetTotal())
+
+	return t, nil
+}
+
+// updateTab changes the tab, so no order added concurrently is lost
+func (s *TavernService) updateTab(ctx context.Context, tabID uuid.UUID, change func(t *aggregate.Tab) error) error {
+	return update(
+		func() (aggregate.Tab, error) { return s.tabRepo.Get(ctx, tabID) },
+		change,
+		func(t aggregate.Tab) error { return s.tabRepo.Update(ctx, t) },
+		tab.ErrConcurrentUpdate,
+	)
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	customerMemory "golang-learn-ddd/domain/customer/memory"
+	"golang-learn-ddd/domain/payment/fake"
+	"golang-learn-ddd/domain/tab"
+	tabMemory "golang-learn-ddd/domain/tab/memory"
+	"golang-learn-ddd/valueobject"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func Test_TavernServiceTab(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tavern, err := NewTavernService(
+		WithOrderService(os),
+		WithMemoryTabRepository(),
+		WithPercentageServiceCharge(10, true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("SeeU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tavern.OpenTab(ctx, uuid.New(), "4"); err == nil {
+		t.Error("expected an unknown customer not to open a tab")
+	}
+
+	opened, err := tavern.OpenTab(ctx, cust.GetID(), "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// peanuts then bakso, nothing is billed until the tab is closed
+	for _, p := range []aggregate.Product{products[1], products[2]} {
+		if _, err := tavern.AddToTab(ctx, opened.GetID(), ItemsOf(p.GetID())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cust, err = os.customerRepo.Get(ctx, cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cust.GetTransactions()) != 0 {
+		t.Errorf("expected no transaction while the tab is open, got %d", len(cust.GetTransactions()))
+	}
+
+	closed, err := tavern.CloseTab(ctx, opened.GetID(), WithTip(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.IsOpen() || len(closed.GetOrders()) != 2 || closed.GetTotal() != 14 {
+		t.Errorf("expected a closed tab of 2 orders totalling 14, got %d orders totalling %v", len(closed.GetOrders()), closed.GetTotal())
+	}
+
+	cust, err = os.customerRepo.Get(ctx, cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[valueobject.TransactionKind]int{}
+	for _, tr := range cust.GetTransactions() {
+		got[tr.Kind()] += tr.Amount()
+	}
+	expected := map[valueobject.TransactionKind]int{
+		valueobject.TransactionPayment:       1400,
+		valueobject.TransactionServiceCharge: 140,
+		valueobject.TransactionTip:           100,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected one charge of %v, got %v", expected, got)
+	}
+	if len(cust.GetTransactions()) != 3 {
+		t.Errorf("expected 3 transactions, got %d", len(cust.GetTransactions()))
+	}
+
+	if _, err := tavern.AddToTab(ctx, opened.GetID(), ItemsOf(products[1].GetID())); !errors.Is(err, tab.ErrTabNotFound) {
+		t.Errorf("expected error %v, got %v", tab.ErrTabNotFound, err)
+	}
+	if _, err := tavern.CloseTab(ctx, opened.GetID()); !errors.Is(err, tab.ErrTabNotFound) {
+		t.Errorf("expected error %v, got %v", tab.ErrTabNotFound, err)
+	}
+}
+
+// forgetfulTabRepository fails to delete tabs
+type forgetfulTabRepository struct {
+	tab.TabRepository
+}
+
+func (r forgetfulTabRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return tab.ErrDeleteTab
+}
+
+// stuckTabRepository fails to update tabs
+type stuckTabRepository struct {
+	tab.TabRepository
+}
+
+func (r stuckTabRepository) Update(ctx context.Context, t aggregate.Tab) error {
+	return tab.ErrUpdateTab
+}
+
+func Test_TavernServiceTabConcurrently(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	peanut := products[1]
+
+	type testCase struct {
+		name    string
+		tabRepo tab.TabRepository
+	}
+	tests := []testCase{
+		{name: "forgotten once settled", tabRepo: tabMemory.New()},
+		{name: "kept closed when it cannot be forgotten", tabRepo: forgetfulTabRepository{tabMemory.New()}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os, err := NewOrderService(
+				WithMemoryCustomerRepository(),
+				WithMemoryProductRepository(products),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tavern, err := NewTavernService(WithOrderService(os), WithTabRepository(tc.tabRepo))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cust, err := aggregate.NewCustomer("SeeU")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.customerRepo.Add(ctx, cust); err != nil {
+				t.Fatal(err)
+			}
+			opened, err := tavern.OpenTab(ctx, cust.GetID(), "4")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// no order added concurrently is lost
+			var wg sync.WaitGroup
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := tavern.AddToTab(ctx, opened.GetID(), ItemsOf(peanut.GetID())); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			// the tab is billed once, whoever closes it and however often
+			closes := make(chan error, 5)
+			for i := 0; i < 5; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					closed, err := tavern.CloseTab(ctx, opened.GetID())
+					if err == nil && len(closed.GetOrders()) != 5 {
+						t.Errorf("expected 5 orders on the tab, got %d", len(closed.GetOrders()))
+					}
+					closes <- err
+				}()
+			}
+			wg.Wait()
+			close(closes)
+
+			var settled int
+			for err := range closes {
+				switch {
+				case err == nil:
+					settled++
+				case !errors.Is(err, aggregate.ErrTabClosed) && !errors.Is(err, tab.ErrTabNotFound):
+					t.Errorf("expected the tab closed or gone, got %v", err)
+				}
+			}
+			if _, err := tavern.CloseTab(ctx, opened.GetID()); err == nil {
+				t.Error("expected a settled tab not to close again")
+			}
+
+			cust, err = os.customerRepo.Get(ctx, cust.GetID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if settled != 1 || len(cust.GetTransactions()) != 1 || cust.GetTransactions()[0].Amount() != 5*1250 {
+				t.Errorf("expected one charge of 5 orders, got %d settled and %v", settled, cust.GetTransactions())
+			}
+		})
+	}
+}
+
+func Test_TavernServiceTabReopened(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	peanut := products[1]
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os), WithMemoryTabRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("SeeU")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := tavern.OpenTab(ctx, cust.GetID(), "4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.AddToTab(ctx, opened.GetID(), ItemsOf(peanut.GetID())); err != nil {
+		t.Fatal(err)
+	}
+
+	// the wallet is empty, nothing is charged and the tab stays open
+	if _, err := tavern.CloseTab(ctx, opened.GetID(), PayFromWallet()); !errors.Is(err, aggregate.ErrInsufficientFunds) {
+		t.Fatalf("expected error %v, got %v", aggregate.ErrInsufficientFunds, err)
+	}
+	if _, err := tavern.AddToTab(ctx, opened.GetID(), ItemsOf(peanut.GetID())); err != nil {
+		t.Fatal(err)
+	}
+
+	closed, err := tavern.CloseTab(ctx, opened.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(closed.GetOrders()) != 2 {
+		t.Errorf("expected 2 orders on the tab, got %d", len(closed.GetOrders()))
+	}
+}
+
+// unluckyCustomerRepository fails to update the customers, and takes the
+// payment gateway down with it
+type unluckyCustomerRepository struct {
+	customer.CustomerRepository
+	gateway *fake.Gateway
+}
+
+func (r unluckyCustomerRepository) Update(ctx context.Context, c aggregate.Customer) error {
+	r.gateway.FailNext(maxGatewayAttempts)
+	return customer.ErrUpdateCustomer
+}
+
+func Test_TavernServiceTabHeld(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	peanut := products[1]
+
+	gateway, err := fake.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	customers := customerMemory.New()
+	os, err := NewOrderService(
+		WithCustomerRepository(unluckyCustomerRepository{customers, gateway}),
+		WithMemoryProductRepository(products),
+		WithOrderPaymentGateway(gateway),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os), WithMemoryTabRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Lily")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := customers.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := tavern.OpenTab(ctx, cust.GetID(), "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.AddToTab(ctx, opened.GetID(), ItemsOf(peanut.GetID())); err != nil {
+		t.Fatal(err)
+	}
+
+	// the card is charged but cannot be refunded, the tab stays closed
+	_, err = tavern.CloseTab(ctx, opened.GetID())
+	if err == nil || errors.Is(err, ErrNotCharged) {
+		t.Fatalf("expected the bill to fail with a charge held, got %v", err)
+	}
+	if _, err := tavern.AddToTab(ctx, opened.GetID(), ItemsOf(peanut.GetID())); !errors.Is(err, aggregate.ErrTabClosed) {
+		t.Errorf("expected error %v, got %v", aggregate.ErrTabClosed, err)
+	}
+	if _, err := tavern.CloseTab(ctx, opened.GetID()); !errors.Is(err, aggregate.ErrTabClosed) {
+		t.Errorf("expected error %v, got %v", aggregate.ErrTabClosed, err)
+	}
+	if n := len(gateway.Payments(cust.GetID())); n != 1 {
+		t.Errorf("expected the tab to be charged once, got %d payments", n)
+	}
+}
+
+func Test_TavernServiceTabNotKept(t *testing.T) {
+	ctx := context.Background()
+
+	os, err := NewOrderService(WithMemoryCustomerRepository())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tavern.OpenTab(ctx, uuid.New(), "4"); !errors.Is(err, tab.ErrFailedToAddTab) {
+		t.Errorf("expected error %v, got %v", tab.ErrFailedToAddTab, err)
+	}
+	if _, err := tavern.AddToTab(ctx, uuid.New(), nil); !errors.Is(err, tab.ErrTabNotFound) {
+		t.Errorf("expected error %v, got %v", tab.ErrTabNotFound, err)
+	}
+	if _, err := tavern.CloseTab(ctx, uuid.New()); !errors.Is(err, tab.ErrTabNotFound) {
+		t.Errorf("expected error %v, got %v", tab.ErrTabNotFound, err)
+	}
+}
@@ -1,21 +1,103 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/category"
+	"golang-learn-ddd/domain/customer"
+	"golang-learn-ddd/domain/idempotency"
+	idempotencyMemory "golang-learn-ddd/domain/idempotency/memory"
+	idempotencyMongo "golang-learn-ddd/domain/idempotency/mongo"
+	"golang-learn-ddd/domain/invoice"
+	invoiceMemory "golang-learn-ddd/domain/invoice/memory"
+	invoiceMongo "golang-learn-ddd/domain/invoice/mongo"
+	"golang-learn-ddd/domain/payment"
+	"golang-learn-ddd/domain/product"
+	"golang-learn-ddd/domain/receipt"
+	"golang-learn-ddd/domain/tab"
+	tabMemory "golang-learn-ddd/domain/tab/memory"
+	tabMongo "golang-learn-ddd/domain/tab/mongo"
+	"golang-learn-ddd/tracing"
+	"golang-learn-ddd/valueobject"
+	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var (
+	ErrInvalidServiceCharge = errors.New("a service charge has to be a positive percentage (up to 100) or amount")
+	ErrInvalidTip           = errors.New("a tip cannot be negative")
+	ErrNotCharged           = errors.New("nobody was charged for the bill")
+	ErrOrderNotKept         = errors.New("the order is paid but could not be kept")
+
+	// errPaymentsHeld tells a failed charge whose payments could not be released
+	errPaymentsHeld = errors.New("the payments are still held")
 )
 
 type TavernConfiguration func(s *TavernService) error
 
 type TavernService struct {
+	// id receives the payments of the customers
+	id uuid.UUID
+
 	OrderService *OrderService
+	MenuService  *MenuService
 
 	BillingService interface{}
+
+	serviceCharge ServiceCharge
+
+	// gateway charges the cards of the payers, the one of the order service
+	// when the tavern has none of its own
+	gateway payment.PaymentGateway
+
+	tabRepo tab.TabRepository
+
+	// header is printed on the receipts, numbered by invoiceRepo
+	header      receipt.Header
+	invoiceRepo invoice.InvoiceRepository
+
+	// resultRepo keeps the orders placed with an idempotency key
+	resultRepo idempotency.ResultRepository
+
+	logger Logger
+	tracer trace.Tracer
+}
+
+// ServiceCharge is added to the bill of every order, either as a percentage
+// of the order after its discounts and before taxes, or as a fixed amount
+type ServiceCharge struct {
+	Percent float64
+	Amount  float64
+	// WaiveForTakeaway does not charge takeaway orders
+	WaiveForTakeaway bool
+}
+
+// For computes the service charge of the orders, billed together
+func (c ServiceCharge) For(takeaway bool, orders ...aggregate.Order) float64 {
+	if len(orders) == 0 || (takeaway && c.WaiveForTakeaway) {
+		return 0
+	}
+
+	var base float64
+	for _, o := range orders {
+		base += o.GetSubtotal() - o.GetOrderDiscount()
+	}
+
+	return aggregate.RoundMoney(base*c.Percent/100 + c.Amount)
 }
 
 func NewTavernService(cfgs ...TavernConfiguration) (*TavernService, error) {
-	s := &TavernService{}
+	s := &TavernService{
+		id:     uuid.New(),
+		logger: noopLogger{},
+		tracer: noop.NewTracerProvider().Tracer(tracing.InstrumentationName),
+	}
 
 	for _, cfg := range cfgs {
 		if err := cfg(s); err != nil {
@@ -33,13 +115,648 @@ func WithOrderService(os *OrderService) TavernConfiguration {
 	}
 }
 
-func (s *TavernService) Order(customer uuid.UUID, products []uuid.UUID) error {
-	price, err := s.OrderService.CreateOrder(customer, products)
+func WithMenuService(ms *MenuService) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.MenuService = ms
+		return nil
+	}
+}
+
+// WithPercentageServiceCharge charges percent (0-100) of every order
+func WithPercentageServiceCharge(percent float64, waiveForTakeaway bool) TavernConfiguration {
+	return func(s *TavernService) error {
+		if percent <= 0 || percent > 100 {
+			return ErrInvalidServiceCharge
+		}
+
+		s.serviceCharge = ServiceCharge{Percent: percent, WaiveForTakeaway: waiveForTakeaway}
+		return nil
+	}
+}
+
+// WithFixedServiceCharge charges the same amount for every order
+func WithFixedServiceCharge(amount float64, waiveForTakeaway bool) TavernConfiguration {
+	return func(s *TavernService) error {
+		if amount <= 0 {
+			return ErrInvalidServiceCharge
+		}
+
+		s.serviceCharge = ServiceCharge{Amount: amount, WaiveForTakeaway: waiveForTakeaway}
+		return nil
+	}
+}
+
+func WithMemoryTabRepository() TavernConfiguration {
+	return WithTabRepository(tabMemory.New())
+}
+
+func WithMongoTabRepository(ctx context.Context, connectionString string) TavernConfiguration {
+	return func(s *TavernService) error {
+		repo, err := tabMongo.New(ctx, connectionString)
+		if err != nil {
+			return err
+		}
+
+		s.tabRepo = repo
+
+		return nil
+	}
+}
+
+func WithTabRepository(tabRepo tab.TabRepository) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.tabRepo = tabRepo
+		return nil
+	}
+}
+
+// WithTavernID identifies the tavern, so its invoices keep their numbers across restarts
+func WithTavernID(id uuid.UUID) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.id = id
+		return nil
+	}
+}
+
+func WithReceiptHeader(header receipt.Header) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.header = header
+		return nil
+	}
+}
+
+func WithMemoryInvoiceRepository() TavernConfiguration {
+	return WithInvoiceRepository(invoiceMemory.New())
+}
+
+func WithMongoInvoiceRepository(ctx context.Context, connectionString string) TavernConfiguration {
+	return func(s *TavernService) error {
+		repo, err := invoiceMongo.New(ctx, connectionString)
+		if err != nil {
+			return err
+		}
+
+		s.invoiceRepo = repo
+
+		return nil
+	}
+}
+
+func WithInvoiceRepository(invoiceRepo invoice.InvoiceRepository) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.invoiceRepo = invoiceRepo
+		return nil
+	}
+}
+
+// WithMemoryResultRepository keeps the orders placed with an idempotency key for ttl
+func WithMemoryResultRepository(ttl time.Duration) TavernConfiguration {
+	return func(s *TavernService) error {
+		repo, err := idempotencyMemory.New(ttl)
+		if err != nil {
+			return err
+		}
+
+		s.resultRepo = repo
+
+		return nil
+	}
+}
+
+func WithMongoResultRepository(ctx context.Context, connectionString string, ttl time.Duration) TavernConfiguration {
+	return func(s *TavernService) error {
+		repo, err := idempotencyMongo.New(ctx, connectionString, ttl)
+		if err != nil {
+			return err
+		}
+
+		s.resultRepo = repo
+
+		return nil
+	}
+}
+
+func WithResultRepository(resultRepo idempotency.ResultRepository) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.resultRepo = resultRepo
+		return nil
+	}
+}
+
+// WithPaymentGateway charges the bills on the cards of the payers, unless
+// they pay from their wallet. Refunds go back to the cards through the
+// gateway of the order service, given with WithOrderPaymentGateway.
+func WithPaymentGateway(gateway payment.PaymentGateway) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.gateway = gateway
+		return nil
+	}
+}
+
+func WithTavernLogger(logger Logger) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.logger = logger
+		return nil
+	}
+}
+
+func WithTavernTracer(tracer trace.Tracer) TavernConfiguration {
+	return func(s *TavernService) error {
+		s.tracer = tracer
+		return nil
+	}
+}
+
+func (s *TavernService) GetID() uuid.UUID {
+	return s.id
+}
+
+// Order creates the order and bills the customer, with the service charge
+// and the tip given with WithTip. An order failing before anybody is charged
+// returns an ErrNotCharged error. An order given WithIdempotencyKey is only
+// placed once: its retries get the order first placed.
+func (s *TavernService) Order(ctx context.Context, customer uuid.UUID, items []OrderItem, opts ...OrderOption) (order aggregate.Order, err error) {
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.Order", attribute.Stringer("customer.id", customer))
+	defer func() { tracing.End(span, err) }()
+
+	req := orderRequest{}
+	for _, opt := range opts {
+		opt(&req)
+	}
+	if req.tip < 0 {
+		return aggregate.Order{}, ErrInvalidTip
+	}
+
+	if req.idempotencyKey != "" {
+		return s.orderOnce(ctx, customer, items, req, opts)
+	}
+
+	return s.order(ctx, customer, items, req, opts)
+}
+
+func (s *TavernService) order(ctx context.Context, customer uuid.UUID, items []OrderItem, req orderRequest, opts []OrderOption) (aggregate.Order, error) {
+	order, err := s.OrderService.CreateOrder(ctx, customer, items, opts...)
+	if err != nil {
+		s.logger.Error("failed to order", "customer_id", customer, "items", len(items), "error", err)
+		return aggregate.Order{}, fmt.Errorf("%w: %w", ErrNotCharged, err)
+	}
+
+	if err := s.bill(ctx, customer, []aggregate.Order{order}, req); err != nil {
+		if errors.Is(err, ErrNotCharged) {
+			s.OrderService.releaseRedemptions(ctx, order)
+		}
+		return aggregate.Order{}, err
+	}
+
+	s.earnPoints(ctx, customer, order)
+
+	return order, nil
+}
+
+// Menu lists the products matching query grouped by category
+func (s *TavernService) Menu(ctx context.Context, query product.Query) ([]MenuSection, error) {
+	if s.MenuService == nil {
+		return nil, fmt.Errorf("no menu service: %w", category.ErrCategoryNotFound)
+	}
+
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.Menu")
+	sections, err := s.MenuService.Menu(ctx, query)
+	tracing.End(span, err)
+
+	return sections, err
+}
+
+// bill charges the customer once for the orders, recording the payment,
+// the service charge and the tip as separate transactions of the customer
+// so tips are not counted as revenue. When the bill is split, each payer
+// gets their share of every transaction, allocated to the cent. A bill that
+// fails without leaving anybody charged returns an ErrNotCharged error.
+func (s *TavernService) bill(ctx context.Context, customerID uuid.UUID, orders []aggregate.Order, req orderRequest) (err error) {
+	var total float64
+	orderIDs := make([]uuid.UUID, 0, len(orders))
+	for _, o := range orders {
+		total += o.GetTotal()
+		orderIDs = append(orderIDs, o.GetID())
+	}
+	total = aggregate.RoundMoney(total)
+	serviceCharge := s.serviceCharge.For(req.takeaway, orders...)
+	tip := aggregate.RoundMoney(req.tip)
+
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.Bill",
+		attribute.Float64("order.total", total),
+		attribute.Float64("order.service_charge", serviceCharge),
+		attribute.Float64("order.tip", tip),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	charged := false
+	defer func() {
+		if err != nil && !charged {
+			err = fmt.Errorf("%w: %w", ErrNotCharged, err)
+		}
+	}()
+
+	payers, weights := []uuid.UUID{customerID}, []int{1}
+	if req.split != nil {
+		payers, weights, err = req.split(orders, valueobject.Cents(total))
+		if err != nil {
+			s.logger.Error("failed to split the bill", "customer_id", customerID, "order_ids", orderIDs, "error", err)
+			return err
+		}
+	}
+
+	now := s.OrderService.now()
+
+	charges := make([][]valueobject.Transaction, len(payers))
+	for _, charge := range []struct {
+		kind   valueobject.TransactionKind
+		amount float64
+	}{
+		{valueobject.TransactionPayment, total},
+		{valueobject.TransactionServiceCharge, serviceCharge},
+		{valueobject.TransactionTip, tip},
+	} {
+		if charge.amount <= 0 {
+			continue
+		}
+
+		shares, err := valueobject.Allocate(valueobject.Cents(charge.amount), weights)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidSplit, err)
+		}
+
+		for i, payer := range payers {
+			if shares[i] == 0 {
+				continue
+			}
+			charges[i] = append(charges[i], valueobject.NewTransaction(charge.kind, shares[i], payer, s.id, now))
+		}
+	}
+
+	pay := func(i int) func(c *aggregate.Customer) error {
+		return func(c *aggregate.Customer) error {
+			if req.wallet {
+				var due int
+				for _, t := range charges[i] {
+					due += t.Amount()
+				}
+				if due > 0 {
+					if err := c.Withdraw(float64(due)/100, now); err != nil {
+						return fmt.Errorf("customer %s cannot pay %d cents from the wallet: %w", c.GetID(), due, err)
+					}
+				}
+			}
+
+			for _, t := range charges[i] {
+				c.AddTransaction(t)
+			}
+
+			if evaluator := s.OrderService.membership; evaluator != nil {
+				c.SetTier(string(evaluator.Evaluate(*c)))
+			}
+			return nil
+		}
+	}
+
+	// every payer is checked before any is charged, so an unknown payer or
+	// a short wallet charges nobody
+	customerRepo := s.OrderService.customerRepo
+	customers := make([]aggregate.Customer, len(payers))
+	for i, payer := range payers {
+		c, err := customerRepo.Get(ctx, payer)
+		if err != nil {
+			s.logger.Error("failed to bill the customer", "customer_id", payer, "error", err)
+			return err
+		}
+		customers[i] = c
+	}
+
+	var payments []payment.Payment
+	if s.paymentGateway() != nil && !req.wallet {
+		payments, err = s.charge(ctx, payers, charges)
+		if err != nil {
+			charged = errors.Is(err, errPaymentsHeld)
+			s.logger.Error("failed to charge the bill", "customer_id", customerID, "order_ids", orderIDs, "error", err)
+			return err
+		}
+		for i := range charges {
+			for j := range charges[i] {
+				charges[i][j] = charges[i][j].WithReference(payments[i].ID)
+			}
+		}
+	}
+
+	for i, payer := range payers {
+		if err := pay(i)(&customers[i]); err != nil {
+			charged = !s.release(ctx, payments)
+			s.logger.Error("failed to bill the customer", "customer_id", payer, "error", err)
+			return err
+		}
+	}
+
+	for i, payer := range payers {
+		err := customerRepo.Update(ctx, customers[i])
+		if errors.Is(err, customer.ErrConcurrentUpdate) {
+			err = s.OrderService.updateCustomer(ctx, payer, pay(i))
+		}
+		if err != nil {
+			// the bill is all or nothing, the payers before were charged and
+			// recorded so they are given their money back
+			released := true
+			if payments != nil {
+				released = s.release(ctx, payments[i:])
+			}
+			givenBack := s.giveBackBill(ctx, payers[:i], charges[:i], payments, req.wallet, now)
+			charged = !released || !givenBack
+			err = fmt.Errorf("failed to record the transactions of orders %v: %w", orderIDs, err)
+			s.logger.Error("failed to bill the customer", "customer_id", payer, "error", err)
+			return err
+		}
+	}
+
+	// the payers are charged, a bill whose orders are not kept fails without
+	// being given back so the orders can be kept by hand
+	charged = true
+	if err := s.keepOrders(ctx, orders, charges, req, now); err != nil {
+		s.logger.Error("failed to keep the paid orders", "customer_id", customerID, "order_ids", orderIDs, "error", err)
+		return err
+	}
+
+	s.logger.Info("bill the customer",
+		"customer_id", customerID,
+		"payers", payers,
+		"order_ids", orderIDs,
+		"total", total,
+		"service_charge", serviceCharge,
+		"tip", tip,
+	)
+
+	return nil
+}
+
+// charge authorizes the share of every payer on the payment gateway before
+// capturing them all, so a declined card charges nobody: the payments held
+// or captured already are released. The payments are returned by payer,
+// empty for payers with nothing to pay.
+func (s *TavernService) charge(ctx context.Context, payers []uuid.UUID, charges [][]valueobject.Transaction) (payments []payment.Payment, err error) {
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.Charge")
+	defer func() { tracing.End(span, err) }()
+
+	gateway := s.paymentGateway()
+
+	// every charge gets its own keys, a retried bill is a new charge
+	chargeID := uuid.New()
+	key := func(i int, call string) string {
+		return fmt.Sprintf("%s:%d:%s", chargeID, i, call)
+	}
+
+	payments = make([]payment.Payment, len(payers))
+	for i, payer := range payers {
+		var due int
+		for _, t := range charges[i] {
+			due += t.Amount()
+		}
+		if due == 0 {
+			continue
+		}
+
+		p, err := s.OrderService.callGateway(ctx, func() (payment.Payment, error) {
+			return gateway.Authorize(ctx, key(i, "authorize"), payer, due)
+		})
+		if err != nil {
+			return nil, s.failCharge(ctx, payments, fmt.Errorf("failed to authorize %d cents for customer %s: %w", due, payer, err))
+		}
+		payments[i] = p
+	}
+
+	for i, p := range payments {
+		if p.ID == "" {
+			continue
+		}
+
+		captured, err := s.OrderService.callGateway(ctx, func() (payment.Payment, error) {
+			return gateway.Capture(ctx, key(i, "capture"), p.ID, p.Amount)
+		})
+		if err != nil {
+			return nil, s.failCharge(ctx, payments, fmt.Errorf("failed to capture payment %s of customer %s: %w", p.ID, payers[i], err))
+		}
+		payments[i] = captured
+	}
+
+	return payments, nil
+}
+
+// paymentGateway is the gateway charging the cards, nil when bills are only recorded
+func (s *TavernService) paymentGateway() payment.PaymentGateway {
+	if s.gateway != nil {
+		return s.gateway
+	}
+	return s.OrderService.gateway
+}
+
+// failCharge releases the payments of a failed charge, its error tells
+// whether some of them are still held
+func (s *TavernService) failCharge(ctx context.Context, payments []payment.Payment, err error) error {
+	if !s.release(ctx, payments) {
+		return fmt.Errorf("%w; %w", err, errPaymentsHeld)
+	}
+	return err
+}
+
+// release voids the authorized payments and refunds the captured ones. It
+// cannot fail the bill any further, failures are logged to be settled by hand.
+func (s *TavernService) release(ctx context.Context, payments []payment.Payment) (released bool) {
+	released = true
+	gateway := s.paymentGateway()
+	for _, p := range payments {
+		var err error
+		switch p.Status {
+		case payment.Authorized:
+			_, err = s.OrderService.callGateway(ctx, func() (payment.Payment, error) {
+				return gateway.Void(ctx, p.ID+":void", p.ID)
+			})
+		case payment.Captured:
+			_, err = s.OrderService.callGateway(ctx, func() (payment.Payment, error) {
+				return gateway.Refund(ctx, p.ID+":release", p.ID, p.Captured)
+			})
+		default:
+			continue
+		}
+		if err != nil {
+			s.logger.Error("failed to release the payment", "payment_id", p.ID, "customer_id", p.CustomerID, "status", p.Status, "error", err)
+			released = false
+		}
+	}
+
+	return released
+}
+
+// giveBackBill reverses the charges recorded for the payers of a bill that
+// could not be completed: their card payments are released, their wallets
+// refilled, and refunds recorded next to the charges. It reports whether
+// every payer got their money back, failures are logged.
+func (s *TavernService) giveBackBill(ctx context.Context, payers []uuid.UUID, charges [][]valueobject.Transaction, payments []payment.Payment, wallet bool, now time.Time) bool {
+	givenBack := true
+	if payments != nil {
+		givenBack = s.release(ctx, payments[:len(payers)])
+	}
+
+	for i, payer := range payers {
+		if len(charges[i]) == 0 {
+			continue
+		}
+
+		err := s.OrderService.updateCustomer(ctx, payer, func(c *aggregate.Customer) error {
+			var due int
+			for _, t := range charges[i] {
+				due += t.Amount()
+				kind := valueobject.TransactionRefund
+				if t.Kind() == valueobject.TransactionTip {
+					kind = valueobject.TransactionTipRefund
+				}
+				c.AddTransaction(valueobject.NewTransaction(kind, t.Amount(), s.id, payer, now).WithReference(t.Reference()))
+			}
+			if wallet {
+				if err := c.Deposit(float64(due)/100, now); err != nil {
+					return err
+				}
+			}
+
+			if evaluator := s.OrderService.membership; evaluator != nil {
+				c.SetTier(string(evaluator.Evaluate(*c)))
+			}
+			return nil
+		})
+		if err != nil {
+			s.logger.Error("failed to give the bill back", "customer_id", payer, "error", err)
+			givenBack = false
+		}
+	}
+
+	return givenBack
+}
+
+// keepOrders saves the billed orders with what every payer paid for each of
+// them, the payment, service charge and tip of a payer being shared between
+// the orders in proportion of their totals. Every order is tried, the first
+// failure is returned.
+func (s *TavernService) keepOrders(ctx context.Context, orders []aggregate.Order, charges [][]valueobject.Transaction, req orderRequest, now time.Time) (err error) {
+	orderRepo := s.OrderService.orderRepo
+	if orderRepo == nil {
+		return nil
+	}
+
+	method := aggregate.PaymentAccount
+	switch {
+	case req.wallet:
+		method = aggregate.PaymentWallet
+	case s.paymentGateway() != nil:
+		method = aggregate.PaymentCard
+	}
+
+	totals := make([]int, len(orders))
+	for i, o := range orders {
+		totals[i] = valueobject.Cents(o.GetTotal())
+	}
+
+	// the service charge and the tip are shared the same way, as extras
+	payments := make([][]valueobject.Transaction, len(orders))
+	extras := make([][]valueobject.Transaction, len(orders))
+	for _, transactions := range charges {
+		for _, t := range transactions {
+			shares, err := valueobject.Allocate(t.Amount(), totals)
+			if err != nil {
+				continue
+			}
+			for i, share := range shares {
+				if share == 0 {
+					continue
+				}
+				share := valueobject.NewTransaction(t.Kind(), share, t.From(), t.To(), t.CreatedAt()).WithReference(t.Reference())
+				if t.Kind() == valueobject.TransactionPayment {
+					payments[i] = append(payments[i], share)
+				} else {
+					extras[i] = append(extras[i], share)
+				}
+			}
+		}
+	}
+
+	for i, o := range orders {
+		keepErr := o.Pay(method, payments[i], now)
+		if keepErr == nil {
+			keepErr = o.AddExtras(extras[i])
+		}
+		if keepErr == nil {
+			keepErr = orderRepo.Add(ctx, o)
+		}
+		if keepErr != nil {
+			s.logger.Error("failed to keep the paid order", "order_id", o.GetID(), "customer_id", o.GetCustomerID(), "error", keepErr)
+			if err == nil {
+				err = fmt.Errorf("order %s: %w; %w", o.GetID(), ErrOrderNotKept, keepErr)
+			}
+		}
+	}
+
+	return err
+}
+
+// earnPoints credits the loyalty points of paid orders. The orders are paid
+// already so a failure is only logged, the points can be credited by hand.
+func (s *TavernService) earnPoints(ctx context.Context, customerID uuid.UUID, orders ...aggregate.Order) {
+	program := s.OrderService.loyalty
+	if program == nil {
+		return
+	}
+
+	var earned int
+	points := make([]int, len(orders))
+	for i, o := range orders {
+		points[i] = program.Earned(o)
+		earned += points[i]
+	}
+	if earned == 0 {
+		return
+	}
+
+	now := s.OrderService.now()
+	err := s.OrderService.updateCustomer(ctx, customerID, func(c *aggregate.Customer) error {
+		for i, o := range orders {
+			if points[i] == 0 {
+				continue
+			}
+			if err := c.EarnPoints(points[i], o.GetID(), now, program.ExpiresAt(now)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("failed to credit loyalty points", "customer_id", customerID, "points", earned, "error", err)
+		return
+	}
+
+	s.logger.Info("loyalty points earned", "customer_id", customerID, "points", earned)
+}
+
+// Deposit adds prepaid credit to the wallet of the customer, see PayFromWallet
+func (s *TavernService) Deposit(ctx context.Context, customerID uuid.UUID, amount float64) (err error) {
+	ctx, span := tracing.Start(ctx, s.tracer, "TavernService.Deposit",
+		attribute.Stringer("customer.id", customerID),
+		attribute.Float64("deposit.amount", amount),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	now := s.OrderService.now()
+	err = s.OrderService.updateCustomer(ctx, customerID, func(c *aggregate.Customer) error {
+		return c.Deposit(amount, now)
+	})
 	if err != nil {
+		s.logger.Error("failed to deposit", "customer_id", customerID, "amount", amount, "error", err)
 		return err
 	}
 
-	fmt.Printf("\nBill the customer: %.2f\n\n", price)
+	s.logger.Info("deposit", "customer_id", customerID, "amount", amount)
 
 	return nil
 }
@@ -1,10 +1,19 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"golang-learn-ddd/aggregate"
+	customerMemory "golang-learn-ddd/domain/customer/memory"
+	productMemory "golang-learn-ddd/domain/product/memory"
+	"golang-learn-ddd/tracing"
+	"golang-learn-ddd/valueobject"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func Test_TavernService(t *testing.T) {
@@ -28,7 +37,7 @@ func Test_TavernService(t *testing.T) {
 		t.Error(err)
 	}
 
-	if err := os.customerRepo.Add(cust); err != nil {
+	if err := os.customerRepo.Add(context.Background(), cust); err != nil {
 		t.Error(err)
 	}
 
@@ -36,7 +45,245 @@ func Test_TavernService(t *testing.T) {
 		products[0].GetID(),
 	}
 
-	if err := tavern.Order(cust.GetID(), order); err != nil {
+	if _, err := tavern.Order(context.Background(), cust.GetID(), ItemsOf(order...)); err != nil {
 		t.Error(err)
 	}
 }
+
+func Test_TavernServiceTracing(t *testing.T) {
+	ctx := context.Background()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewProvider(exporter)
+	tracer := provider.Tracer(tracing.InstrumentationName)
+
+	products := init_products(t)
+	productRepo := productMemory.New()
+	for _, p := range products {
+		if err := productRepo.Add(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	os, err := NewOrderService(
+		WithCustomerRepository(tracing.NewCustomerRepository(customerMemory.New(), tracer)),
+		WithProductRepository(tracing.NewProductRepository(productRepo, tracer)),
+		WithTracer(tracer),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tavern, err := NewTavernService(WithOrderService(os), WithTavernTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("SeeU")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	// only keep the spans of the order itself
+	if err := provider.ForceFlush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	exporter.Reset()
+
+	order := []uuid.UUID{
+		products[0].GetID(),
+		products[1].GetID(),
+	}
+
+	if _, err := tavern.Order(ctx, cust.GetID(), ItemsOf(order...)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.ForceFlush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+
+	byName := map[string]tracetest.SpanStub{}
+	counts := map[string]int{}
+	for _, span := range spans {
+		byName[span.Name] = span
+		counts[span.Name]++
+	}
+
+	expected := map[string]int{
+		"TavernService.Order":        1,
+		"OrderService.CreateOrder":   1,
+		"CustomerRepository.Get":     2,
+		"ProductRepository.GetByIDs": 1,
+		"TavernService.Bill":         1,
+		"CustomerRepository.Update":  1,
+	}
+	for name, count := range expected {
+		if counts[name] != count {
+			t.Errorf("expected %d %q spans, got %d", count, name, counts[name])
+		}
+	}
+
+	// every span belongs to the same trace and is nested below the tavern order
+	root := byName["TavernService.Order"]
+	for _, span := range spans {
+		if span.SpanContext.TraceID() != root.SpanContext.TraceID() {
+			t.Errorf("span %q is not part of the order trace", span.Name)
+		}
+	}
+	parents := map[string][]string{}
+	for _, span := range spans {
+		for _, parent := range spans {
+			if span.Parent.SpanID() == parent.SpanContext.SpanID() {
+				parents[span.Name] = append(parents[span.Name], parent.Name)
+			}
+		}
+	}
+	expectedParents := map[string][]string{
+		"CustomerRepository.Get":    {"OrderService.CreateOrder", "TavernService.Bill"},
+		"CustomerRepository.Update": {"TavernService.Bill"},
+		"TavernService.Bill":        {"TavernService.Order"},
+	}
+	for name, expected := range expectedParents {
+		got := append([]string(nil), parents[name]...)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %q to be a child of %v, got %v", name, expected, got)
+		}
+	}
+	if byName["OrderService.CreateOrder"].Parent.SpanID() != root.SpanContext.SpanID() {
+		t.Error("expected CreateOrder to be a child of the tavern order")
+	}
+}
+
+func Test_TavernServiceBilling(t *testing.T) {
+	products := init_products(t)
+
+	type testCase struct {
+		name     string
+		cfgs     []TavernConfiguration
+		opts     []OrderOption
+		expected map[valueobject.TransactionKind]int
+	}
+	tests := []testCase{
+		{
+			name:     "payment only",
+			expected: map[valueobject.TransactionKind]int{valueobject.TransactionPayment: 1400},
+		},
+		{
+			name: "percentage service charge and tip",
+			cfgs: []TavernConfiguration{WithPercentageServiceCharge(10, true)},
+			opts: []OrderOption{WithTip(2)},
+			expected: map[valueobject.TransactionKind]int{
+				valueobject.TransactionPayment:       1400,
+				valueobject.TransactionServiceCharge: 140,
+				valueobject.TransactionTip:           200,
+			},
+		},
+		{
+			name: "service charge waived for takeaway",
+			cfgs: []TavernConfiguration{WithPercentageServiceCharge(10, true)},
+			opts: []OrderOption{AsTakeaway()},
+			expected: map[valueobject.TransactionKind]int{
+				valueobject.TransactionPayment: 1400,
+			},
+		},
+		{
+			name: "fixed service charge on takeaway",
+			cfgs: []TavernConfiguration{WithFixedServiceCharge(1.5, false)},
+			opts: []OrderOption{AsTakeaway()},
+			expected: map[valueobject.TransactionKind]int{
+				valueobject.TransactionPayment:       1400,
+				valueobject.TransactionServiceCharge: 150,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os, err := NewOrderService(
+				WithMemoryCustomerRepository(),
+				WithMemoryProductRepository(products),
+				WithMemoryOrderRepository(),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tavern, err := NewTavernService(append([]TavernConfiguration{WithOrderService(os)}, tc.cfgs...)...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cust, err := aggregate.NewCustomer("SeeU")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.customerRepo.Add(context.Background(), cust); err != nil {
+				t.Fatal(err)
+			}
+
+			items := ItemsOf(products[1].GetID(), products[2].GetID())
+			order, err := tavern.Order(context.Background(), cust.GetID(), items, tc.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cust, err = os.customerRepo.Get(context.Background(), cust.GetID())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[valueobject.TransactionKind]int{}
+			for _, tr := range cust.GetTransactions() {
+				if tr.From() != cust.GetID() || tr.To() != tavern.GetID() {
+					t.Errorf("expected a transaction from the customer to the tavern, got %v to %v", tr.From(), tr.To())
+				}
+				got[tr.Kind()] += tr.Amount()
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected transactions %v, got %v", tc.expected, got)
+			}
+
+			// the order keeps what was paid along with it, for its receipt
+			kept, err := os.orderRepo.Get(context.Background(), order.GetID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if valueobject.Cents(kept.GetServiceCharge()) != tc.expected[valueobject.TransactionServiceCharge] ||
+				valueobject.Cents(kept.GetTip()) != tc.expected[valueobject.TransactionTip] {
+				t.Errorf("expected the order to keep the service charge and the tip of %v, got %v and %v", tc.expected, kept.GetServiceCharge(), kept.GetTip())
+			}
+		})
+	}
+}
+
+func Test_TavernServiceInvalidCharges(t *testing.T) {
+	for _, cfg := range []TavernConfiguration{
+		WithPercentageServiceCharge(0, false),
+		WithPercentageServiceCharge(120, false),
+		WithFixedServiceCharge(-1, false),
+	} {
+		if _, err := NewTavernService(cfg); !errors.Is(err, ErrInvalidServiceCharge) {
+			t.Errorf("expected error %v, got %v", ErrInvalidServiceCharge, err)
+		}
+	}
+
+	os, err := NewOrderService(WithMemoryCustomerRepository(), WithMemoryProductRepository(init_products(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tavern.Order(context.Background(), uuid.New(), nil, WithTip(-1)); !errors.Is(err, ErrInvalidTip) {
+		t.Errorf("expected error %v, got %v", ErrInvalidTip, err)
+	}
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	"golang-learn-ddd/domain/membership"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidTierJob = errors.New("a tier job needs a customer repository and an evaluator")
+
+	// errTierUnchanged stops the update of a customer whose tier is right already
+	errTierUnchanged = errors.New("the tier is unchanged")
+)
+
+// tierJobPageSize is the number of customers re-evaluated per repository call
+const tierJobPageSize = 100
+
+// TierJob re-evaluates the membership tier of every customer, billing only
+// re-evaluates the customers who pay so tiers also change with rule changes
+type TierJob struct {
+	customerRepo customer.CustomerRepository
+	evaluator    *membership.Evaluator
+	logger       Logger
+}
+
+type TierJobConfiguration func(j *TierJob) error
+
+func NewTierJob(customerRepo customer.CustomerRepository, evaluator *membership.Evaluator, cfgs ...TierJobConfiguration) (*TierJob, error) {
+	if customerRepo == nil || evaluator == nil {
+		return nil, ErrInvalidTierJob
+	}
+
+	j := &TierJob{
+		customerRepo: customerRepo,
+		evaluator:    evaluator,
+		logger:       noopLogger{},
+	}
+
+	for _, cfg := range cfgs {
+		if err := cfg(j); err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
+
+func WithTierJobLogger(logger Logger) TierJobConfiguration {
+	return func(j *TierJob) error {
+		j.logger = logger
+		return nil
+	}
+}
+
+// RunOnce re-evaluates every customer and returns how many changed tier. A
+// customer that cannot be updated does not stop the others, the error tells
+// how many failed.
+func (j *TierJob) RunOnce(ctx context.Context) (int, error) {
+	// collect the ids first, updating customers while paging could skip some
+	var ids []uuid.UUID
+	for offset := 0; ; offset += tierJobPageSize {
+		page, err := j.customerRepo.List(ctx, offset, tierJobPageSize)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, c := range page.Customers {
+			if string(j.evaluator.Evaluate(c)) != c.GetTier() {
+				ids = append(ids, c.GetID())
+			}
+		}
+
+		if offset+tierJobPageSize >= page.Total {
+			break
+		}
+	}
+
+	// a customer failing is retried on the next run, the others are re-evaluated anyway
+	var changed, failed int
+	var firstErr error
+	for _, id := range ids {
+		updated, err := j.reevaluate(ctx, id)
+		if err != nil {
+			j.logger.Error("failed to re-evaluate the tier", "customer_id", id, "error", err)
+			if ctx.Err() != nil {
+				return changed, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+		if updated {
+			changed++
+		}
+	}
+
+	if firstErr != nil {
+		j.logger.Info("tiers re-evaluated", "changed", changed, "failed", failed)
+		return changed, fmt.Errorf("%d of %d customers not re-evaluated: %w", failed, len(ids), firstErr)
+	}
+
+	j.logger.Info("tiers re-evaluated", "changed", changed)
+
+	return changed, nil
+}
+
+// Run re-evaluates the tiers every interval until ctx is done
+func (j *TierJob) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			j.logger.Error("tier job failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// reevaluate sets the tier of the latest version of the customer, retrying
+// when a bill updates the customer at the same time
+func (j *TierJob) reevaluate(ctx context.Context, id uuid.UUID) (bool, error) {
+	var from, to string
+	err := updateCustomer(ctx, j.customerRepo, id, func(c *aggregate.Customer) error {
+		from, to = c.GetTier(), string(j.evaluator.Evaluate(*c))
+		if from == to {
+			return errTierUnchanged
+		}
+
+		c.SetTier(to)
+		return nil
+	})
+	if errors.Is(err, errTierUnchanged) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	j.logger.Info("tier changed", "customer_id", id, "from", from, "to", to)
+
+	return true, nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	customerMemory "golang-learn-ddd/domain/customer/memory"
+	"golang-learn-ddd/domain/membership"
+	"golang-learn-ddd/valueobject"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Test_TierJob(t *testing.T) {
+	ctx := context.Background()
+	repo := customerMemory.New()
+
+	evaluator, err := membership.NewEvaluator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// synthetic histories of payments in cents, and the tier the customers have now
+	histories := []struct {
+		name     string
+		spend    int
+		tier     membership.Tier
+		expected membership.Tier
+	}{
+		{"Miku", 0, membership.NoTier, membership.NoTier},
+		{"Rin", 15000, membership.NoTier, membership.Bronze},
+		{"Len", 60000, membership.Bronze, membership.Silver},
+		{"Luka", 300000, membership.Gold, membership.Gold},
+		{"Kaito", 5000, membership.Silver, membership.NoTier},
+	}
+
+	ids := map[string]uuid.UUID{}
+	for _, h := range histories {
+		c, err := aggregate.NewCustomer(h.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.spend > 0 {
+			c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionPayment, h.spend, c.GetID(), uuid.New(), time.Now()))
+		}
+		c.SetTier(string(h.tier))
+
+		if err := repo.Add(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		ids[h.name] = c.GetID()
+	}
+
+	job, err := NewTierJob(repo, evaluator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := job.RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 3 {
+		t.Errorf("expected %d customers to change tier, got %d", 3, changed)
+	}
+
+	for _, h := range histories {
+		c, err := repo.Get(ctx, ids[h.name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if membership.Tier(c.GetTier()) != h.expected {
+			t.Errorf("expected %s to be %q, got %q", h.name, h.expected, c.GetTier())
+		}
+	}
+
+	// nothing changes when run again
+	if changed, err := job.RunOnce(ctx); err != nil || changed != 0 {
+		t.Errorf("expected no change on a second run, got %d, %v", changed, err)
+	}
+}
+
+func Test_TierJobContinuesPastFailures(t *testing.T) {
+	ctx := context.Background()
+
+	evaluator, err := membership.NewEvaluator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stuck := uuid.Nil
+	repo := stuckCustomerRepository{customerMemory.New(), &stuck}
+
+	ids := map[string]uuid.UUID{}
+	for _, name := range []string{"Miku", "Rin", "Len"} {
+		c, err := aggregate.NewCustomer(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.AddTransaction(valueobject.NewTransaction(valueobject.TransactionPayment, 15000, c.GetID(), uuid.New(), time.Now()))
+
+		if err := repo.Add(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = c.GetID()
+	}
+	stuck = ids["Rin"]
+
+	job, err := NewTierJob(repo, evaluator)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := job.RunOnce(ctx)
+	if !errors.Is(err, customer.ErrUpdateCustomer) {
+		t.Errorf("expected error %v, got %v", customer.ErrUpdateCustomer, err)
+	}
+	if changed != 2 {
+		t.Errorf("expected %d customers to change tier, got %d", 2, changed)
+	}
+
+	for name, id := range ids {
+		c, err := repo.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := membership.Bronze
+		if name == "Rin" {
+			expected = membership.NoTier
+		}
+		if membership.Tier(c.GetTier()) != expected {
+			t.Errorf("expected %s to be %q, got %q", name, expected, c.GetTier())
+		}
+	}
+}
+
+func Test_TavernServiceMembership(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	beer := products[0]
+
+	evaluator, err := membership.NewEvaluator(
+		membership.WithTier(membership.Silver, 100, membership.Benefits{Discount: 10, Priority: 2}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+		WithMembership(evaluator),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Meiko")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := os.CreateOrder(ctx, cust.GetID(), ItemsOf(beer.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTierDiscount() != 0 || order.GetPriority() != 0 {
+		t.Errorf("expected no benefit without a tier, got %v off and priority %d", order.GetTierDiscount(), order.GetPriority())
+	}
+
+	// paying 2 beers reaches silver
+	for i := 0; i < 2; i++ {
+		if _, err := tavern.Order(ctx, cust.GetID(), ItemsOf(beer.GetID())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order, err = os.CreateOrder(ctx, cust.GetID(), ItemsOf(beer.GetID()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.GetTier() != string(membership.Silver) || order.GetPriority() != 2 {
+		t.Errorf("expected a silver order with priority 2, got %q with %d", order.GetTier(), order.GetPriority())
+	}
+	if order.GetTierDiscount() != 9.99 || order.GetTotal() != 89.93 {
+		t.Errorf("expected 9.99 off for a total of 89.93, got %v off for %v", order.GetTierDiscount(), order.GetTotal())
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+	"sync"
+	"testing"
+)
+
+func Test_TavernServiceWallet(t *testing.T) {
+	ctx := context.Background()
+	products := init_products(t)
+	peanut := products[1]
+
+	os, err := NewOrderService(
+		WithMemoryCustomerRepository(),
+		WithMemoryProductRepository(products),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tavern, err := NewTavernService(WithOrderService(os))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cust, err := aggregate.NewCustomer("Gumi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.customerRepo.Add(ctx, cust); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tavern.Order(ctx, cust.GetID(), ItemsOf(peanut.GetID()), PayFromWallet()); !errors.Is(err, aggregate.ErrInsufficientFunds) {
+		t.Errorf("expected error %v, got %v", aggregate.ErrInsufficientFunds, err)
+	}
+
+	// enough credit for 4 bags of peanuts at 12.5 and a 1.00 tip each
+	if err := tavern.Deposit(ctx, cust.GetID(), 55); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := tavern.Order(ctx, cust.GetID(), ItemsOf(peanut.GetID()), PayFromWallet(), WithTip(1))
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var paid int
+	for err := range errs {
+		switch {
+		case err == nil:
+			paid++
+		case !errors.Is(err, aggregate.ErrInsufficientFunds) && !errors.Is(err, customer.ErrConcurrentUpdate):
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+
+	cust, err = os.customerRepo.Get(ctx, cust.GetID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := 55 - float64(paid)*13.5
+	if paid == 0 || paid > 4 || cust.GetBalance() != expected {
+		t.Errorf("expected %d paid orders to leave %v, got %v", paid, expected, cust.GetBalance())
+	}
+}
@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"context"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/customer"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// customerRepository wraps every call of a customer.CustomerRepository in a span
+type customerRepository struct {
+	next   customer.CustomerRepository
+	tracer trace.Tracer
+}
+
+func NewCustomerRepository(next customer.CustomerRepository, tracer trace.Tracer) customer.CustomerRepository {
+	return &customerRepository{
+		next:   next,
+		tracer: tracer,
+	}
+}
+
+func (r *customerRepository) Get(ctx context.Context, id uuid.UUID) (aggregate.Customer, error) {
+	ctx, span := Start(ctx, r.tracer, "CustomerRepository.Get", attribute.Stringer("customer.id", id))
+	c, err := r.next.Get(ctx, id)
+	End(span, err)
+
+	return c, err
+}
+
+func (r *customerRepository) Add(ctx context.Context, c aggregate.Customer) error {
+	ctx, span := Start(ctx, r.tracer, "CustomerRepository.Add", attribute.Stringer("customer.id", c.GetID()))
+	err := r.next.Add(ctx, c)
+	End(span, err)
+
+	return err
+}
+
+func (r *customerRepository) Update(ctx context.Context, c aggregate.Customer) error {
+	ctx, span := Start(ctx, r.tracer, "CustomerRepository.Update", attribute.Stringer("customer.id", c.GetID()))
+	err := r.next.Update(ctx, c)
+	End(span, err)
+
+	return err
+}
+
+func (r *customerRepository) Delete(ctx context.Context, c aggregate.Customer) error {
+	ctx, span := Start(ctx, r.tracer, "CustomerRepository.Delete", attribute.Stringer("customer.id", c.GetID()))
+	err := r.next.Delete(ctx, c)
+	End(span, err)
+
+	return err
+}
+
+func (r *customerRepository) List(ctx context.Context, offset, limit int) (customer.Page, error) {
+	ctx, span := Start(ctx, r.tracer, "CustomerRepository.List",
+		attribute.Int("query.offset", offset),
+		attribute.Int("query.limit", limit),
+	)
+	page, err := r.next.List(ctx, offset, limit)
+	span.SetAttributes(attribute.Int("customer.total", page.Total))
+	End(span, err)
+
+	return page, err
+}
+
+func (r *customerRepository) FindByName(ctx context.Context, prefix string) ([]aggregate.Customer, error) {
+	ctx, span := Start(ctx, r.tracer, "CustomerRepository.FindByName")
+	customers, err := r.next.FindByName(ctx, prefix)
+	span.SetAttributes(attribute.Int("customer.count", len(customers)))
+	End(span, err)
+
+	return customers, err
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"golang-learn-ddd/aggregate"
+	"golang-learn-ddd/domain/product"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// productRepository wraps every call of a product.ProductRepository in a span
+type productRepository struct {
+	next   product.ProductRepository
+	tracer trace.Tracer
+}
+
+func NewProductRepository(next product.ProductRepository, tracer trace.Tracer) product.ProductRepository {
+	return &productRepository{
+		next:   next,
+		tracer: tracer,
+	}
+}
+
+func (r *productRepository) GetAll(ctx context.Context) ([]aggregate.Product, error) {
+	ctx, span := Start(ctx, r.tracer, "ProductRepository.GetAll")
+	products, err := r.next.GetAll(ctx)
+	span.SetAttributes(attribute.Int("product.count", len(products)))
+	End(span, err)
+
+	return products, err
+}
+
+func (r *productRepository) GetByID(ctx context.Context, id uuid.UUID) (aggregate.Product, error) {
+	ctx, span := Start(ctx, r.tracer, "ProductRepository.GetByID", attribute.Stringer("product.id", id))
+	p, err := r.next.GetByID(ctx, id)
+	End(span, err)
+
+	return p, err
+}
+
+func (r *productRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]aggregate.Product, []uuid.UUID, error) {
+	ctx, span := Start(ctx, r.tracer, "ProductRepository.GetByIDs", attribute.Int("product.requested", len(ids)))
+	products, missing, err := r.next.GetByIDs(ctx, ids)
+	span.SetAttributes(attribute.Int("product.missing", len(missing)))
+	End(span, err)
+
+	return products, missing, err
+}
+
+func (r *productRepository) Search(ctx context.Context, query product.Query) (product.Page, error) {
+	ctx, span := Start(ctx, r.tracer, "ProductRepository.Search",
+		attribute.String("query.sort_by", string(query.SortBy)),
+		attribute.Int("query.offset", query.Offset),
+		attribute.Int("query.limit", query.Limit),
+	)
+	page, err := r.next.Search(ctx, query)
+	span.SetAttributes(attribute.Int("product.total", page.Total))
+	End(span, err)
+
+	return page, err
+}
+
+func (r *productRepository) Add(ctx context.Context, p aggregate.Product) error {
+	ctx, span := Start(ctx, r.tracer, "ProductRepository.Add", attribute.Stringer("product.id", p.GetID()))
+	err := r.next.Add(ctx, p)
+	End(span, err)
+
+	return err
+}
+
+func (r *productRepository) Update(ctx context.Context, p aggregate.Product) error {
+	ctx, span := Start(ctx, r.tracer, "ProductRepository.Update", attribute.Stringer("product.id", p.GetID()))
+	err := r.next.Update(ctx, p)
+	End(span, err)
+
+	return err
+}
+
+func (r *productRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := Start(ctx, r.tracer, "ProductRepository.Delete", attribute.Stringer("product.id", id))
+	err := r.next.Delete(ctx, id)
+	End(span, err)
+
+	return err
+}
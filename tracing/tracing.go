@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name every tavern tracer is created with
+const InstrumentationName = "golang-learn-ddd"
+
+// NewProvider creates a TracerProvider batching spans to the exporter.
+// Use tracetest.NewInMemoryExporter in tests and NewOTLPExporter in production.
+func NewProvider(exporter sdktrace.SpanExporter, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(semconv.ServiceName("tavern"))
+
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	}, opts...)
+
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// NewOTLPExporter exports spans over OTLP/HTTP to the collector at endpoint (host:port)
+func NewOTLPExporter(ctx context.Context, endpoint string, opts ...otlptracehttp.Option) (sdktrace.SpanExporter, error) {
+	opts = append([]otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}, opts...)
+
+	return otlptracehttp.New(ctx, opts...)
+}
+
+// Start starts a span named name, decorated with attrs
+func Start(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span, if any, then ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,52 @@
+package valueobject
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrInvalidAllocation = errors.New("an allocation needs positive weights and a non-negative amount")
+)
+
+// Allocate splits an amount of cents in proportion of the weights without
+// losing a cent: the shares always sum to the amount, the cents left by
+// rounding down go to the largest remainders, then to the first weights
+func Allocate(amount int, weights []int) ([]int, error) {
+	if amount < 0 || len(weights) == 0 {
+		return nil, ErrInvalidAllocation
+	}
+
+	var sum int
+	for _, w := range weights {
+		if w < 0 {
+			return nil, ErrInvalidAllocation
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return nil, ErrInvalidAllocation
+	}
+
+	shares := make([]int, len(weights))
+	remainders := make([]int, len(weights))
+	left := amount
+	for i, w := range weights {
+		shares[i] = amount * w / sum
+		remainders[i] = amount * w % sum
+		left -= shares[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for _, i := range order[:left] {
+		shares[i]++
+	}
+
+	return shares, nil
+}
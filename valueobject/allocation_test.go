@@ -0,0 +1,78 @@
+package valueobject
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAllocation_Allocate(t *testing.T) {
+	type testCase struct {
+		name        string
+		amount      int
+		weights     []int
+		expected    []int
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:     "even split leaves the extra cent to the first",
+			amount:   1000,
+			weights:  []int{1, 1, 1},
+			expected: []int{334, 333, 333},
+		},
+		{
+			name:     "proportional split",
+			amount:   1001,
+			weights:  []int{70, 30},
+			expected: []int{701, 300},
+		},
+		{
+			name:     "largest remainder gets the cent",
+			amount:   5,
+			weights:  []int{1, 3},
+			expected: []int{1, 4},
+		},
+		{
+			name:     "zero weight pays nothing",
+			amount:   500,
+			weights:  []int{0, 2},
+			expected: []int{0, 500},
+		},
+		{
+			name:     "nothing to allocate",
+			amount:   0,
+			weights:  []int{1, 1},
+			expected: []int{0, 0},
+		},
+		{
+			name:        "no weights",
+			amount:      100,
+			expectedErr: ErrInvalidAllocation,
+		},
+		{
+			name:        "only zero weights",
+			amount:      100,
+			weights:     []int{0, 0},
+			expectedErr: ErrInvalidAllocation,
+		},
+		{
+			name:        "negative weight",
+			amount:      100,
+			weights:     []int{2, -1},
+			expectedErr: ErrInvalidAllocation,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			shares, err := Allocate(tc.amount, tc.weights)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tc.expectedErr, err)
+			}
+			if !reflect.DeepEqual(shares, tc.expected) {
+				t.Errorf("expected shares %v, got %v", tc.expected, shares)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package valueobject
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email address")
+)
+
+// Email is a validated email address, the domain is stored lowercased
+type Email struct {
+	address string
+}
+
+func NewEmail(address string) (Email, error) {
+	address = strings.TrimSpace(address)
+
+	parsed, err := mail.ParseAddress(address)
+	// reject display names such as "Adhiana <adhiana@example.com>"
+	if err != nil || parsed.Address != address {
+		return Email{}, ErrInvalidEmail
+	}
+
+	at := strings.LastIndex(address, "@")
+	if !strings.Contains(address[at+1:], ".") {
+		return Email{}, ErrInvalidEmail
+	}
+
+	return Email{
+		address: address[:at] + "@" + strings.ToLower(address[at+1:]),
+	}, nil
+}
+
+// IsZero reports whether no email address was set
+func (e Email) IsZero() bool {
+	return e.address == ""
+}
+
+func (e Email) String() string {
+	return e.address
+}
@@ -0,0 +1,60 @@
+package valueobject
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEmail_NewEmail(t *testing.T) {
+	type testCase struct {
+		name        string
+		address     string
+		expected    string
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:     "valid address",
+			address:  "adhiana@example.com",
+			expected: "adhiana@example.com",
+		},
+		{
+			name:     "domain is lowercased, surrounding spaces trimmed",
+			address:  " Adhiana@Example.COM ",
+			expected: "Adhiana@example.com",
+		},
+		{
+			name:        "empty address",
+			address:     "",
+			expectedErr: ErrInvalidEmail,
+		},
+		{
+			name:        "missing at sign",
+			address:     "adhiana.example.com",
+			expectedErr: ErrInvalidEmail,
+		},
+		{
+			name:        "missing top level domain",
+			address:     "adhiana@localhost",
+			expectedErr: ErrInvalidEmail,
+		},
+		{
+			name:        "display name",
+			address:     "Adhiana <adhiana@example.com>",
+			expectedErr: ErrInvalidEmail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, err := NewEmail(tt.address)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+
+			if email.String() != tt.expected {
+				t.Errorf("expected email %q, got %q", tt.expected, email.String())
+			}
+		})
+	}
+}
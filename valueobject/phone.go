@@ -0,0 +1,50 @@
+package valueobject
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidPhoneNumber = errors.New("invalid phone number")
+)
+
+// PhoneNumber is a validated phone number in E.164 format, e.g. +6281234567890
+type PhoneNumber struct {
+	number string
+}
+
+// NewPhoneNumber accepts an international number, spaces, dashes, dots
+// and parentheses used as separators are removed
+func NewPhoneNumber(number string) (PhoneNumber, error) {
+	replacer := strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "")
+	number = replacer.Replace(strings.TrimSpace(number))
+
+	if !strings.HasPrefix(number, "+") {
+		return PhoneNumber{}, ErrInvalidPhoneNumber
+	}
+
+	digits := number[1:]
+	if len(digits) < 7 || len(digits) > 15 || digits[0] == '0' {
+		return PhoneNumber{}, ErrInvalidPhoneNumber
+	}
+
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			return PhoneNumber{}, ErrInvalidPhoneNumber
+		}
+	}
+
+	return PhoneNumber{
+		number: number,
+	}, nil
+}
+
+// IsZero reports whether no phone number was set
+func (p PhoneNumber) IsZero() bool {
+	return p.number == ""
+}
+
+func (p PhoneNumber) String() string {
+	return p.number
+}
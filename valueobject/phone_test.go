@@ -0,0 +1,60 @@
+package valueobject
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPhoneNumber_NewPhoneNumber(t *testing.T) {
+	type testCase struct {
+		name        string
+		number      string
+		expected    string
+		expectedErr error
+	}
+	tests := []testCase{
+		{
+			name:     "E.164 number",
+			number:   "+6281234567890",
+			expected: "+6281234567890",
+		},
+		{
+			name:     "separators are removed",
+			number:   "+62 (812) 345-678.90",
+			expected: "+6281234567890",
+		},
+		{
+			name:        "missing country code",
+			number:      "081234567890",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			name:        "letters",
+			number:      "+62812ABC7890",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			name:        "too short",
+			number:      "+62812",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+		{
+			name:        "too long",
+			number:      "+6281234567890123",
+			expectedErr: ErrInvalidPhoneNumber,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phone, err := NewPhoneNumber(tt.number)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+
+			if phone.String() != tt.expected {
+				t.Errorf("expected phone number %q, got %q", tt.expected, phone.String())
+			}
+		})
+	}
+}
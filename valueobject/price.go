@@ -0,0 +1,32 @@
+package valueobject
+
+import (
+	"time"
+)
+
+// PriceChange records a price going from old to new at effectiveAt
+type PriceChange struct {
+	old         float64
+	new         float64
+	effectiveAt time.Time
+}
+
+func NewPriceChange(old, new float64, effectiveAt time.Time) PriceChange {
+	return PriceChange{
+		old:         old,
+		new:         new,
+		effectiveAt: effectiveAt,
+	}
+}
+
+func (p PriceChange) Old() float64 {
+	return p.old
+}
+
+func (p PriceChange) New() float64 {
+	return p.new
+}
+
+func (p PriceChange) EffectiveAt() time.Time {
+	return p.effectiveAt
+}
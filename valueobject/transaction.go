@@ -1,14 +1,86 @@
 package valueobject
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type TransactionKind string
+
+const (
+	// TransactionPayment pays for an order and counts as revenue
+	TransactionPayment TransactionKind = "payment"
+	// TransactionServiceCharge is charged on top of an order and counts as revenue
+	TransactionServiceCharge TransactionKind = "service_charge"
+	// TransactionTip goes to the staff and is not revenue
+	TransactionTip TransactionKind = "tip"
+	// TransactionDeposit adds prepaid credit to the wallet of a customer
+	TransactionDeposit TransactionKind = "deposit"
+	// TransactionWithdrawal takes credit from the wallet of a customer, to pay a bill
+	TransactionWithdrawal TransactionKind = "withdrawal"
+	// TransactionRefund gives back to a customer money paid for an order
+	TransactionRefund TransactionKind = "refund"
+	// TransactionTipRefund gives back a tip to a customer, it was not revenue either
+	TransactionTipRefund TransactionKind = "tip_refund"
+)
+
+// Transaction is money moving between two parties, amounts are in cents
 type Transaction struct {
+	kind      TransactionKind
 	amount    int
 	from      uuid.UUID
 	to        uuid.UUID
 	createdAt time.Time
+	// reference identifies the payment at the payment gateway, if any
+	reference string
+}
+
+func NewTransaction(kind TransactionKind, amount int, from, to uuid.UUID, createdAt time.Time) Transaction {
+	return Transaction{
+		kind:      kind,
+		amount:    amount,
+		from:      from,
+		to:        to,
+		createdAt: createdAt,
+	}
+}
+
+func (t Transaction) Kind() TransactionKind {
+	return t.kind
+}
+
+// Amount is in cents
+func (t Transaction) Amount() int {
+	return t.amount
+}
+
+func (t Transaction) From() uuid.UUID {
+	return t.from
+}
+
+func (t Transaction) To() uuid.UUID {
+	return t.to
+}
+
+func (t Transaction) CreatedAt() time.Time {
+	return t.createdAt
+}
+
+// Reference is the ID of the payment at the payment gateway, empty when
+// the transaction did not go through one
+func (t Transaction) Reference() string {
+	return t.reference
+}
+
+// WithReference returns the transaction settled by the payment of the gateway
+func (t Transaction) WithReference(reference string) Transaction {
+	t.reference = reference
+	return t
+}
+
+// Cents converts an amount of money to cents
+func Cents(amount float64) int {
+	return int(math.Round(amount * 100))
 }